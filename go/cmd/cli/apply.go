@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bonyuta0204/personal-agent/go/config"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
+	postgresRepo "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
+	storageFactory "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/storage"
+	storeusecase "github.com/bonyuta0204/personal-agent/go/internal/usecase/store"
+	"github.com/spf13/cobra"
+)
+
+// Flags for apply command
+var applyDryRun bool
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or update the document stores declared in the config file",
+	Long: `Create or update the document stores listed under "stores" in the config file.
+Stores are matched by repository and ref, so running apply repeatedly is safe.
+Stores that exist in the database but are not declared are left untouched.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+		if len(ctx.Config.Stores) == 0 {
+			return fmt.Errorf("no stores declared in the config file")
+		}
+
+		specs := make([]storeusecase.CreateInput, len(ctx.Config.Stores))
+		for i, store := range ctx.Config.Stores {
+			specs[i] = storeSpecFromConfig(store)
+		}
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		applyUsecase := storeusecase.NewApplyUsecase(postgresRepo.NewStoreRepository(db), storageFactory.NewStoreValidator())
+		results, err := applyUsecase.Apply(cmd.Context(), specs, applyDryRun)
+
+		views := make(applyResultListView, 0, len(results))
+		for _, r := range results {
+			views = append(views, newApplyResultView(ctx.Config.Stores[r.Index], r, applyDryRun))
+		}
+		if renderErr := render(views); renderErr != nil {
+			return renderErr
+		}
+
+		var specErr *storeusecase.SpecError
+		if errors.As(err, &specErr) {
+			return &config.ValidationError{Key: fmt.Sprintf("stores[%d]", specErr.Index), Message: specErr.Err.Error()}
+		}
+		return err
+	},
+}
+
+// storeSpecFromConfig converts a declared store into use case input
+func storeSpecFromConfig(store config.StoreConfig) storeusecase.CreateInput {
+	return storeusecase.CreateInput{
+		Type: store.Type,
+		Repo: store.Repo,
+		Ref:  store.Ref,
+		Filters: model.StoreFilters{
+			Include: store.Include,
+			Exclude: store.Exclude,
+		},
+	}
+}
+
+// applyResultView is the structured representation of one applied store
+type applyResultView struct {
+	Key    string                   `json:"key"`
+	Action storeusecase.ApplyAction `json:"action"`
+	DryRun bool                     `json:"dry_run"`
+	Repo   string                   `json:"repo"`
+	Ref    string                   `json:"ref,omitempty"`
+	Store  *storeView               `json:"store,omitempty"`
+}
+
+func newApplyResultView(declared config.StoreConfig, r storeusecase.ApplyResult, dryRun bool) applyResultView {
+	view := applyResultView{
+		Key:    fmt.Sprintf("stores[%d]", r.Index),
+		Action: r.Action,
+		DryRun: dryRun,
+		Repo:   declared.Repo,
+		Ref:    declared.Ref,
+	}
+	if r.Store != nil {
+		store := newStoreView(r.Store)
+		view.Repo = store.Repo
+		view.Store = &store
+	}
+	return view
+}
+
+// applyResultListView is the result of "apply"
+type applyResultListView []applyResultView
+
+func (v applyResultListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v applyResultListView) renderText(w io.Writer) {
+	for _, r := range v {
+		id := "-"
+		if r.Store != nil {
+			id = fmt.Sprintf("%d", r.Store.ID)
+		}
+		location := storeView{Repo: r.Repo, Ref: r.Ref}.location()
+		prefix := ""
+		if r.DryRun && r.Action != storeusecase.ApplyActionUnchanged {
+			prefix = "(dry run) "
+		}
+		fmt.Fprintf(w, "%s%-9s %-11s store %-3s %s\n", prefix, r.Action, r.Key, id, location)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().BoolVarP(&applyDryRun, "dry-run", "n", false, "Show what would change without writing to the database")
+}
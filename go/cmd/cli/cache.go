@@ -0,0 +1,75 @@
+// Package main implements the cache commands for the personal-agent CLI.
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and prune the cache of repository snapshots",
+	Long: `GitHub repositories are downloaded once per commit into a local cache, so that
+document and memory syncs of the same commit reuse the extracted files across runs.
+The least recently used snapshots are evicted when the cache exceeds cache.max_size_mb.`,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the cached repository snapshots",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := GetAppContext().SnapshotCache()
+		snapshots, err := cache.List()
+		if err != nil {
+			return err
+		}
+		return render(newSnapshotListView(cache.Dir(), cache.MaxSize(), snapshots))
+	},
+}
+
+var (
+	// Flags for prune command
+	pruneMaxSizeMB int
+	pruneAll       bool
+)
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict the least recently used repository snapshots",
+	Long: `Remove the least recently used snapshots until the cache holds at most --max-size-mb,
+which defaults to cache.max_size_mb in the config file. --all empties the cache.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := GetAppContext().SnapshotCache()
+		maxSize := cache.MaxSize()
+		switch {
+		case pruneAll && cmd.Flags().Changed("max-size-mb"):
+			return fmt.Errorf("--all and --max-size-mb cannot be combined")
+		case pruneAll:
+			maxSize = 0
+		case cmd.Flags().Changed("max-size-mb"):
+			if pruneMaxSizeMB < 0 {
+				return fmt.Errorf("--max-size-mb must not be negative")
+			}
+			maxSize = int64(pruneMaxSizeMB) << 20
+		}
+
+		removed, err := cache.Prune(maxSize)
+		if err != nil {
+			return err
+		}
+		return render(newSnapshotPrunedView(removed))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+
+	cachePruneCmd.Flags().IntVar(&pruneMaxSizeMB, "max-size-mb", 0, "Keep at most this many megabytes of snapshots")
+	cachePruneCmd.Flags().BoolVar(&pruneAll, "all", false, "Remove every snapshot")
+}
@@ -1,6 +1,14 @@
 package main
 
-import "github.com/bonyuta0204/personal-agent/go/config"
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/config"
+	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
+	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/storage"
+	"github.com/jmoiron/sqlx"
+)
 
 // AppContext holds the application context including configuration
 type AppContext struct {
@@ -13,3 +21,28 @@ func NewAppContext(cfg *config.Config) *AppContext {
 		Config: cfg,
 	}
 }
+
+// OpenDatabase connects to the database and verifies that its schema is up to date.
+// The caller must close the returned connection with database.CloseDB.
+func (c *AppContext) OpenDatabase(ctx context.Context) (*sqlx.DB, error) {
+	db, err := database.NewDBConnection(ctx, &c.Config.Database)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
+	}
+
+	migrator, err := database.NewMigrator(db, &c.Config.Database)
+	if err == nil {
+		err = migrator.CheckSchema(ctx)
+	}
+	if err != nil {
+		database.CloseDB(db)
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// SnapshotCache returns the configured cache of repository snapshots
+func (c *AppContext) SnapshotCache() *storage.SnapshotCache {
+	return storage.NewSnapshotCache(c.Config.Cache.Dir, int64(c.Config.Cache.MaxSizeMB)<<20)
+}
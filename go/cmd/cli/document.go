@@ -2,15 +2,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"strconv"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
 	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
-	embeddingProvider "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/embedding"
 	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
 	storageFactory "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/storage"
 	"github.com/bonyuta0204/personal-agent/go/internal/usecase/document"
+	"github.com/jmoiron/sqlx"
 	"github.com/spf13/cobra"
 )
 
@@ -34,6 +37,20 @@ func parseStoreID(storeIDStr string) (model.StoreId, error) {
 	return model.StoreId(id), nil
 }
 
+// pruneDocumentVersions applies the configured history retention; failures are only logged
+func pruneDocumentVersions(ctx context.Context, app *AppContext, db *sqlx.DB) {
+	limits := app.Config.History
+	pruneUsecase := document.NewPruneVersionsUsecase(postgres.NewDocumentVersionRepository(db))
+	removed, err := pruneUsecase.Prune(ctx, limits.MaxVersions, limits.MaxAge)
+	if err != nil {
+		log.Printf("failed to prune document versions: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("removed %d document versions", removed)
+	}
+}
+
 var syncDocumentCmd = &cobra.Command{
 	Use:   "sync <store-id>",
 	Short: "Sync documents from a store",
@@ -49,9 +66,9 @@ var syncDocumentCmd = &cobra.Command{
 		ctx := GetAppContext()
 
 		// Initialize database connection
-		db, err := database.NewDBConnection(&ctx.Config.Database)
+		db, err := ctx.OpenDatabase(cmd.Context())
 		if err != nil {
-			return fmt.Errorf("database connection error: %w", err)
+			return err
 		}
 		defer database.CloseDB(db)
 
@@ -60,27 +77,184 @@ var syncDocumentCmd = &cobra.Command{
 		storeRepo := postgres.NewStoreRepository(db)
 
 		// Initialize storage factory provider
-		storageFactoryProvider := storageFactory.NewStorageFactoryProvider()
+		storageFactoryProvider := storageFactory.NewStorageFactoryProvider(ctx.Config.Git.MirrorDir, ctx.SnapshotCache(), ctx.Config.GitHub.Submodules)
 
 		// Initialize embedding provider
-		openaiProvider, err := embeddingProvider.NewOpenAIProvider()
+		provider, cache, err := newEmbeddingProvider(ctx, db)
 		if err != nil {
-			return fmt.Errorf("failed to create embedding provider: %w", err)
+			return err
 		}
 
 		// Initialize sync use case
-		syncUsecase := document.NewSyncUsecase(storeRepo, documentRepo, storageFactoryProvider, openaiProvider)
+		syncUsecase := document.NewSyncUsecase(storeRepo, documentRepo, storageFactoryProvider, provider, postgres.NewUsageRepository(db))
 
 		// Execute the sync
-		fmt.Printf("Starting sync for store ID: %d\n", storeID)
+		status("Starting sync for store ID: %d", storeID)
 
-		err = syncUsecase.Sync(storeIDStr)
+		result, err := syncUsecase.OnEvent(newSyncEventHandler()).WithBatchSize(ctx.Config.Sync.BatchSize).Sync(cmd.Context(), storeIDStr)
+		if err == nil {
+			evictEmbeddingCache(cmd.Context(), ctx, db)
+			pruneDocumentVersions(cmd.Context(), ctx, db)
+		}
+
+		return renderSyncResult(result, cache.Usage(), err)
+	},
+}
+
+var (
+	// Flags for list command
+	listStoreID    string
+	listTag        string
+	listPathPrefix string
+	listSince      string
+	listAuthor     string
+	listLimit      int
+)
+
+var listDocumentCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List ingested documents",
+	Long: `List the documents stored in the database, optionally filtered by store, tag, path prefix,
+modification time and the author of their last commit.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := repository.DocumentFilter{
+			Tag:        listTag,
+			PathPrefix: listPathPrefix,
+			Author:     listAuthor,
+			Limit:      listLimit,
+		}
+		if listStoreID != "" {
+			storeID, err := parseStoreID(listStoreID)
+			if err != nil {
+				return err
+			}
+			filter.StoreId = storeID
+		}
+		since, err := parseSince(listSince)
 		if err != nil {
-			return fmt.Errorf("sync failed: %w", err)
+			return err
 		}
+		filter.ModifiedSince = since
 
-		fmt.Println("Sync completed successfully")
-		return nil
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		listUsecase := document.NewListUsecase(postgres.NewDocumentRepository(db))
+		documents, err := listUsecase.List(cmd.Context(), filter)
+		if err != nil {
+			return err
+		}
+
+		views := make(documentListView, 0, len(documents))
+		for _, d := range documents {
+			views = append(views, newDocumentView(d, false))
+		}
+		return render(views)
+	},
+}
+
+var showDocumentCmd = &cobra.Command{
+	Use:   "show <store-id> <path>",
+	Short: "Show an ingested document",
+	Long:  `Print the metadata, tags, SHA and embedding model of a document followed by its content.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storeID, err := parseStoreID(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		showUsecase := document.NewShowUsecase(postgres.NewDocumentRepository(db))
+		doc, err := showUsecase.Show(cmd.Context(), storeID, args[1])
+		if err != nil {
+			return err
+		}
+
+		return render(newDocumentView(doc, true))
+	},
+}
+
+var historyDocumentCmd = &cobra.Command{
+	Use:   "history <store-id> <path>",
+	Short: "List the versions of a document",
+	Long: `List the previous versions of a document, kept each time a sync changed its content,
+followed by its current version. Retention is set by history in the config file.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storeID, err := parseStoreID(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		historyUsecase := document.NewHistoryUsecase(postgres.NewDocumentRepository(db), postgres.NewDocumentVersionRepository(db))
+		versions, err := historyUsecase.History(cmd.Context(), storeID, args[1])
+		if err != nil {
+			return err
+		}
+
+		return render(newDocumentHistoryView(versions))
+	},
+}
+
+var (
+	// Flags for diff command
+	diffFrom int
+	diffTo   int
+)
+
+var diffDocumentCmd = &cobra.Command{
+	Use:   "diff <store-id> <path>",
+	Short: "Show the changes between two versions of a document",
+	Long: `Print a unified diff between two versions of a document as numbered by "document history".
+By default the current version is compared with the previous one.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storeID, err := parseStoreID(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		historyUsecase := document.NewHistoryUsecase(postgres.NewDocumentRepository(db), postgres.NewDocumentVersionRepository(db))
+		diff, err := historyUsecase.Diff(cmd.Context(), storeID, args[1], diffFrom, diffTo)
+		if err != nil {
+			return err
+		}
+
+		return render(newDocumentDiffView(diff))
 	},
 }
 
@@ -92,7 +266,23 @@ var (
 func init() {
 	rootCmd.AddCommand(documentCmd)
 	documentCmd.AddCommand(syncDocumentCmd)
+	documentCmd.AddCommand(listDocumentCmd)
+	documentCmd.AddCommand(showDocumentCmd)
+	documentCmd.AddCommand(historyDocumentCmd)
+	documentCmd.AddCommand(diffDocumentCmd)
 
 	// Add flags for document commands
 	syncDocumentCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Perform a trial run with no changes made")
+
+	listDocumentCmd.Flags().StringVar(&listStoreID, "store", "", "Only list documents of this store ID")
+	listDocumentCmd.Flags().StringVar(&listTag, "tag", "", "Only list documents with this tag")
+	listDocumentCmd.Flags().StringVar(&listPathPrefix, "path-prefix", "", "Only list documents whose path starts with this prefix")
+	listDocumentCmd.Flags().StringVar(&listSince, "since", "", "Only list documents modified since a date (2006-01-02), RFC 3339 time or duration (7d)")
+	listDocumentCmd.Flags().StringVar(&listAuthor, "author", "", "Only list documents whose last commit author contains this text")
+	listDocumentCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of documents to list (0 for no limit)")
+	addJSONAliasFlag(listDocumentCmd)
+	addJSONAliasFlag(showDocumentCmd)
+
+	diffDocumentCmd.Flags().IntVar(&diffFrom, "from", 0, "Version to compare from (default: the version before --to)")
+	diffDocumentCmd.Flags().IntVar(&diffTo, "to", 0, "Version to compare to (default: the current version)")
 }
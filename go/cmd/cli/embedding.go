@@ -0,0 +1,146 @@
+// Package main implements the embedding-related commands for the personal-agent CLI.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
+	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
+	embeddingProvider "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/embedding"
+	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
+	"github.com/bonyuta0204/personal-agent/go/internal/usecase/embeddingcache"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+// newEmbeddingProvider creates the configured embedding provider. Texts are checked against the
+// token limit first, so that truncated texts and chunks are looked up in the embedding cache.
+// The cache is returned as well to report its usage.
+func newEmbeddingProvider(ctx *AppContext, db *sqlx.DB) (embedding.EmbeddingProvider, *embeddingProvider.CachedProvider, error) {
+	provider, err := embeddingProvider.NewProvider(&ctx.Config.Embedding)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create embedding provider: %w", err)
+	}
+
+	cached := embeddingProvider.NewCachedProvider(provider, postgres.NewEmbeddingCacheRepository(db))
+	limited, err := embeddingProvider.WithTokenLimit(cached, &ctx.Config.Embedding)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create embedding provider: %w", err)
+	}
+	return limited, cached, nil
+}
+
+// evictEmbeddingCache applies the configured cache limits; failures are only logged
+func evictEmbeddingCache(ctx context.Context, app *AppContext, db *sqlx.DB) {
+	limits := app.Config.Embedding.Cache
+	pruneUsecase := embeddingcache.NewPruneUsecase(postgres.NewEmbeddingCacheRepository(db))
+	removed, err := pruneUsecase.Prune(ctx, limits.MaxEntries, limits.MaxAge)
+	if err != nil {
+		log.Printf("failed to evict embedding cache: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("evicted %d embeddings from the cache", removed)
+	}
+}
+
+// embeddingCmd represents the embedding command
+var embeddingCmd = &cobra.Command{
+	Use:   "embedding",
+	Short: "Manage embeddings",
+}
+
+// embeddingCacheCmd represents the embedding cache command
+var embeddingCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and prune the embedding cache",
+	Long: `Embeddings are cached by the SHA-256 of the embedded text and the model, so identical
+content in different stores, paths or memories is only sent to the provider once.`,
+}
+
+var embeddingCacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size and hit rate of the embedding cache",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		statsUsecase := embeddingcache.NewStatsUsecase(postgres.NewEmbeddingCacheRepository(db))
+		stats, err := statsUsecase.Stats(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		return render(embeddingCacheStatsView{
+			Entries:      stats.Entries,
+			SizeBytes:    stats.SizeBytes,
+			Hits:         stats.Hits,
+			HitRate:      stats.HitRate(),
+			OldestUsedAt: stats.OldestUsedAt,
+			NewestUsedAt: stats.NewestUsedAt,
+		})
+	},
+}
+
+var (
+	// Flags for prune command
+	pruneMaxEntries int
+	pruneMaxAge     time.Duration
+)
+
+var embeddingCachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict old and least recently used embeddings",
+	Long: `Remove cached embeddings unused for longer than --max-age and the least recently used
+entries above --max-entries. Limits default to embedding.cache in the config file,
+which is also applied after every sync.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+		limits := ctx.Config.Embedding.Cache
+		if cmd.Flags().Changed("max-entries") {
+			limits.MaxEntries = pruneMaxEntries
+		}
+		if cmd.Flags().Changed("max-age") {
+			limits.MaxAge = pruneMaxAge
+		}
+		if limits.MaxEntries == 0 && limits.MaxAge == 0 {
+			return fmt.Errorf("no limit given: set --max-entries or --max-age, or embedding.cache in the config file")
+		}
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		pruneUsecase := embeddingcache.NewPruneUsecase(postgres.NewEmbeddingCacheRepository(db))
+		removed, err := pruneUsecase.Prune(cmd.Context(), limits.MaxEntries, limits.MaxAge)
+		if err != nil {
+			return err
+		}
+
+		return render(embeddingCachePrunedView{Removed: removed})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(embeddingCmd)
+	embeddingCmd.AddCommand(embeddingCacheCmd)
+	embeddingCacheCmd.AddCommand(embeddingCacheStatsCmd)
+	embeddingCacheCmd.AddCommand(embeddingCachePruneCmd)
+
+	embeddingCachePruneCmd.Flags().IntVar(&pruneMaxEntries, "max-entries", 0, "Keep at most this many embeddings")
+	embeddingCachePruneCmd.Flags().DurationVar(&pruneMaxAge, "max-age", 0, "Remove embeddings unused for longer than this (e.g. 720h)")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
+	postgresRepo "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
+	indexusecase "github.com/bonyuta0204/personal-agent/go/internal/usecase/index"
+	"github.com/spf13/cobra"
+)
+
+// indexCmd represents the index command
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage vector indexes",
+	Long: `Commands for managing the approximate nearest-neighbour indexes on the embedding
+columns of the documents and memories tables.`,
+}
+
+// Flags shared by the index subcommands
+var indexTable string
+
+// indexTables returns the tables selected by the --table flag
+func indexTables() ([]string, error) {
+	if indexTable == "" {
+		return model.VectorTables, nil
+	}
+	if !model.IsVectorTable(indexTable) {
+		return nil, fmt.Errorf("invalid --table %q: must be one of documents, memories", indexTable)
+	}
+	return []string{indexTable}, nil
+}
+
+var indexStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the vector indexes and their health",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		statusUsecase := indexusecase.NewStatusUsecase(postgresRepo.NewIndexRepository(db))
+		statuses, err := statusUsecase.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		views := make(indexListView, 0, len(statuses))
+		for _, s := range statuses {
+			views = append(views, newIndexView(s.Index, s.Warnings))
+		}
+		return render(views)
+	},
+}
+
+var (
+	// Flags for create command
+	indexType           string
+	indexM              int
+	indexEfConstruction int
+	indexLists          int
+)
+
+var indexCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create or replace the vector indexes",
+	Long: `Build an HNSW or IVFFlat index on the embedding column. An existing index is replaced
+once the new one is built, so similarity queries keep using it in the meantime.
+Parameters default to the "index" section of the config file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tables, err := indexTables()
+		if err != nil {
+			return err
+		}
+
+		ctx := GetAppContext()
+		defaults := ctx.Config.Index
+		spec := model.VectorIndexSpec{
+			Method:         defaults.Type,
+			M:              defaults.M,
+			EfConstruction: defaults.EfConstruction,
+			Lists:          defaults.Lists,
+		}
+		if cmd.Flags().Changed("type") {
+			spec.Method = indexType
+		}
+		if cmd.Flags().Changed("m") {
+			spec.M = indexM
+		}
+		if cmd.Flags().Changed("ef-construction") {
+			spec.EfConstruction = indexEfConstruction
+		}
+		if cmd.Flags().Changed("lists") {
+			spec.Lists = indexLists
+		}
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		createUsecase := indexusecase.NewCreateUsecase(postgresRepo.NewIndexRepository(db))
+		views := make(indexListView, 0, len(tables))
+		for _, table := range tables {
+			spec.Table = table
+			status("Building %s index on %s...", spec.Method, table)
+			index, err := createUsecase.Create(cmd.Context(), spec)
+			if err != nil {
+				return fmt.Errorf("failed to create index on %s: %w", table, err)
+			}
+			views = append(views, newIndexView(index, nil))
+		}
+		return render(views)
+	},
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild the vector indexes with their current parameters",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tables, err := indexTables()
+		if err != nil {
+			return err
+		}
+
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		rebuildUsecase := indexusecase.NewRebuildUsecase(postgresRepo.NewIndexRepository(db))
+		views := make(indexListView, 0, len(tables))
+		for _, table := range tables {
+			status("Rebuilding index on %s...", table)
+			index, err := rebuildUsecase.Rebuild(cmd.Context(), table)
+			if err != nil {
+				return err
+			}
+			views = append(views, newIndexView(index, nil))
+		}
+		return render(views)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexStatusCmd)
+	indexCmd.AddCommand(indexCreateCmd)
+	indexCmd.AddCommand(indexRebuildCmd)
+
+	for _, cmd := range []*cobra.Command{indexCreateCmd, indexRebuildCmd} {
+		cmd.Flags().StringVar(&indexTable, "table", "", "Only this table: documents or memories (default all)")
+	}
+
+	indexCreateCmd.Flags().StringVar(&indexType, "type", "", "Index method: hnsw or ivfflat")
+	indexCreateCmd.Flags().IntVar(&indexM, "m", 0, "HNSW: maximum connections per layer")
+	indexCreateCmd.Flags().IntVar(&indexEfConstruction, "ef-construction", 0, "HNSW: size of the candidate list while building")
+	indexCreateCmd.Flags().IntVar(&indexLists, "lists", 0, "IVFFlat: number of lists (0 derives it from the row count)")
+}
@@ -2,16 +2,47 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
 
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
 	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
-	embeddingProvider "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/embedding"
 	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
 	storageFactory "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/storage"
 	"github.com/bonyuta0204/personal-agent/go/internal/usecase/memory"
+	"github.com/jmoiron/sqlx"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+var (
+	listMemoryType      string
+	listMinImportance   int
+	listSource          string
+	listIncludeArchived bool
+)
+
+// sweepMemories archives expired and superseded memories after a sync; failures are only logged
+func sweepMemories(ctx context.Context, db *sqlx.DB) {
+	result, err := memory.NewSweepUsecase(postgres.NewMemoryRepository(db)).Sweep(ctx, time.Now())
+	if err != nil {
+		log.Printf("failed to sweep memories: %v", err)
+		return
+	}
+	if n := len(result.Expired) + len(result.Superseded); n > 0 {
+		log.Printf("archived %d expired and %d superseded memories", len(result.Expired), len(result.Superseded))
+	}
+}
+
 // memoryCmd represents the memory command
 var memoryCmd = &cobra.Command{
 	Use:   "memory",
@@ -29,13 +60,15 @@ var syncMemoryCmd = &cobra.Command{
 	Long:  `Synchronize memorys`,
 	Args:  cobra.ExactArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
-
 		ctx := GetAppContext()
+		if err := ctx.Config.RequireMemory(); err != nil {
+			return err
+		}
 
 		// Initialize database connection
-		db, err := database.NewDBConnection(&ctx.Config.Database)
+		db, err := ctx.OpenDatabase(cmd.Context())
 		if err != nil {
-			return fmt.Errorf("database connection error: %w", err)
+			return err
 		}
 		defer database.CloseDB(db)
 
@@ -43,31 +76,402 @@ var syncMemoryCmd = &cobra.Command{
 		memoryRepo := postgres.NewMemoryRepository(db)
 
 		// Initialize storage factory provider
-		memoryStorageFactory := storageFactory.NewMemoryStorageFactory(ctx.Config.Memory.Repo)
+		memoryStorageFactory := storageFactory.NewMemoryStorageFactory(ctx.Config.Memory.Repo, ctx.SnapshotCache())
 
 		// Initialize embedding provider
-		openaiProvider, err := embeddingProvider.NewOpenAIProvider()
+		provider, cache, err := newEmbeddingProvider(ctx, db)
 		if err != nil {
-			return fmt.Errorf("failed to create embedding provider: %w", err)
+			return err
 		}
 
 		// Initialize sync use case
-		syncUsecase := memory.NewSyncUsecase(memoryRepo, memoryStorageFactory, openaiProvider)
+		syncUsecase := memory.NewSyncUsecase(memoryRepo, memoryStorageFactory, provider, postgres.NewUsageRepository(db))
 
 		// Execute the sync
-		err = syncUsecase.Sync()
+		result, err := syncUsecase.OnEvent(newSyncEventHandler()).WithBatchSize(ctx.Config.Sync.BatchSize).Sync(cmd.Context())
+		if err == nil {
+			sweepMemories(cmd.Context(), db)
+			evictEmbeddingCache(cmd.Context(), ctx, db)
+		}
+
+		return renderSyncResult(result, cache.Usage(), err)
+	},
+}
+
+var listMemoryCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List synchronized memories",
+	Long: `List the memories stored in the database, optionally filtered by tag, path prefix,
+modification time, type, importance and source. Archived memories are only listed with --archived.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseSince(listSince)
+		if err != nil {
+			return err
+		}
+		memoryType, err := model.ParseMemoryType(listMemoryType)
 		if err != nil {
-			return fmt.Errorf("sync failed: %w", err)
+			return err
+		}
+		if listMinImportance < 0 || listMinImportance > model.MaxImportance {
+			return fmt.Errorf("invalid --min-importance %d: must be between %d and %d", listMinImportance, model.MinImportance, model.MaxImportance)
+		}
+		filter := repository.MemoryFilter{
+			Tag:             listTag,
+			PathPrefix:      listPathPrefix,
+			ModifiedSince:   since,
+			Type:            memoryType,
+			MinImportance:   listMinImportance,
+			Source:          listSource,
+			IncludeArchived: listIncludeArchived,
+			Limit:           listLimit,
 		}
 
-		fmt.Println("Sync completed successfully")
-		return nil
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		listUsecase := memory.NewListUsecase(postgres.NewMemoryRepository(db))
+		memories, err := listUsecase.List(cmd.Context(), filter)
+		if err != nil {
+			return err
+		}
+
+		views := make(memoryListView, 0, len(memories))
+		for _, m := range memories {
+			views = append(views, newMemoryView(m, true))
+		}
+		return render(views)
+	},
+}
+
+var sweepMemoryCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Archive expired and superseded memories",
+	Long: `Archive the memories whose expires_at has passed and the memories listed in the
+supersedes field of an active memory. Archived memories stay in the database but are
+excluded from "memory list" unless --archived is given. This also runs after every memory sync.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		sweepUsecase := memory.NewSweepUsecase(postgres.NewMemoryRepository(db))
+		result, err := sweepUsecase.Sweep(cmd.Context(), time.Now())
+		if err != nil {
+			return err
+		}
+
+		return render(newMemorySweptView(result))
+	},
+}
+
+// memoryContentFlags are the flags of "memory add" and "memory edit" that give the content
+type memoryContentFlags struct {
+	content string
+	file    string
+}
+
+// read returns the content given with --content or --file ("-" reads stdin); ok is false if neither was set
+func (f *memoryContentFlags) read(cmd *cobra.Command) (content string, ok bool, err error) {
+	switch {
+	case cmd.Flags().Changed("content"):
+		return f.content, true, nil
+	case f.file == "-":
+		data, err := io.ReadAll(cmd.InOrStdin())
+		return string(data), true, err
+	case f.file != "":
+		data, err := os.ReadFile(f.file)
+		return string(data), true, err
+	default:
+		return "", false, nil
+	}
+}
+
+// memoryFrontmatterFlags are the frontmatter fields that can be set with "memory add" flags
+type memoryFrontmatterFlags struct {
+	Type       string   `yaml:"type,omitempty"`
+	Importance int      `yaml:"importance,omitempty"`
+	Source     string   `yaml:"source,omitempty"`
+	ExpiresAt  string   `yaml:"expires_at,omitempty"`
+	Supersedes []string `yaml:"supersedes,omitempty"`
+	Tags       []string `yaml:"tags,omitempty"`
+}
+
+// prepend adds the fields that were set as frontmatter to content, which must not have one already
+func (f *memoryFrontmatterFlags) prepend(content string) (string, error) {
+	if f.Type == "" && f.Importance == 0 && f.Source == "" && f.ExpiresAt == "" && len(f.Supersedes) == 0 && len(f.Tags) == 0 {
+		return content, nil
+	}
+	if strings.HasPrefix(content, "---") {
+		return "", fmt.Errorf("the content already has frontmatter: set the fields there instead of with flags")
+	}
+	fm, err := yaml.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	return "---\n" + string(fm) + "---\n" + content, nil
+}
+
+// editInEditor lets the user edit content in $VISUAL or $EDITOR, vi by default
+func editInEditor(ctx context.Context, content string) (string, error) {
+	file, err := os.CreateTemp("", "memory-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The editor may include arguments, e.g. "code --wait"
+	editorCmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$1"`, "sh", file.Name())
+	editorCmd.Stdin, editorCmd.Stdout, editorCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(edited), nil
+}
+
+// newMemorySaveUsecase wires the use case of "memory add" and "memory edit"
+func newMemorySaveUsecase(ctx *AppContext, db *sqlx.DB) (*memory.SaveUsecase, error) {
+	provider, _, err := newEmbeddingProvider(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewSaveUsecase(
+		postgres.NewMemoryRepository(db),
+		storageFactory.NewMemoryStorageFactory(ctx.Config.Memory.Repo, ctx.SnapshotCache()),
+		provider,
+		postgres.NewUsageRepository(db),
+	), nil
+}
+
+var (
+	addMemoryContent     memoryContentFlags
+	addMemoryFrontmatter memoryFrontmatterFlags
+	editMemoryContent    memoryContentFlags
+	rmMemoryYes          bool
+)
+
+var addMemoryCmd = &cobra.Command{
+	Use:   "add <path>",
+	Short: "Add a memory to the memory repository",
+	Long: `Commit a new memory file .memories/<path>.md to the memory repository and save it with its
+embedding, without a full sync. The content is given with --content or --file ("-" for stdin);
+the frontmatter flags are written as YAML frontmatter in front of it.`,
+	Example: `  personal-agent memory add preferences/editor --type preference --importance 4 --content "Prefers Neovim"
+  personal-agent memory add facts/home --file home.md`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+		if err := ctx.Config.RequireMemory(); err != nil {
+			return err
+		}
+		content, ok, err := addMemoryContent.read(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no content given: set --content or --file")
+		}
+		content, err = addMemoryFrontmatter.prepend(content)
+		if err != nil {
+			return err
+		}
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		saveUsecase, err := newMemorySaveUsecase(ctx, db)
+		if err != nil {
+			return err
+		}
+		if _, err := saveUsecase.Fetch(cmd.Context(), args[0]); err == nil {
+			return fmt.Errorf("memory %s already exists: use \"memory edit\" to change it", args[0])
+		} else if !errors.Is(err, storage.ErrMemoryNotFound) {
+			return err
+		}
+
+		saved, err := saveUsecase.Save(cmd.Context(), args[0], content)
+		if err != nil {
+			return err
+		}
+		return render(memorySavedView{Path: saved.Path, SHA: saved.SHA, TokenCount: saved.TokenCount, Saved: true})
+	},
+}
+
+var editMemoryCmd = &cobra.Command{
+	Use:   "edit <path>",
+	Short: "Edit a memory in the memory repository",
+	Long: `Open the current content of a memory from the memory repository in $VISUAL or $EDITOR,
+or replace it with --content or --file, then commit the change and save the memory with its
+embedding, without a full sync. Nothing is written if the content is unchanged.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+		if err := ctx.Config.RequireMemory(); err != nil {
+			return err
+		}
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		saveUsecase, err := newMemorySaveUsecase(ctx, db)
+		if err != nil {
+			return err
+		}
+		current, err := saveUsecase.Fetch(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		content, ok, err := editMemoryContent.read(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if content, err = editInEditor(cmd.Context(), current.Content); err != nil {
+				return err
+			}
+		}
+		if content == current.Content {
+			return render(memorySavedView{Path: current.Path, SHA: current.SHA, Saved: false})
+		}
+
+		saved, err := saveUsecase.Save(cmd.Context(), current.Path, content)
+		if err != nil {
+			return err
+		}
+		return render(memorySavedView{Path: saved.Path, SHA: saved.SHA, TokenCount: saved.TokenCount, Saved: true})
+	},
+}
+
+var rmMemoryCmd = &cobra.Command{
+	Use:   "rm <path>",
+	Short: "Delete a memory from the memory repository and the database",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+		if err := ctx.Config.RequireMemory(); err != nil {
+			return err
+		}
+		path, err := model.NormalizeMemoryPath(args[0])
+		if err != nil {
+			return err
+		}
+		if !rmMemoryYes && !confirm(fmt.Sprintf("Delete memory %s?", path)) {
+			return render(memoryDeletedView{Path: path, Deleted: false})
+		}
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		deleteUsecase := memory.NewDeleteUsecase(postgres.NewMemoryRepository(db), storageFactory.NewMemoryStorageFactory(ctx.Config.Memory.Repo, ctx.SnapshotCache()))
+		if err := deleteUsecase.Delete(cmd.Context(), path); err != nil {
+			return err
+		}
+		return render(memoryDeletedView{Path: path, Deleted: true})
+	},
+}
+
+var showMemoryCmd = &cobra.Command{
+	Use:   "show <path>",
+	Short: "Show a synchronized memory",
+	Long:  `Print the metadata, tags, SHA and embedding model of a memory followed by its content.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		showUsecase := memory.NewShowUsecase(postgres.NewMemoryRepository(db))
+		m, err := showUsecase.Show(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		return render(newMemoryView(m, true))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(memoryCmd)
 	memoryCmd.AddCommand(syncMemoryCmd)
+	memoryCmd.AddCommand(listMemoryCmd)
+	memoryCmd.AddCommand(sweepMemoryCmd)
+	memoryCmd.AddCommand(addMemoryCmd)
+	memoryCmd.AddCommand(editMemoryCmd)
+	memoryCmd.AddCommand(rmMemoryCmd)
+	memoryCmd.AddCommand(showMemoryCmd)
+
+	// Flag variables are shared with "document list"
+	listMemoryCmd.Flags().StringVar(&listTag, "tag", "", "Only list memories with this tag")
+	listMemoryCmd.Flags().StringVar(&listPathPrefix, "path-prefix", "", "Only list memories whose path starts with this prefix")
+	listMemoryCmd.Flags().StringVar(&listSince, "since", "", "Only list memories modified since a date (2006-01-02), RFC 3339 time or duration (7d)")
+	listMemoryCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of memories to list (0 for no limit)")
+	listMemoryCmd.Flags().StringVar(&listMemoryType, "type", "", "Only list memories of this type (preference, fact, instruction or event)")
+	listMemoryCmd.Flags().IntVar(&listMinImportance, "min-importance", 0, "Only list memories with at least this importance (1-5)")
+	listMemoryCmd.Flags().StringVar(&listSource, "source", "", "Only list memories from this source")
+	listMemoryCmd.Flags().BoolVar(&listIncludeArchived, "archived", false, "Also list archived memories")
+	addJSONAliasFlag(listMemoryCmd)
+
+	addMemoryCmd.Flags().StringVarP(&addMemoryContent.content, "content", "m", "", "Content of the memory")
+	addMemoryCmd.Flags().StringVarP(&addMemoryContent.file, "file", "f", "", `Read the content from a file ("-" for stdin)`)
+	addMemoryCmd.MarkFlagsMutuallyExclusive("content", "file")
+	editMemoryCmd.Flags().StringVarP(&editMemoryContent.content, "content", "m", "", "New content of the memory instead of opening an editor")
+	editMemoryCmd.Flags().StringVarP(&editMemoryContent.file, "file", "f", "", `Read the new content from a file ("-" for stdin)`)
+	editMemoryCmd.MarkFlagsMutuallyExclusive("content", "file")
+	addMemoryCmd.Flags().StringVar(&addMemoryFrontmatter.Type, "type", "", "Type of the memory (preference, fact, instruction or event)")
+	addMemoryCmd.Flags().IntVar(&addMemoryFrontmatter.Importance, "importance", 0, "Importance of the memory (1-5)")
+	addMemoryCmd.Flags().StringVar(&addMemoryFrontmatter.Source, "source", "", "Where the memory came from, e.g. a Slack thread URL")
+	addMemoryCmd.Flags().StringVar(&addMemoryFrontmatter.ExpiresAt, "expires-at", "", "Date (2006-01-02) or RFC 3339 time after which the memory is archived")
+	addMemoryCmd.Flags().StringSliceVar(&addMemoryFrontmatter.Supersedes, "supersedes", nil, "Paths of the memories this memory replaces")
+	addMemoryCmd.Flags().StringSliceVar(&addMemoryFrontmatter.Tags, "tag", nil, "Tags of the memory")
+	rmMemoryCmd.Flags().BoolVarP(&rmMemoryYes, "yes", "y", false, "Delete without asking for confirmation")
 
 	// Add flags for memory commands (dryRun is already declared in document.go)
 	syncMemoryCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Perform a trial run with no changes made")
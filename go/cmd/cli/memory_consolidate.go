@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
+	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
+	storageFactory "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/storage"
+	"github.com/bonyuta0204/personal-agent/go/internal/usecase/memory"
+	"github.com/spf13/cobra"
+)
+
+// Flags for consolidate commands
+var (
+	consolidateThreshold float64
+	consolidatePlanFile  string
+	consolidateApplyYes  bool
+)
+
+// defaultConsolidationPlanFile is where "memory consolidate plan" writes the plan
+const defaultConsolidationPlanFile = "memory-consolidation.json"
+
+var consolidateMemoryCmd = &cobra.Command{
+	Use:   "consolidate",
+	Short: "Merge near-duplicate memories",
+	Long: `Find memories whose embeddings are similar above a threshold and merge each cluster into
+its newest memory. "plan" writes the proposed merges to a file without changing anything;
+"apply" performs the merges of a reviewed plan in the memory repository and the database.`,
+}
+
+var consolidatePlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Propose merges of near-duplicate memories",
+	Long: `Cluster the active memories whose cosine similarity is at least --threshold, propose to
+merge every cluster into its most recently modified memory and write the plan to --out.
+The kept memory keeps its wording; its frontmatter combines the tags, importance, source and
+expiry of the cluster, and lists the merged memories under supersedes.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		// Planning neither writes to the memory repository nor embeds
+		consolidateUsecase := memory.NewConsolidateUsecase(postgres.NewMemoryRepository(db), nil, nil, nil)
+		plan, err := consolidateUsecase.Plan(cmd.Context(), consolidateThreshold)
+		if err != nil {
+			return err
+		}
+
+		view := consolidationPlanView{Plan: plan}
+		if len(plan.Merges) > 0 {
+			data, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(consolidatePlanFile, append(data, '\n'), 0o644); err != nil {
+				return fmt.Errorf("failed to write plan: %w", err)
+			}
+			view.File = consolidatePlanFile
+		}
+		return render(view)
+	},
+}
+
+var consolidateApplyCmd = &cobra.Command{
+	Use:   "apply [plan-file]",
+	Short: "Apply a reviewed consolidation plan",
+	Long: `Apply the merges of a plan written by "memory consolidate plan": the merged content is
+committed to the kept memory and embedded, and the merged memories are deleted from the memory
+repository and the database. Merges whose memories changed since the plan are skipped.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := GetAppContext()
+		if err := ctx.Config.RequireMemory(); err != nil {
+			return err
+		}
+		file := defaultConsolidationPlanFile
+		if len(args) > 0 {
+			file = args[0]
+		}
+		plan, err := readConsolidationPlan(file)
+		if err != nil {
+			return err
+		}
+		if len(plan.Merges) == 0 {
+			return fmt.Errorf("plan %s has no merges", file)
+		}
+		if !consolidateApplyYes {
+			consolidationPlanView{Plan: plan}.renderText(os.Stderr)
+			if !confirm(fmt.Sprintf("Apply %d merges?", len(plan.Merges))) {
+				return render(consolidationResultListView{})
+			}
+		}
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		provider, _, err := newEmbeddingProvider(ctx, db)
+		if err != nil {
+			return err
+		}
+		consolidateUsecase := memory.NewConsolidateUsecase(
+			postgres.NewMemoryRepository(db),
+			storageFactory.NewMemoryStorageFactory(ctx.Config.Memory.Repo, ctx.SnapshotCache()),
+			provider,
+			postgres.NewUsageRepository(db),
+		)
+		results, err := consolidateUsecase.Apply(cmd.Context(), plan)
+
+		views := make(consolidationResultListView, 0, len(results))
+		for _, r := range results {
+			views = append(views, newConsolidationResultView(r))
+		}
+		if renderErr := render(views); renderErr != nil {
+			return renderErr
+		}
+		return err
+	},
+}
+
+// readConsolidationPlan reads a plan written by "memory consolidate plan"
+func readConsolidationPlan(file string) (*model.ConsolidationPlan, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var plan model.ConsolidationPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", file, err)
+	}
+	return &plan, nil
+}
+
+// consolidationPlanView is the result of "memory consolidate plan"
+type consolidationPlanView struct {
+	Plan *model.ConsolidationPlan `json:"plan"`
+	File string                   `json:"file,omitempty"`
+}
+
+func (v consolidationPlanView) renderText(w io.Writer) {
+	if len(v.Plan.Merges) == 0 {
+		fmt.Fprintf(w, "No memories are at least %.2f similar\n", v.Plan.Threshold)
+		return
+	}
+	for _, m := range v.Plan.Merges {
+		fmt.Fprintf(w, "~ %s (min similarity %.3f)\n", m.Keep, m.Similarity)
+		for _, path := range m.Merge {
+			fmt.Fprintf(w, "  - %s\n", path)
+		}
+		for _, line := range strings.Split(strings.TrimRight(m.Content, "\n"), "\n") {
+			fmt.Fprintf(w, "    | %s\n", line)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d merges planned\n", len(v.Plan.Merges))
+	if v.File != "" {
+		fmt.Fprintf(w, "Plan written to %s; apply it with: personal-agent memory consolidate apply %s\n", v.File, v.File)
+	}
+}
+
+// consolidationResultView is the structured representation of one applied merge
+type consolidationResultView struct {
+	Keep    string   `json:"keep"`
+	Merge   []string `json:"merge"`
+	Applied bool     `json:"applied"`
+	Error   string   `json:"error,omitempty"`
+}
+
+func newConsolidationResultView(r memory.ConsolidationResult) consolidationResultView {
+	view := consolidationResultView{Keep: r.Merge.Keep, Merge: nonNilStrings(r.Merge.Merge), Applied: r.Applied}
+	if r.Err != nil {
+		view.Error = r.Err.Error()
+	}
+	return view
+}
+
+// consolidationResultListView is the result of "memory consolidate apply"
+type consolidationResultListView []consolidationResultView
+
+func (v consolidationResultListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v consolidationResultListView) renderText(w io.Writer) {
+	if len(v) == 0 {
+		fmt.Fprintln(w, "Aborted")
+		return
+	}
+	applied := 0
+	for _, r := range v {
+		if r.Applied {
+			applied++
+			fmt.Fprintf(w, "merged    %s <- %s\n", r.Keep, strings.Join(r.Merge, ", "))
+		} else {
+			fmt.Fprintf(w, "failed    %s: %s\n", r.Keep, r.Error)
+		}
+	}
+	fmt.Fprintf(w, "%d of %d merges applied\n", applied, len(v))
+}
+
+func init() {
+	memoryCmd.AddCommand(consolidateMemoryCmd)
+	consolidateMemoryCmd.AddCommand(consolidatePlanCmd)
+	consolidateMemoryCmd.AddCommand(consolidateApplyCmd)
+
+	consolidatePlanCmd.Flags().Float64Var(&consolidateThreshold, "threshold", model.DefaultConsolidationThreshold, "Minimum cosine similarity of memories to merge (0-1]")
+	consolidatePlanCmd.Flags().StringVar(&consolidatePlanFile, "out", defaultConsolidationPlanFile, "File to write the plan to")
+	consolidateApplyCmd.Flags().BoolVarP(&consolidateApplyYes, "yes", "y", false, "Apply without showing the plan and asking for confirmation")
+}
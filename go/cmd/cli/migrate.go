@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema",
+	Long: `Apply, roll back or inspect the database migrations embedded in this binary.
+Applied versions are recorded in the table named by database.migrations_table (GOOSE_TABLE).`,
+}
+
+// withMigrator connects to the database without the schema check and runs fn
+func withMigrator(cmd *cobra.Command, fn func(m *database.Migrator) error) error {
+	ctx := GetAppContext()
+
+	db, err := database.NewDBConnection(cmd.Context(), &ctx.Config.Database)
+	if err != nil {
+		return fmt.Errorf("database connection error: %w", err)
+	}
+	defer database.CloseDB(db)
+
+	migrator, err := database.NewMigrator(db, &ctx.Config.Database)
+	if err != nil {
+		return err
+	}
+	return fn(migrator)
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(cmd, func(m *database.Migrator) error {
+			results, err := m.Up(cmd.Context())
+			if renderErr := render(newMigrationResultListView(results)); renderErr != nil {
+				return renderErr
+			}
+			return err
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the most recently applied migration",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(cmd, func(m *database.Migrator) error {
+			result, err := m.Down(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return render(newMigrationResultListView([]database.MigrationResult{*result}))
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which migrations have been applied",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(cmd, func(m *database.Migrator) error {
+			statuses, err := m.Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			views := make(migrationStatusListView, 0, len(statuses))
+			for _, s := range statuses {
+				view := migrationStatusView{Version: s.Version, Name: s.Name, Applied: s.Applied}
+				if s.Applied {
+					appliedAt := s.AppliedAt
+					view.AppliedAt = &appliedAt
+				}
+				views = append(views, view)
+			}
+			return render(views)
+		})
+	},
+}
+
+// migrationResultView is the structured representation of an applied migration
+type migrationResultView struct {
+	Version    int64  `json:"version"`
+	Name       string `json:"name"`
+	Direction  string `json:"direction"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// migrationResultListView is the result of "migrate up" and "migrate down"
+type migrationResultListView []migrationResultView
+
+func newMigrationResultListView(results []database.MigrationResult) migrationResultListView {
+	views := make(migrationResultListView, 0, len(results))
+	for _, r := range results {
+		views = append(views, migrationResultView{
+			Version:    r.Version,
+			Name:       r.Name,
+			Direction:  r.Direction,
+			DurationMS: r.Duration.Milliseconds(),
+		})
+	}
+	return views
+}
+
+func (v migrationResultListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v migrationResultListView) renderText(w io.Writer) {
+	if len(v) == 0 {
+		fmt.Fprintln(w, "No migrations to apply; the schema is up to date")
+		return
+	}
+	for _, r := range v {
+		fmt.Fprintf(w, "%-4s %s (%s)\n", r.Direction, r.Name, time.Duration(r.DurationMS)*time.Millisecond)
+	}
+}
+
+// migrationStatusView is the structured representation of a migration's state
+type migrationStatusView struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// migrationStatusListView is the result of "migrate status"
+type migrationStatusListView []migrationStatusView
+
+func (v migrationStatusListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v migrationStatusListView) renderText(w io.Writer) {
+	fmt.Fprintln(w, "Applied At                | Migration")
+	fmt.Fprintln(w, "--------------------------|----------")
+	for _, s := range v {
+		appliedAt := "Pending"
+		if s.AppliedAt != nil {
+			appliedAt = formatTime(s.AppliedAt)
+		}
+		fmt.Fprintf(w, "%-25s | %s\n", appliedAt, s.Name)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+// outputFormat is set by the global --output flag
+var outputFormat = outputText
+
+// validateOutputFormat checks the value of the --output flag
+func validateOutputFormat() error {
+	switch outputFormat {
+	case outputText, outputJSON, outputNDJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid --output %q: must be one of text, json, ndjson", outputFormat)
+	}
+}
+
+// jsonAliasFlag is the value of the --json flag that some commands had before --output.
+// Setting it selects the JSON output format.
+type jsonAliasFlag bool
+
+func (f *jsonAliasFlag) String() string { return strconv.FormatBool(bool(*f)) }
+
+func (f *jsonAliasFlag) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*f = jsonAliasFlag(v)
+	if v {
+		outputFormat = outputJSON
+	}
+	return nil
+}
+
+func (f *jsonAliasFlag) Type() string { return "bool" }
+
+func (f *jsonAliasFlag) IsBoolFlag() bool { return true }
+
+// addJSONAliasFlag adds the deprecated --json flag to cmd as an alias of --output json,
+// so that scripts written before --output keep working
+func addJSONAliasFlag(cmd *cobra.Command) {
+	flag := cmd.Flags().VarPF(new(jsonAliasFlag), "json", "", "Print the result as JSON")
+	flag.NoOptDefVal = "true"
+	_ = cmd.Flags().MarkDeprecated("json", "use --output json instead")
+}
+
+// result is implemented by every command result
+type result interface {
+	// renderText writes the human readable form of the result
+	renderText(w io.Writer)
+}
+
+// listResult is implemented by results that are a list of records.
+// In NDJSON mode each record is written on its own line.
+type listResult interface {
+	result
+	records() []interface{}
+}
+
+// render writes the result to stdout in the selected output format
+func render(r result) error {
+	switch outputFormat {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case outputNDJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		if list, ok := r.(listResult); ok {
+			for _, record := range list.records() {
+				if err := encoder.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return encoder.Encode(r)
+	default:
+		r.renderText(os.Stdout)
+		return nil
+	}
+}
+
+// status prints a progress message for humans. It is written to stdout in
+// text mode and to stderr otherwise so that stdout stays machine readable.
+func status(format string, args ...interface{}) {
+	w := io.Writer(os.Stdout)
+	if outputFormat != outputText {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+// syncEventView is the NDJSON representation of a sync progress event
+type syncEventView struct {
+	Type  model.SyncEventType `json:"type"`
+	Path  string              `json:"path,omitempty"`
+	From  string              `json:"from,omitempty"`
+	Stage string              `json:"stage,omitempty"`
+	Error string              `json:"error,omitempty"`
+	Total int                 `json:"total,omitempty"`
+	Time  time.Time           `json:"time"`
+}
+
+// newSyncEventHandler returns a handler that streams sync events as NDJSON
+// to stdout in ndjson mode, and nil otherwise
+func newSyncEventHandler() model.SyncEventHandler {
+	if outputFormat != outputNDJSON {
+		return nil
+	}
+
+	var mu sync.Mutex
+	encoder := json.NewEncoder(os.Stdout)
+	return func(event model.SyncEvent) {
+		view := syncEventView{
+			Type:  event.Type,
+			Path:  event.Path,
+			From:  event.From,
+			Stage: event.Stage,
+			Total: event.Total,
+			Time:  event.Time,
+		}
+		if event.Error != nil {
+			view.Error = event.Error.Error()
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		_ = encoder.Encode(view)
+	}
+}
+
+// renderSyncResult renders the result of a sync. An interrupted sync returns its partial
+// result together with the error, so the entries processed before it stopped are reported.
+func renderSyncResult(result *model.SyncResult, cacheUsage model.EmbeddingCacheUsage, err error) error {
+	if result == nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+	view := newSyncResultView(result).withCacheUsage(cacheUsage)
+	view.Interrupted = err != nil
+	if renderErr := render(view); renderErr != nil {
+		return renderErr
+	}
+	if err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+	return nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// shortSHA abbreviates a SHA for table output
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// orDash shows an empty string as "-" in tables
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func formatPatterns(patterns []string) string {
+	if len(patterns) == 0 {
+		return "-"
+	}
+	return strings.Join(patterns, ", ")
+}
+
+// optionalTime returns nil for the zero time so that it is omitted or shown as "never"
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+// formatSizeKB formats a size reported in kilobytes
+func formatSizeKB(kb int) string {
+	switch {
+	case kb >= 1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(kb)/(1024*1024))
+	case kb >= 1024:
+		return fmt.Sprintf("%.1f MB", float64(kb)/1024)
+	default:
+		return fmt.Sprintf("%d KB", kb)
+	}
+}
+
+// formatEmbedding describes the embedding of a document or memory
+func formatEmbedding(embeddingModel string, dimensions int) string {
+	if dimensions == 0 {
+		return "none"
+	}
+	if embeddingModel == "" {
+		embeddingModel = "unknown model"
+	}
+	return fmt.Sprintf("%s (%d dimensions)", embeddingModel, dimensions)
+}
+
+// formatCost formats an estimated cost in USD; nil means the price is unknown
+func formatCost(cost *float64) string {
+	if cost == nil {
+		return "unknown price"
+	}
+	return fmt.Sprintf("$%.4f", *cost)
+}
+
+// parseSince parses a --since value. It accepts RFC 3339 timestamps, dates
+// (2006-01-02) and relative durations such as 36h, 7d or 2w.
+func parseSince(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	// Relative durations with day and week units
+	if n := len(value); n > 1 && (value[n-1] == 'd' || value[n-1] == 'w') {
+		count, err := strconv.Atoi(value[:n-1])
+		if err == nil && count >= 0 {
+			days := count
+			if value[n-1] == 'w' {
+				days *= 7
+			}
+			return time.Now().AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return time.Now().Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --since value %q: use a date (2006-01-02), RFC 3339 time or duration (36h, 7d, 2w)", value)
+}
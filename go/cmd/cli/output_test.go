@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestJSONAliasFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "default", args: nil, want: outputText},
+		{name: "output flag", args: []string{"--output", "json"}, want: outputJSON},
+		{name: "short output flag", args: []string{"-o", "ndjson"}, want: outputNDJSON},
+		{name: "deprecated json flag", args: []string{"--json"}, want: outputJSON},
+		{name: "deprecated json flag set explicitly", args: []string{"--json=true"}, want: outputJSON},
+		{name: "deprecated json flag disabled", args: []string{"--json=false"}, want: outputText},
+	}
+	commands := map[string]*cobra.Command{
+		"document list": listDocumentCmd,
+		"document show": showDocumentCmd,
+		"memory list":   listMemoryCmd,
+	}
+	for name, cmd := range commands {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				outputFormat = outputText
+				t.Cleanup(func() { outputFormat = outputText })
+
+				if err := cmd.ParseFlags(tt.args); err != nil {
+					t.Fatalf("ParseFlags(%v) failed: %v", tt.args, err)
+				}
+				if outputFormat != tt.want {
+					t.Errorf("output format = %q, want %q", outputFormat, tt.want)
+				}
+			})
+		}
+	}
+}
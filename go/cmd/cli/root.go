@@ -2,8 +2,15 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/bonyuta0204/personal-agent/go/config"
 	"github.com/spf13/cobra"
 )
 
@@ -13,16 +20,55 @@ var rootCmd = &cobra.Command{
 	Short: "Personal Agent is a tool for managing personal documents",
 	Long: `A CLI tool for managing and syncing personal documents
 across different storage providers.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+
+		// Load and validate configuration
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+
+		// Create application context with the loaded configuration
+		appContext = NewAppContext(cfg)
+
+		if commandTimeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), commandTimeout)
+			cancelTimeout = cancel
+			cmd.SetContext(ctx)
+		}
+		return nil
+	},
 }
 
 // appContext holds the application context including configuration
 var appContext *AppContext
 
+// configFile is set by the global --config flag
+var configFile string
+
+// commandTimeout is set by the global --timeout flag; zero means no deadline
+var commandTimeout time.Duration
+
+// cancelTimeout releases the deadline set by --timeout
+var cancelTimeout context.CancelFunc = func() {}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
-// This is called by main.main().
-func Execute(ctx *AppContext) {
-	appContext = ctx
-	if err := rootCmd.Execute(); err != nil {
+// This is called by main.main(). The first Ctrl-C or SIGTERM cancels the context of the
+// running command so that it stops cleanly; a second one terminates the process.
+func Execute() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -33,5 +79,13 @@ func GetAppContext() *AppContext {
 }
 
 func init() {
-	// Add global flags here if needed
+	// Logs always go to stderr; stdout is reserved for command results
+	log.SetOutput(os.Stderr)
+
+	// Run the root PersistentPreRunE in addition to the hooks of subcommands
+	cobra.EnableTraverseRunHooks = true
+
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to the YAML config file (default $PERSONAL_AGENT_CONFIG or ./personal-agent.yaml)")
+	rootCmd.PersistentFlags().DurationVar(&commandTimeout, "timeout", 0, "Abort the command after this duration, e.g. 30s or 1h (default no timeout)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "Output format: text, json or ndjson")
 }
@@ -1,12 +1,15 @@
 package main
 
 import (
-	"database/sql"
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
 	postgresRepo "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
+	storageFactory "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/storage"
 	storeusecase "github.com/bonyuta0204/personal-agent/go/internal/usecase/store"
 	"github.com/spf13/cobra"
 )
@@ -22,48 +25,59 @@ var storeCmd = &cobra.Command{
 	},
 }
 
+var (
+	// Flags for create command
+	createType    string
+	createRef     string
+	createInclude []string
+	createExclude []string
+)
+
 var createStoreCmd = &cobra.Command{
 	Use:   "create [repository]",
 	Short: "Create a new document store",
-	Long:  `Create a new document store. For GitHub repositories, use the format "owner/repo".`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Create a new document store. For GitHub repositories, use the format "owner/repo".
+HTTPS URLs and SSH remotes are accepted and normalized. The repository must be
+readable with the configured GITHUB_TOKEN.
+
+With --type git, the repository is the URL of any git remote (GitLab, Gitea, Bitbucket,
+self-hosted or file://). It is fetched into a local mirror without GITHUB_TOKEN; SSH
+remotes authenticate with the SSH agent, and HTTP remotes with GIT_PASSWORD (a password
+or access token) and GIT_USERNAME, so that credentials need not be part of the URL.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := args[0]
 		ctx := GetAppContext()
 
 		// Initialize database connection
-		db, err := database.NewDBConnection(&ctx.Config.Database)
+		db, err := ctx.OpenDatabase(cmd.Context())
 		if err != nil {
-			return fmt.Errorf("database connection error: %w", err)
+			return err
 		}
 		defer database.CloseDB(db)
 
 		// Initialize repository and use case
 		repository := postgresRepo.NewStoreRepository(db)
-		createUsecase := storeusecase.NewCreateUsecase(repository)
+		createUsecase := storeusecase.NewCreateUsecase(repository, storageFactory.NewStoreValidator())
 
 		// Create the store
-		store, err := createUsecase.Create(repo)
+		result, err := createUsecase.Create(cmd.Context(), storeusecase.CreateInput{
+			Type: createType,
+			Repo: repo,
+			Ref:  createRef,
+			Filters: model.StoreFilters{
+				Include: nonEmpty(createInclude),
+				Exclude: nonEmpty(createExclude),
+			},
+		})
 		if err != nil {
 			return fmt.Errorf("failed to create store: %w", err)
 		}
 
-		// Print success message
-		fmt.Printf("Successfully created store with ID: %d\n", store.ID())
-		fmt.Printf("Type: %s, Repository: %s\n", store.Type(), store.(*model.GitHubStore).Repo())
-
-		return nil
+		return render(newStoreCreatedView(result))
 	},
 }
 
-// listStoresResponse represents the structure of a store in the list
-// This is a simplified version of the Store model for listing purposes
-type listStoresResponse struct {
-	ID   uint   `db:"id"`
-	Type string `db:"type"`
-	Repo string `db:"repo"`
-}
-
 var listStoresCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all document stores",
@@ -71,46 +85,197 @@ var listStoresCmd = &cobra.Command{
 		ctx := GetAppContext()
 
 		// Initialize database connection
-		db, err := database.NewDBConnection(&ctx.Config.Database)
+		db, err := ctx.OpenDatabase(cmd.Context())
 		if err != nil {
-			return fmt.Errorf("database connection error: %w", err)
+			return err
 		}
 		defer database.CloseDB(db)
 
-		// Query to list all stores
-		query := `SELECT id, type, repo FROM stores ORDER BY id`
+		listUsecase := storeusecase.NewListUsecase(postgresRepo.NewStoreRepository(db))
+		stores, err := listUsecase.List(cmd.Context())
+		if err != nil {
+			return err
+		}
 
-		var stores []listStoresResponse
-		if err := db.Select(&stores, query); err != nil {
-			if err == sql.ErrNoRows {
-				fmt.Println("No document stores found")
-				return nil
-			}
-			return fmt.Errorf("failed to list stores: %w", err)
+		views := make(storeListView, 0, len(stores))
+		for _, store := range stores {
+			views = append(views, newStoreView(store))
 		}
+		return render(views)
+	},
+}
 
-		if len(stores) == 0 {
-			fmt.Println("No document stores found")
-			return nil
+var showStoreCmd = &cobra.Command{
+	Use:   "show <store-id>",
+	Short: "Show details of a document store",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storeID, err := parseStoreID(args[0])
+		if err != nil {
+			return err
 		}
 
-		// Print stores in a table format
-		fmt.Println("ID  | Type    | Repository")
-		fmt.Println("----|---------|-----------")
-		for _, store := range stores {
-			fmt.Printf("%-3d | %-7s | %s\n", store.ID, store.Type, store.Repo)
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
 		}
+		defer database.CloseDB(db)
 
-		return nil
+		showUsecase := storeusecase.NewShowUsecase(postgresRepo.NewStoreRepository(db))
+		detail, err := showUsecase.Show(cmd.Context(), storeID)
+		if err != nil {
+			return err
+		}
+
+		return render(newStoreDetailView(detail))
 	},
 }
 
+var (
+	// Flags for update command
+	updateRepo    string
+	updateRef     string
+	updateInclude []string
+	updateExclude []string
+)
+
+var updateStoreCmd = &cobra.Command{
+	Use:   "update <store-id>",
+	Short: "Update the settings of a document store",
+	Long: `Update the repository, ref or path filters of a document store.
+Only the given flags are changed. Pass an empty value (e.g. --include "") to clear a filter.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storeID, err := parseStoreID(args[0])
+		if err != nil {
+			return err
+		}
+
+		var input storeusecase.UpdateInput
+		if cmd.Flags().Changed("repo") {
+			input.Repo = &updateRepo
+		}
+		if cmd.Flags().Changed("ref") {
+			input.Ref = &updateRef
+		}
+		if cmd.Flags().Changed("include") {
+			include := nonEmpty(updateInclude)
+			input.Include = &include
+		}
+		if cmd.Flags().Changed("exclude") {
+			exclude := nonEmpty(updateExclude)
+			input.Exclude = &exclude
+		}
+		if input == (storeusecase.UpdateInput{}) {
+			return fmt.Errorf("nothing to update: specify at least one of --repo, --ref, --include, --exclude")
+		}
+
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		updateUsecase := storeusecase.NewUpdateUsecase(postgresRepo.NewStoreRepository(db), storageFactory.NewStoreValidator())
+		store, err := updateUsecase.Update(cmd.Context(), storeID, input)
+		if err != nil {
+			return err
+		}
+
+		return render(storeUpdatedView{Store: newStoreView(store)})
+	},
+}
+
+// Flags for delete command
+var deleteYes bool
+
+var deleteStoreCmd = &cobra.Command{
+	Use:   "delete <store-id>",
+	Short: "Delete a document store and all of its documents",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storeID, err := parseStoreID(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		repository := postgresRepo.NewStoreRepository(db)
+
+		if !deleteYes {
+			detail, err := storeusecase.NewShowUsecase(repository).Show(cmd.Context(), storeID)
+			if err != nil {
+				return err
+			}
+			prompt := fmt.Sprintf("Delete store %d (%s) and its %d documents?",
+				detail.Store.ID(), newStoreView(detail.Store).location(), detail.Stats.DocumentCount)
+			if !confirm(prompt) {
+				return render(storeDeletedView{ID: storeID, Deleted: false})
+			}
+		}
+
+		if err := storeusecase.NewDeleteUsecase(repository).Delete(cmd.Context(), storeID); err != nil {
+			return err
+		}
+
+		return render(storeDeletedView{ID: storeID, Deleted: true})
+	},
+}
+
+// nonEmpty drops empty values so that an empty flag clears a list
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// confirm asks the user a yes/no question on stdin; the default answer is no.
+// The prompt is written to stderr so that it does not mix with structured output.
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 func init() {
 	rootCmd.AddCommand(storeCmd)
 	storeCmd.AddCommand(createStoreCmd)
 	storeCmd.AddCommand(listStoresCmd)
+	storeCmd.AddCommand(showStoreCmd)
+	storeCmd.AddCommand(updateStoreCmd)
+	storeCmd.AddCommand(deleteStoreCmd)
+
+	createStoreCmd.Flags().StringVar(&createType, "type", model.StoreTypeGitHub, "Store type: github, or git for any git remote URL")
+	createStoreCmd.Flags().StringVar(&createRef, "ref", "", "Branch, tag or commit to sync (default branch if omitted)")
+	createStoreCmd.Flags().StringSliceVar(&createInclude, "include", nil, "Glob patterns of paths to include (repeatable)")
+	createStoreCmd.Flags().StringSliceVar(&createExclude, "exclude", nil, "Glob patterns of paths to exclude (repeatable)")
+
+	updateStoreCmd.Flags().StringVar(&updateRepo, "repo", "", "GitHub repository in owner/repo format, or the remote URL of a git store")
+	updateStoreCmd.Flags().StringVar(&updateRef, "ref", "", "Branch, tag or commit to sync (empty for the default branch)")
+	updateStoreCmd.Flags().StringSliceVar(&updateInclude, "include", nil, "Glob patterns of paths to include (repeatable)")
+	updateStoreCmd.Flags().StringSliceVar(&updateExclude, "exclude", nil, "Glob patterns of paths to exclude (repeatable)")
 
-	// Add flags for store commands here if needed
-	// Example:
-	// createStoreCmd.Flags().StringP("type", "t", "github", "Type of the store (e.g., github)")
+	deleteStoreCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Delete without asking for confirmation")
 }
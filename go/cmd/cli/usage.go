@@ -0,0 +1,51 @@
+// Package main implements the usage command for the personal-agent CLI.
+package main
+
+import (
+	database "github.com/bonyuta0204/personal-agent/go/internal/infrastructure/database"
+	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/repository/postgres"
+	"github.com/bonyuta0204/personal-agent/go/internal/usecase/usage"
+	"github.com/spf13/cobra"
+)
+
+// Flags for usage command
+var usageSince string
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show embedding token usage and estimated cost",
+	Long: `Show the embedding requests and tokens billed by document and memory syncs, per store
+and model, with the estimated cost. Prices default to the OpenAI list prices and can be
+overridden with embedding.prices in the config file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseSince(usageSince)
+		if err != nil {
+			return err
+		}
+
+		ctx := GetAppContext()
+
+		// Initialize database connection
+		db, err := ctx.OpenDatabase(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer database.CloseDB(db)
+
+		reportUsecase := usage.NewReportUsecase(postgres.NewUsageRepository(db), ctx.Config.Embedding.Prices)
+		report, err := reportUsecase.Report(cmd.Context(), since)
+		if err != nil {
+			return err
+		}
+
+		return render(newUsageReportView(report))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "Only syncs started since a date, time or duration (e.g. 2024-01-01, 30d)")
+}
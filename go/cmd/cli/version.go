@@ -1,8 +1,6 @@
 package main
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
 )
 
@@ -11,8 +9,8 @@ var version = "dev" // This will be set during build
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of Personal Agent",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Personal Agent %s\n", version)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return render(versionView{Version: version})
 	},
 }
 
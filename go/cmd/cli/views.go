@@ -0,0 +1,903 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/storage"
+	storeusecase "github.com/bonyuta0204/personal-agent/go/internal/usecase/store"
+	"github.com/bonyuta0204/personal-agent/go/internal/usecase/usage"
+)
+
+// storeView is the structured representation of a store
+type storeView struct {
+	ID           model.StoreId      `json:"id"`
+	Type         string             `json:"type"`
+	Repo         string             `json:"repo,omitempty"` // owner/repo, or the remote URL of a git store
+	Ref          string             `json:"ref,omitempty"`
+	Filters      model.StoreFilters `json:"filters"`
+	LastSyncedAt *time.Time         `json:"last_synced_at"`
+}
+
+func newStoreView(store model.DocumentStore) storeView {
+	view := storeView{
+		ID:           store.ID(),
+		Type:         store.Type(),
+		Filters:      store.Filters(),
+		LastSyncedAt: store.LastSyncedAt(),
+	}
+	switch s := store.(type) {
+	case *model.GitHubStore:
+		view.Repo = s.Repo()
+		view.Ref = s.Ref()
+	case *model.GitStore:
+		view.Repo = model.RedactGitURL(s.URL())
+		view.Ref = s.Ref()
+	}
+	return view
+}
+
+// location returns a human readable location of the store
+func (v storeView) location() string {
+	if v.Ref != "" {
+		return v.Repo + "@" + v.Ref
+	}
+	return v.Repo
+}
+
+// storeListView is the result of "store list"
+type storeListView []storeView
+
+func (v storeListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v storeListView) renderText(w io.Writer) {
+	if len(v) == 0 {
+		fmt.Fprintln(w, "No document stores found")
+		return
+	}
+
+	// Print stores in a table format
+	fmt.Fprintln(w, "ID  | Type    | Repository")
+	fmt.Fprintln(w, "----|---------|-----------")
+	for _, store := range v {
+		fmt.Fprintf(w, "%-3d | %-7s | %s\n", store.ID, store.Type, store.location())
+	}
+}
+
+// storeStatsView is the structured representation of store statistics
+type storeStatsView struct {
+	DocumentCount  int        `json:"document_count"`
+	EmbeddedCount  int        `json:"embedded_count"`
+	LastModifiedAt *time.Time `json:"last_modified_at"`
+}
+
+// storeDetailView is the result of "store show"
+type storeDetailView struct {
+	storeView
+	Stats storeStatsView `json:"stats"`
+}
+
+func newStoreDetailView(detail *storeusecase.StoreDetail) storeDetailView {
+	return storeDetailView{
+		storeView: newStoreView(detail.Store),
+		Stats: storeStatsView{
+			DocumentCount:  detail.Stats.DocumentCount,
+			EmbeddedCount:  detail.Stats.EmbeddedCount,
+			LastModifiedAt: detail.Stats.LastModifiedAt,
+		},
+	}
+}
+
+func (v storeDetailView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "ID:          %d\n", v.ID)
+	fmt.Fprintf(w, "Type:        %s\n", v.Type)
+	if v.Type == model.StoreTypeGitHub || v.Type == model.StoreTypeGit {
+		ref := v.Ref
+		if ref == "" {
+			ref = "(default branch)"
+		}
+		fmt.Fprintf(w, "Repository:  %s\n", v.Repo)
+		fmt.Fprintf(w, "Ref:         %s\n", ref)
+	}
+	fmt.Fprintf(w, "Include:     %s\n", formatPatterns(v.Filters.Include))
+	fmt.Fprintf(w, "Exclude:     %s\n", formatPatterns(v.Filters.Exclude))
+	fmt.Fprintf(w, "Documents:   %d (%d embedded)\n", v.Stats.DocumentCount, v.Stats.EmbeddedCount)
+	fmt.Fprintf(w, "Last sync:   %s\n", formatTime(v.LastSyncedAt))
+	fmt.Fprintf(w, "Last change: %s\n", formatTime(v.Stats.LastModifiedAt))
+}
+
+// repositoryInfoView is the structured representation of a verified repository
+type repositoryInfoView struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+	SizeKB        int    `json:"size_kb"`
+	Private       bool   `json:"private"`
+}
+
+// storeCreatedView is the result of "store create"
+type storeCreatedView struct {
+	Store      storeView           `json:"store"`
+	Repository *repositoryInfoView `json:"repository,omitempty"`
+}
+
+func newStoreCreatedView(result *storeusecase.CreateResult) storeCreatedView {
+	view := storeCreatedView{Store: newStoreView(result.Store)}
+	if info := result.Repository; info != nil {
+		view.Repository = &repositoryInfoView{
+			FullName:      info.FullName,
+			DefaultBranch: info.DefaultBranch,
+			SizeKB:        info.SizeKB,
+			Private:       info.Private,
+		}
+	}
+	return view
+}
+
+func (v storeCreatedView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Successfully created store with ID: %d\n", v.Store.ID)
+	fmt.Fprintf(w, "Type: %s, Repository: %s\n", v.Store.Type, v.Store.location())
+	if v.Repository != nil {
+		fmt.Fprintf(w, "Default branch: %s", v.Repository.DefaultBranch)
+		// The size is unknown for git stores
+		if v.Repository.SizeKB > 0 {
+			fmt.Fprintf(w, ", Size: ~%s", formatSizeKB(v.Repository.SizeKB))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// storeUpdatedView is the result of "store update"
+type storeUpdatedView struct {
+	Store storeView `json:"store"`
+}
+
+func (v storeUpdatedView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Successfully updated store %d\n", v.Store.ID)
+}
+
+// storeDeletedView is the result of "store delete"
+type storeDeletedView struct {
+	ID      model.StoreId `json:"id"`
+	Deleted bool          `json:"deleted"`
+}
+
+func (v storeDeletedView) renderText(w io.Writer) {
+	if !v.Deleted {
+		fmt.Fprintln(w, "Aborted")
+		return
+	}
+	fmt.Fprintf(w, "Successfully deleted store %d\n", v.ID)
+}
+
+// documentView is the structured representation of a document
+type documentView struct {
+	StoreID        model.StoreId `json:"store_id"`
+	Path           string        `json:"path"`
+	SHA            string        `json:"sha"`
+	BodySHA        string        `json:"body_sha,omitempty"`
+	MetadataSHA    string        `json:"metadata_sha,omitempty"`
+	Tags           []string      `json:"tags"`
+	EmbeddingModel string        `json:"embedding_model,omitempty"`
+	EmbeddingDim   int           `json:"embedding_dimensions,omitempty"`
+	TokenCount     int           `json:"token_count,omitempty"`
+	ModifiedAt     time.Time     `json:"modified_at"`
+	CommitSHA      string        `json:"commit_sha,omitempty"`
+	Author         string        `json:"author,omitempty"`
+	CommitMessage  string        `json:"commit_message,omitempty"`
+	Encoding       string        `json:"encoding,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Content        string        `json:"content,omitempty"`
+}
+
+func newDocumentView(d *model.Document, withContent bool) documentView {
+	view := documentView{
+		StoreID:        d.StoreId,
+		Path:           d.Path,
+		SHA:            d.SHA,
+		BodySHA:        d.BodySHA,
+		MetadataSHA:    d.MetadataSHA,
+		Tags:           nonNilStrings(d.Tags),
+		EmbeddingModel: d.EmbeddingModel,
+		EmbeddingDim:   len(d.Embedding),
+		TokenCount:     d.TokenCount,
+		ModifiedAt:     d.ModifiedAt,
+		CommitSHA:      d.LastCommit.SHA,
+		Author:         d.LastCommit.Author,
+		CommitMessage:  d.LastCommit.Message,
+		Encoding:       d.Encoding,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+	if withContent {
+		view.Content = d.Content
+	}
+	return view
+}
+
+func (v documentView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Store:     %d\n", v.StoreID)
+	fmt.Fprintf(w, "Path:      %s\n", v.Path)
+	fmt.Fprintf(w, "SHA:       %s\n", v.SHA)
+	fmt.Fprintf(w, "Tags:      %s\n", formatPatterns(v.Tags))
+	fmt.Fprintf(w, "Embedding: %s\n", formatEmbedding(v.EmbeddingModel, v.EmbeddingDim))
+	if v.TokenCount > 0 {
+		fmt.Fprintf(w, "Tokens:    %d\n", v.TokenCount)
+	}
+	fmt.Fprintf(w, "Modified:  %s\n", formatTime(&v.ModifiedAt))
+	if v.CommitSHA != "" {
+		fmt.Fprintf(w, "Commit:    %s by %s: %s\n", shortSHA(v.CommitSHA), v.Author, v.CommitMessage)
+	}
+	if v.Encoding != "" && v.Encoding != model.EncodingUTF8 {
+		fmt.Fprintf(w, "Encoding:  %s\n", v.Encoding)
+	}
+	fmt.Fprintf(w, "Created:   %s\n", formatTime(&v.CreatedAt))
+	fmt.Fprintf(w, "Updated:   %s\n", formatTime(&v.UpdatedAt))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, v.Content)
+}
+
+// documentListView is the result of "document list"
+type documentListView []documentView
+
+func (v documentListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v documentListView) renderText(w io.Writer) {
+	if len(v) == 0 {
+		fmt.Fprintln(w, "No documents found")
+		return
+	}
+
+	fmt.Fprintln(w, "Store | Modified            | Author           | SHA          | Path")
+	fmt.Fprintln(w, "------|---------------------|------------------|--------------|-----")
+	for _, d := range v {
+		fmt.Fprintf(w, "%-5d | %-19s | %-16s | %-12s | %s\n",
+			d.StoreID, d.ModifiedAt.Local().Format("2006-01-02 15:04:05"), orDash(d.Author), shortSHA(d.SHA), d.Path)
+	}
+	fmt.Fprintf(w, "%d documents\n", len(v))
+}
+
+// documentVersionView is the structured representation of a version of a document
+type documentVersionView struct {
+	Version       int        `json:"version"`
+	Current       bool       `json:"current"`
+	Path          string     `json:"path"`
+	SHA           string     `json:"sha"`
+	ModifiedAt    time.Time  `json:"modified_at"`
+	CommitSHA     string     `json:"commit_sha,omitempty"`
+	Author        string     `json:"author,omitempty"`
+	CommitMessage string     `json:"commit_message,omitempty"`
+	ReplacedAt    *time.Time `json:"replaced_at"`
+}
+
+func newDocumentVersionView(v *model.DocumentVersion) documentVersionView {
+	return documentVersionView{
+		Version:       v.Number,
+		Current:       v.ReplacedAt == nil,
+		Path:          v.Path,
+		SHA:           v.SHA,
+		ModifiedAt:    v.ModifiedAt,
+		CommitSHA:     v.LastCommit.SHA,
+		Author:        v.LastCommit.Author,
+		CommitMessage: v.LastCommit.Message,
+		ReplacedAt:    v.ReplacedAt,
+	}
+}
+
+// documentHistoryView is the result of "document history"
+type documentHistoryView []documentVersionView
+
+func newDocumentHistoryView(versions []*model.DocumentVersion) documentHistoryView {
+	views := make(documentHistoryView, len(versions))
+	for i, v := range versions {
+		views[i] = newDocumentVersionView(v)
+	}
+	return views
+}
+
+func (v documentHistoryView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v documentHistoryView) renderText(w io.Writer) {
+	fmt.Fprintln(w, "Version | Modified            | Author           | SHA          | Commit")
+	fmt.Fprintln(w, "--------|---------------------|------------------|--------------|-------")
+	for _, version := range v {
+		number := strconv.Itoa(version.Version)
+		if version.Current {
+			number += "*"
+		}
+		fmt.Fprintf(w, "%-7s | %-19s | %-16s | %-12s | %s\n",
+			number, version.ModifiedAt.Local().Format("2006-01-02 15:04:05"), orDash(version.Author),
+			shortSHA(version.SHA), orDash(version.CommitMessage))
+	}
+	fmt.Fprintf(w, "%d versions (* current)\n", len(v))
+}
+
+// documentDiffView is the result of "document diff"
+type documentDiffView struct {
+	From documentVersionView `json:"from"`
+	To   documentVersionView `json:"to"`
+	Diff string              `json:"diff"`
+}
+
+func newDocumentDiffView(diff *model.DocumentDiff) documentDiffView {
+	return documentDiffView{
+		From: newDocumentVersionView(diff.From),
+		To:   newDocumentVersionView(diff.To),
+		Diff: diff.Diff,
+	}
+}
+
+func (v documentDiffView) renderText(w io.Writer) {
+	if v.Diff == "" {
+		fmt.Fprintf(w, "Versions %d and %d have the same content\n", v.From.Version, v.To.Version)
+		return
+	}
+	fmt.Fprint(w, v.Diff)
+}
+
+// memoryView is the structured representation of a memory
+type memoryView struct {
+	Path           string           `json:"path"`
+	SHA            string           `json:"sha"`
+	BodySHA        string           `json:"body_sha,omitempty"`
+	MetadataSHA    string           `json:"metadata_sha,omitempty"`
+	Tags           []string         `json:"tags"`
+	EmbeddingModel string           `json:"embedding_model,omitempty"`
+	EmbeddingDim   int              `json:"embedding_dimensions,omitempty"`
+	TokenCount     int              `json:"token_count,omitempty"`
+	Type           model.MemoryType `json:"type,omitempty"`
+	Importance     int              `json:"importance,omitempty"`
+	Source         string           `json:"source,omitempty"`
+	ExpiresAt      *time.Time       `json:"expires_at,omitempty"`
+	Supersedes     []string         `json:"supersedes,omitempty"`
+	ArchivedAt     *time.Time       `json:"archived_at,omitempty"`
+	ModifiedAt     time.Time        `json:"modified_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	Content        string           `json:"content,omitempty"`
+}
+
+func newMemoryView(m *model.Memory, withContent bool) memoryView {
+	view := memoryView{
+		Path:           m.Path,
+		SHA:            m.SHA,
+		BodySHA:        m.BodySHA,
+		MetadataSHA:    m.MetadataSHA,
+		Tags:           nonNilStrings(m.Tags),
+		EmbeddingModel: m.EmbeddingModel,
+		EmbeddingDim:   len(m.Embedding),
+		TokenCount:     m.TokenCount,
+		Type:           m.Type,
+		Importance:     m.Importance,
+		Source:         m.Source,
+		ExpiresAt:      optionalTime(m.ExpiresAt),
+		Supersedes:     m.Supersedes,
+		ArchivedAt:     optionalTime(m.ArchivedAt),
+		ModifiedAt:     m.ModifiedAt,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+	if withContent {
+		view.Content = m.Content
+	}
+	return view
+}
+
+func (v memoryView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Path:       %s\n", v.Path)
+	fmt.Fprintf(w, "SHA:        %s\n", v.SHA)
+	fmt.Fprintf(w, "Tags:       %s\n", formatPatterns(v.Tags))
+	fmt.Fprintf(w, "Type:       %s\n", orDash(string(v.Type)))
+	fmt.Fprintf(w, "Importance: %s\n", formatImportance(v.Importance))
+	if v.Source != "" {
+		fmt.Fprintf(w, "Source:     %s\n", v.Source)
+	}
+	if v.ExpiresAt != nil {
+		fmt.Fprintf(w, "Expires:    %s\n", formatTime(v.ExpiresAt))
+	}
+	if len(v.Supersedes) > 0 {
+		fmt.Fprintf(w, "Supersedes: %s\n", formatPatterns(v.Supersedes))
+	}
+	if v.ArchivedAt != nil {
+		fmt.Fprintf(w, "Archived:   %s\n", formatTime(v.ArchivedAt))
+	}
+	fmt.Fprintf(w, "Embedding:  %s\n", formatEmbedding(v.EmbeddingModel, v.EmbeddingDim))
+	if v.TokenCount > 0 {
+		fmt.Fprintf(w, "Tokens:     %d\n", v.TokenCount)
+	}
+	fmt.Fprintf(w, "Modified:   %s\n", formatTime(&v.ModifiedAt))
+	fmt.Fprintf(w, "Created:    %s\n", formatTime(&v.CreatedAt))
+	fmt.Fprintf(w, "Updated:    %s\n", formatTime(&v.UpdatedAt))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, v.Content)
+}
+
+// memorySavedView is the result of "memory add" and "memory edit"
+type memorySavedView struct {
+	Path       string `json:"path"`
+	SHA        string `json:"sha"`
+	TokenCount int    `json:"token_count,omitempty"`
+	Saved      bool   `json:"saved"`
+}
+
+func (v memorySavedView) renderText(w io.Writer) {
+	if !v.Saved {
+		fmt.Fprintf(w, "No changes to memory %s\n", v.Path)
+		return
+	}
+	fmt.Fprintf(w, "Saved memory %s (%s, %d tokens)\n", v.Path, shortSHA(v.SHA), v.TokenCount)
+}
+
+// memoryDeletedView is the result of "memory rm"
+type memoryDeletedView struct {
+	Path    string `json:"path"`
+	Deleted bool   `json:"deleted"`
+}
+
+func (v memoryDeletedView) renderText(w io.Writer) {
+	if !v.Deleted {
+		fmt.Fprintln(w, "Aborted")
+		return
+	}
+	fmt.Fprintf(w, "Deleted memory %s\n", v.Path)
+}
+
+// memoryListView is the result of "memory list"
+type memoryListView []memoryView
+
+func (v memoryListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v memoryListView) renderText(w io.Writer) {
+	if len(v) == 0 {
+		fmt.Fprintln(w, "No memories found")
+		return
+	}
+
+	fmt.Fprintln(w, "Modified            | Type        | Imp | SHA          | Path")
+	fmt.Fprintln(w, "--------------------|-------------|-----|--------------|-----")
+	for _, m := range v {
+		path := m.Path
+		if m.ArchivedAt != nil {
+			path += " (archived)"
+		}
+		fmt.Fprintf(w, "%-19s | %-11s | %-3s | %-12s | %s\n",
+			m.ModifiedAt.Local().Format("2006-01-02 15:04:05"), orDash(string(m.Type)), formatImportance(m.Importance), shortSHA(m.SHA), path)
+	}
+	fmt.Fprintf(w, "%d memories\n", len(v))
+}
+
+// formatImportance shows an unset importance as "-"
+func formatImportance(importance int) string {
+	if importance == 0 {
+		return "-"
+	}
+	return strconv.Itoa(importance)
+}
+
+// memorySweptView is the result of "memory sweep"
+type memorySweptView struct {
+	Expired    []string `json:"expired"`
+	Superseded []string `json:"superseded"`
+}
+
+func newMemorySweptView(r *model.SweepResult) memorySweptView {
+	return memorySweptView{
+		Expired:    nonNilStrings(r.Expired),
+		Superseded: nonNilStrings(r.Superseded),
+	}
+}
+
+func (v memorySweptView) renderText(w io.Writer) {
+	for _, path := range v.Expired {
+		fmt.Fprintf(w, "archived %s (expired)\n", path)
+	}
+	for _, path := range v.Superseded {
+		fmt.Fprintf(w, "archived %s (superseded)\n", path)
+	}
+	fmt.Fprintf(w, "Archived %d expired and %d superseded memories\n", len(v.Expired), len(v.Superseded))
+}
+
+// syncResultView is the result of "document sync" and "memory sync".
+// Type is always "result" so that it can be told apart from progress events in NDJSON output.
+type syncResultView struct {
+	Type         string        `json:"type"`
+	StoreID      model.StoreId `json:"store_id,omitempty"`
+	Total        int           `json:"total"`
+	Saved        int           `json:"saved"`
+	MetadataOnly int           `json:"metadata_only,omitempty"` // saved entries whose frontmatter changed but not their body
+	Moved        int           `json:"moved"`
+	Unchanged    int           `json:"unchanged"`
+	Failed       int           `json:"failed"`
+	StartedAt    time.Time     `json:"started_at"`
+	FinishedAt   time.Time     `json:"finished_at"`
+	DurationMS   int64         `json:"duration_ms"`
+	// Interrupted is set when the sync was cancelled before all entries were processed
+	Interrupted bool `json:"interrupted,omitempty"`
+	// EmbeddingRequests and EmbeddingTokens are the requests and tokens billed by the provider
+	EmbeddingModel    string `json:"embedding_model"`
+	EmbeddingRequests int    `json:"embedding_requests"`
+	EmbeddingTokens   int    `json:"embedding_tokens"`
+	// EmbeddingCache counts the embedding cache lookups of this sync
+	EmbeddingCache *embeddingCacheUsageView `json:"embedding_cache,omitempty"`
+	// Undecodable lists the text files whose encoding could not be detected
+	Undecodable []string `json:"undecodable,omitempty"`
+}
+
+// embeddingCacheUsageView is the structured representation of the cache lookups of a run
+type embeddingCacheUsageView struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+func newSyncResultView(r *model.SyncResult) syncResultView {
+	return syncResultView{
+		Type:         "result",
+		StoreID:      r.StoreId,
+		Total:        r.Total,
+		Saved:        r.Saved,
+		MetadataOnly: r.MetadataOnly,
+		Moved:        r.Moved,
+		Unchanged:    r.Unchanged,
+		Failed:       r.Failed,
+		StartedAt:    r.StartedAt,
+		FinishedAt:   r.FinishedAt,
+		DurationMS:   r.FinishedAt.Sub(r.StartedAt).Milliseconds(),
+
+		EmbeddingModel:    r.Usage.Model,
+		EmbeddingRequests: r.Usage.Requests,
+		EmbeddingTokens:   r.Usage.Tokens,
+		Undecodable:       r.Undecodable,
+	}
+}
+
+// withCacheUsage adds the embedding cache lookups of the sync to the view
+func (v syncResultView) withCacheUsage(usage model.EmbeddingCacheUsage) syncResultView {
+	v.EmbeddingCache = &embeddingCacheUsageView{
+		Hits:    usage.Hits,
+		Misses:  usage.Misses,
+		HitRate: usage.HitRate(),
+	}
+	return v
+}
+
+func (v syncResultView) renderText(w io.Writer) {
+	if v.Interrupted {
+		fmt.Fprintln(w, "Sync interrupted")
+	} else {
+		fmt.Fprintln(w, "Sync completed successfully")
+	}
+	fmt.Fprintf(w, "%d entries: %d saved, %d moved, %d unchanged, %d failed (%s)\n",
+		v.Total, v.Saved, v.Moved, v.Unchanged, v.Failed, time.Duration(v.DurationMS)*time.Millisecond)
+	if v.MetadataOnly > 0 {
+		fmt.Fprintf(w, "%d saved entries had only frontmatter changes and kept their embeddings\n", v.MetadataOnly)
+	}
+	if v.EmbeddingRequests > 0 {
+		fmt.Fprintf(w, "Embedding usage: %d requests, %d tokens (%s)\n", v.EmbeddingRequests, v.EmbeddingTokens, v.EmbeddingModel)
+	}
+	if c := v.EmbeddingCache; c != nil && c.Hits+c.Misses > 0 {
+		fmt.Fprintf(w, "Embedding cache: %d hits, %d misses (%.0f%% hit rate)\n", c.Hits, c.Misses, c.HitRate*100)
+	}
+	if len(v.Undecodable) > 0 {
+		fmt.Fprintf(w, "%d files could not be decoded:\n", len(v.Undecodable))
+		for _, path := range v.Undecodable {
+			fmt.Fprintf(w, "  %s\n", path)
+		}
+	}
+}
+
+// indexView is the structured representation of the vector index of a table
+type indexView struct {
+	Table        string   `json:"table"`
+	Name         string   `json:"name,omitempty"`
+	Method       string   `json:"method,omitempty"`
+	Valid        bool     `json:"valid"`
+	SizeBytes    int64    `json:"size_bytes"`
+	Lists        int      `json:"lists,omitempty"`
+	Rows         int      `json:"rows"`
+	EmbeddedRows int      `json:"embedded_rows"`
+	Definition   string   `json:"definition,omitempty"`
+	Warnings     []string `json:"warnings"`
+}
+
+func newIndexView(index *model.VectorIndex, warnings []string) indexView {
+	return indexView{
+		Table:        index.Table,
+		Name:         index.Name,
+		Method:       index.Method,
+		Valid:        index.Valid,
+		SizeBytes:    index.SizeBytes,
+		Lists:        index.Lists,
+		Rows:         index.Rows,
+		EmbeddedRows: index.EmbeddedRows,
+		Definition:   index.Definition,
+		Warnings:     nonNilStrings(warnings),
+	}
+}
+
+// indexListView is the result of the index commands
+type indexListView []indexView
+
+func (v indexListView) records() []interface{} {
+	records := make([]interface{}, len(v))
+	for i := range v {
+		records[i] = v[i]
+	}
+	return records
+}
+
+func (v indexListView) renderText(w io.Writer) {
+	fmt.Fprintln(w, "Table     | Method  | Valid | Size       | Embedded rows")
+	fmt.Fprintln(w, "----------|---------|-------|------------|--------------")
+	for _, index := range v {
+		method, valid, size := "-", "-", "-"
+		if index.Name != "" {
+			method = index.Method
+			if index.Lists > 0 {
+				method = fmt.Sprintf("%s/%d", index.Method, index.Lists)
+			}
+			valid = fmt.Sprintf("%t", index.Valid)
+			size = formatSizeKB(int(index.SizeBytes / 1024))
+		}
+		fmt.Fprintf(w, "%-9s | %-7s | %-5s | %-10s | %d of %d\n",
+			index.Table, method, valid, size, index.EmbeddedRows, index.Rows)
+	}
+	for _, index := range v {
+		for _, warning := range index.Warnings {
+			fmt.Fprintf(w, "warning: %s: %s\n", index.Table, warning)
+		}
+	}
+}
+
+// embeddingCacheStatsView is the result of "embedding cache stats"
+type embeddingCacheStatsView struct {
+	Entries      int        `json:"entries"`
+	SizeBytes    int64      `json:"size_bytes"`
+	Hits         int64      `json:"hits"`
+	HitRate      float64    `json:"hit_rate"`
+	OldestUsedAt *time.Time `json:"oldest_used_at"`
+	NewestUsedAt *time.Time `json:"newest_used_at"`
+}
+
+func (v embeddingCacheStatsView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Entries:     %d\n", v.Entries)
+	fmt.Fprintf(w, "Size:        %s\n", formatSizeKB(int(v.SizeBytes/1024)))
+	fmt.Fprintf(w, "Hits:        %d (%.0f%% hit rate)\n", v.Hits, v.HitRate*100)
+	fmt.Fprintf(w, "Oldest use:  %s\n", formatTime(v.OldestUsedAt))
+	fmt.Fprintf(w, "Newest use:  %s\n", formatTime(v.NewestUsedAt))
+}
+
+// embeddingCachePrunedView is the result of "embedding cache prune"
+type embeddingCachePrunedView struct {
+	Removed int `json:"removed"`
+}
+
+func (v embeddingCachePrunedView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Removed %d embeddings from the cache\n", v.Removed)
+}
+
+// snapshotView is the structured representation of a cached repository snapshot
+type snapshotView struct {
+	Repo       string    `json:"repo"`
+	Ref        string    `json:"ref"`
+	Commit     string    `json:"commit"`
+	SizeBytes  int64     `json:"size_bytes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+func newSnapshotViews(snapshots []storage.Snapshot) []snapshotView {
+	views := make([]snapshotView, len(snapshots))
+	for i, s := range snapshots {
+		views[i] = snapshotView{
+			Repo:       s.Repo,
+			Ref:        s.Ref,
+			Commit:     s.Commit,
+			SizeBytes:  s.SizeBytes,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+		}
+	}
+	return views
+}
+
+// snapshotListView is the result of "cache ls"
+type snapshotListView struct {
+	Dir          string         `json:"dir"`
+	MaxSizeBytes int64          `json:"max_size_bytes"`
+	Snapshots    []snapshotView `json:"snapshots"`
+}
+
+func newSnapshotListView(dir string, maxSize int64, snapshots []storage.Snapshot) snapshotListView {
+	return snapshotListView{Dir: dir, MaxSizeBytes: maxSize, Snapshots: newSnapshotViews(snapshots)}
+}
+
+func (v snapshotListView) records() []interface{} {
+	records := make([]interface{}, len(v.Snapshots))
+	for i := range v.Snapshots {
+		records[i] = v.Snapshots[i]
+	}
+	return records
+}
+
+func (v snapshotListView) renderText(w io.Writer) {
+	var total int64
+	for _, s := range v.Snapshots {
+		total += s.SizeBytes
+	}
+	fmt.Fprintf(w, "Cache: %s (%s of %s)\n", v.Dir, formatSizeKB(int(total/1024)), formatSizeKB(int(v.MaxSizeBytes/1024)))
+	if len(v.Snapshots) == 0 {
+		fmt.Fprintln(w, "No snapshots cached")
+		return
+	}
+	fmt.Fprintln(w, "Repository                     | Ref          | Commit       | Size       | Last used")
+	fmt.Fprintln(w, "-------------------------------|--------------|--------------|------------|--------------------------")
+	for _, s := range v.Snapshots {
+		ref := s.Ref
+		if ref == "" {
+			ref = "(default)"
+		}
+		commit := s.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		fmt.Fprintf(w, "%-30s | %-12s | %-12s | %-10s | %s\n",
+			s.Repo, ref, commit, formatSizeKB(int(s.SizeBytes/1024)), formatTime(&s.LastUsedAt))
+	}
+}
+
+// snapshotPrunedView is the result of "cache prune"
+type snapshotPrunedView struct {
+	Removed    []snapshotView `json:"removed"`
+	FreedBytes int64          `json:"freed_bytes"`
+}
+
+func newSnapshotPrunedView(removed []storage.Snapshot) snapshotPrunedView {
+	v := snapshotPrunedView{Removed: newSnapshotViews(removed)}
+	for _, s := range removed {
+		v.FreedBytes += s.SizeBytes
+	}
+	return v
+}
+
+func (v snapshotPrunedView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Removed %d snapshots, freeing %s\n", len(v.Removed), formatSizeKB(int(v.FreedBytes/1024)))
+}
+
+// usageLineView is the structured representation of the usage of a source, store and model
+type usageLineView struct {
+	Source        string        `json:"source"`
+	StoreID       model.StoreId `json:"store_id,omitempty"`
+	Model         string        `json:"model"`
+	Runs          int           `json:"runs"`
+	Requests      int           `json:"requests"`
+	Tokens        int           `json:"tokens"`
+	EstimatedCost *float64      `json:"estimated_cost_usd"`
+}
+
+// usageModelView is the structured representation of the usage of a model
+type usageModelView struct {
+	Model         string   `json:"model"`
+	Requests      int      `json:"requests"`
+	Tokens        int      `json:"tokens"`
+	EstimatedCost *float64 `json:"estimated_cost_usd"`
+}
+
+// usageReportView is the result of "usage"
+type usageReportView struct {
+	Since     *time.Time       `json:"since"`
+	Lines     []usageLineView  `json:"lines"`
+	Models    []usageModelView `json:"models"`
+	TotalCost float64          `json:"total_cost_usd"`
+}
+
+func newUsageReportView(report *usage.UsageReport) usageReportView {
+	view := usageReportView{
+		Lines:     make([]usageLineView, 0, len(report.Lines)),
+		Models:    make([]usageModelView, 0, len(report.Models)),
+		TotalCost: report.TotalCost,
+	}
+	if !report.Since.IsZero() {
+		view.Since = &report.Since
+	}
+	// The cost of models without a known price is null rather than zero
+	cost := func(value float64, priced bool) *float64 {
+		if !priced {
+			return nil
+		}
+		return &value
+	}
+	for _, line := range report.Lines {
+		view.Lines = append(view.Lines, usageLineView{
+			Source:        line.Source,
+			StoreID:       line.StoreId,
+			Model:         line.Model,
+			Runs:          line.Runs,
+			Requests:      line.Requests,
+			Tokens:        line.Tokens,
+			EstimatedCost: cost(line.EstimatedCost, line.Priced),
+		})
+	}
+	for _, total := range report.Models {
+		view.Models = append(view.Models, usageModelView{
+			Model:         total.Model,
+			Requests:      total.Requests,
+			Tokens:        total.Tokens,
+			EstimatedCost: cost(total.EstimatedCost, total.Priced),
+		})
+	}
+	return view
+}
+
+func (v usageReportView) records() []interface{} {
+	records := make([]interface{}, len(v.Lines))
+	for i := range v.Lines {
+		records[i] = v.Lines[i]
+	}
+	return records
+}
+
+func (v usageReportView) renderText(w io.Writer) {
+	if v.Since != nil {
+		fmt.Fprintf(w, "Embedding usage since %s\n\n", formatTime(v.Since))
+	} else {
+		fmt.Fprintf(w, "Embedding usage (all time)\n\n")
+	}
+	if len(v.Lines) == 0 {
+		fmt.Fprintln(w, "No usage recorded")
+		return
+	}
+
+	fmt.Fprintln(w, "Source   | Store | Model                    | Runs  | Requests | Tokens       | Est. cost")
+	fmt.Fprintln(w, "---------|-------|--------------------------|-------|----------|--------------|----------")
+	for _, line := range v.Lines {
+		store := "-"
+		if line.StoreID != 0 {
+			store = fmt.Sprintf("%d", line.StoreID)
+		}
+		fmt.Fprintf(w, "%-8s | %-5s | %-24s | %-5d | %-8d | %-12d | %s\n",
+			line.Source, store, line.Model, line.Runs, line.Requests, line.Tokens, formatCost(line.EstimatedCost))
+	}
+
+	fmt.Fprintln(w)
+	for _, total := range v.Models {
+		fmt.Fprintf(w, "%s: %d tokens, %s\n", total.Model, total.Tokens, formatCost(total.EstimatedCost))
+	}
+	fmt.Fprintf(w, "Total estimated cost: $%.4f\n", v.TotalCost)
+}
+
+// versionView is the result of "version"
+type versionView struct {
+	Version string `json:"version"`
+}
+
+func (v versionView) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Personal Agent %s\n", v.Version)
+}
@@ -1,51 +1,229 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultConfigFile is read when no path is given and the file exists in the working directory
+const DefaultConfigFile = "personal-agent.yaml"
+
+// ConfigFileEnv is the environment variable that points at the configuration file
+const ConfigFileEnv = "PERSONAL_AGENT_CONFIG"
+
 // Config holds all configuration for the application
 type Config struct {
 	// Database configuration
-	Database DatabaseConfig
-	Memory   MemoryConfig
+	Database  DatabaseConfig  `yaml:"database"`
+	Embedding EmbeddingConfig `yaml:"embedding"`
+	Memory    MemoryConfig    `yaml:"memory"`
+	Index     IndexConfig     `yaml:"index"`
+	Sync      SyncConfig      `yaml:"sync"`
+	Git       GitConfig       `yaml:"git"`
+	GitHub    GitHubConfig    `yaml:"github"`
+	Cache     CacheConfig     `yaml:"cache"`
+	History   HistoryConfig   `yaml:"history"`
+	// Document stores managed by "personal-agent apply"
+	Stores []StoreConfig `yaml:"stores"`
 }
 
 // MemoryConfig holds all memory related configuration
 type MemoryConfig struct {
 	// GitHub repository URL
-	Repo string
+	Repo string `yaml:"repo"`
 }
 
 // DatabaseConfig holds all database related configuration
 type DatabaseConfig struct {
-	User     string
-	Password string
-	Name     string
-	Host     string
-	Port     string
-}
-
-// LoadConfig loads the configuration from environment variables
-func LoadConfig() (*Config, error) {
-	config := &Config{
-		Database: DatabaseConfig{
-			User:     os.Getenv("DB_USER"),
-			Password: os.Getenv("DB_PASSWORD"),
-			Name:     os.Getenv("DB_NAME"),
-			Host:     os.Getenv("DB_HOST"),
-			Port:     os.Getenv("DB_PORT"),
-		},
-		Memory: MemoryConfig{
-			Repo: os.Getenv("MEMORY_REPO"),
-		},
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	// MigrationsTable is the table in which applied schema migrations are recorded
+	MigrationsTable string `yaml:"migrations_table"`
+}
+
+// EmbeddingConfig holds the settings of the embedding provider
+type EmbeddingConfig struct {
+	// Provider is the name of the embedding provider; only "openai" is supported
+	Provider string `yaml:"provider"`
+	// Model is the embedding model; empty means the provider default
+	Model  string `yaml:"model"`
+	APIKey string `yaml:"api_key"`
+	// MaxTokens is the input limit of the model; longer texts are handled by Overflow
+	MaxTokens int `yaml:"max_tokens"`
+	// Overflow is the policy for texts above MaxTokens: "reject", "truncate" or "chunk"
+	Overflow string `yaml:"overflow"`
+	// Encoding is the tokenizer encoding (cl100k_base or o200k_base); empty selects the encoding of the model
+	Encoding string               `yaml:"encoding"`
+	Cache    EmbeddingCacheConfig `yaml:"cache"`
+	// Prices in USD per million tokens by model, used by "personal-agent usage".
+	// They override the built-in OpenAI list prices.
+	Prices map[string]float64 `yaml:"prices"`
+}
+
+// EmbeddingCacheConfig holds the eviction limits of the embedding cache; zero means unlimited
+type EmbeddingCacheConfig struct {
+	MaxEntries int           `yaml:"max_entries"`
+	MaxAge     time.Duration `yaml:"max_age"`
+}
+
+// IndexConfig holds the default build parameters of the vector indexes
+type IndexConfig struct {
+	// Type is the approximate nearest-neighbour index method: "hnsw" or "ivfflat"
+	Type string `yaml:"type"`
+	// M and EfConstruction are the HNSW build parameters
+	M              int `yaml:"m"`
+	EfConstruction int `yaml:"ef_construction"`
+	// Lists is the number of IVFFlat lists; 0 derives it from the number of rows
+	Lists int `yaml:"lists"`
+}
+
+// SyncConfig holds the settings of document and memory syncs
+type SyncConfig struct {
+	// BatchSize is the number of documents or memories saved per transaction
+	BatchSize int `yaml:"batch_size"`
+}
+
+// GitConfig holds the settings of git stores
+type GitConfig struct {
+	// MirrorDir is where the local mirrors of git remotes are kept; defaults to the user cache directory
+	MirrorDir string `yaml:"mirror_dir"`
+}
+
+// GitHubConfig holds the settings of GitHub stores
+type GitHubConfig struct {
+	// Submodules syncs the GitHub submodules of the repositories as part of their stores, at their paths
+	Submodules bool `yaml:"submodules"`
+}
+
+// CacheConfig holds the settings of the cache of repository snapshots
+type CacheConfig struct {
+	// Dir is where the extracted repository snapshots are kept; defaults to the user cache directory
+	Dir string `yaml:"dir"`
+	// MaxSizeMB is the disk quota of the snapshots; the least recently used are evicted above it
+	MaxSizeMB int `yaml:"max_size_mb"`
+}
+
+// HistoryConfig holds the retention of previous document versions; zero values keep all versions
+type HistoryConfig struct {
+	// MaxVersions is the number of previous versions kept per document
+	MaxVersions int `yaml:"max_versions"`
+	// MaxAge removes versions replaced longer ago than this
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// StoreConfig declares a document store
+type StoreConfig struct {
+	Type string `yaml:"type"`
+	// Repo is "owner/repo" for GitHub stores and the remote URL for git stores
+	Repo    string   `yaml:"repo"`
+	Ref     string   `yaml:"ref"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+const (
+	EmbeddingProviderOpenAI = "openai"
+	OverflowReject          = "reject"
+	OverflowTruncate        = "truncate"
+	OverflowChunk           = "chunk"
+	StoreTypeGitHub         = "github"
+	StoreTypeGit            = "git"
+	IndexTypeHNSW           = "hnsw"
+	IndexTypeIVFFlat        = "ivfflat"
+)
+
+// ValidationError reports an invalid configuration value at a key such as "stores[1].repo"
+type ValidationError struct {
+	Key     string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// ValidationErrors is a list of validation errors
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
 	}
+	return "invalid configuration:\n  " + strings.Join(messages, "\n  ")
+}
+
+// LoadConfig loads the configuration from the YAML file and environment variables.
+// When file is empty, the file named by PERSONAL_AGENT_CONFIG or personal-agent.yaml in the
+// working directory is used if present. Environment variables override values from the file.
+func LoadConfig(file string) (*Config, error) {
+	config := &Config{}
+
+	if file == "" {
+		file = os.Getenv(ConfigFileEnv)
+	}
+	if file == "" {
+		if _, err := os.Stat(DefaultConfigFile); err == nil {
+			file = DefaultConfigFile
+		}
+	}
+	if file != "" {
+		if err := loadFile(file, config); err != nil {
+			return nil, err
+		}
+	}
+
+	applyEnv(config)
 
 	// Set default values
 	if config.Database.Port == "" {
 		config.Database.Port = "5432" // Default PostgreSQL port
 	}
+	if config.Database.MigrationsTable == "" {
+		config.Database.MigrationsTable = "goose_db_version" // Default goose table
+	}
+	if config.Embedding.Provider == "" {
+		config.Embedding.Provider = EmbeddingProviderOpenAI
+	}
+	if config.Embedding.MaxTokens == 0 {
+		config.Embedding.MaxTokens = 8191 // Input limit of the OpenAI embedding models
+	}
+	if config.Embedding.Overflow == "" {
+		config.Embedding.Overflow = OverflowReject
+	}
+	if config.Index.Type == "" {
+		config.Index.Type = IndexTypeHNSW
+	}
+	if config.Index.M == 0 {
+		config.Index.M = 16 // pgvector default
+	}
+	if config.Index.EfConstruction == 0 {
+		config.Index.EfConstruction = 64 // pgvector default
+	}
+	if config.Sync.BatchSize == 0 {
+		config.Sync.BatchSize = 100
+	}
+	if config.Cache.MaxSizeMB == 0 {
+		config.Cache.MaxSizeMB = 2048
+	}
+	for i := range config.Stores {
+		if config.Stores[i].Type == "" {
+			config.Stores[i].Type = StoreTypeGitHub
+		}
+	}
 
 	// Validate required fields
 	if err := validateConfig(config); err != nil {
@@ -55,30 +233,172 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// loadFile decodes the YAML file into config, rejecting unknown keys
+func loadFile(file string, config *Config) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// applyEnv overrides configuration values with the environment variables that are set
+func applyEnv(config *Config) {
+	overrides := []struct {
+		env    string
+		target *string
+	}{
+		{"DB_USER", &config.Database.User},
+		{"DB_PASSWORD", &config.Database.Password},
+		{"DB_NAME", &config.Database.Name},
+		{"DB_HOST", &config.Database.Host},
+		{"DB_PORT", &config.Database.Port},
+		{"GOOSE_TABLE", &config.Database.MigrationsTable},
+		{"MEMORY_REPO", &config.Memory.Repo},
+		{"OPENAI_API_KEY", &config.Embedding.APIKey},
+	}
+	for _, o := range overrides {
+		if v := os.Getenv(o.env); v != "" {
+			*o.target = v
+		}
+	}
+}
+
 // validateConfig validates that all required configuration is present
 func validateConfig(config *Config) error {
+	var errs ValidationErrors
+	required := func(key, env, value string) {
+		if value == "" {
+			errs = append(errs, &ValidationError{Key: key, Message: fmt.Sprintf("is required (set it in the config file or %s)", env)})
+		}
+	}
+
 	// Validate Database configuration
-	if config.Database.User == "" {
-		return fmt.Errorf("DB_USER is required")
+	required("database.user", "DB_USER", config.Database.User)
+	required("database.password", "DB_PASSWORD", config.Database.Password)
+	required("database.name", "DB_NAME", config.Database.Name)
+	required("database.host", "DB_HOST", config.Database.Host)
+	required("database.port", "DB_PORT", config.Database.Port)
+
+	// Validate Embedding configuration
+	if config.Embedding.Provider != EmbeddingProviderOpenAI {
+		errs = append(errs, &ValidationError{Key: "embedding.provider", Message: fmt.Sprintf("unsupported provider %q (supported: %s)", config.Embedding.Provider, EmbeddingProviderOpenAI)})
 	}
-	if config.Database.Password == "" {
-		return fmt.Errorf("DB_PASSWORD is required")
+
+	if config.Embedding.MaxTokens < 0 {
+		errs = append(errs, &ValidationError{Key: "embedding.max_tokens", Message: "must be positive"})
 	}
-	if config.Database.Name == "" {
-		return fmt.Errorf("DB_NAME is required")
+	switch config.Embedding.Overflow {
+	case OverflowReject, OverflowTruncate, OverflowChunk:
+	default:
+		errs = append(errs, &ValidationError{Key: "embedding.overflow", Message: fmt.Sprintf("unsupported policy %q (supported: %s, %s, %s)", config.Embedding.Overflow, OverflowReject, OverflowTruncate, OverflowChunk)})
 	}
-	if config.Database.Host == "" {
-		return fmt.Errorf("DB_HOST is required")
+	switch config.Embedding.Encoding {
+	case "", "cl100k_base", "o200k_base":
+	default:
+		errs = append(errs, &ValidationError{Key: "embedding.encoding", Message: fmt.Sprintf("unsupported encoding %q (supported: cl100k_base, o200k_base)", config.Embedding.Encoding)})
 	}
-	if config.Database.Port == "" {
-		return fmt.Errorf("DB_PORT is required")
+	if config.Embedding.Cache.MaxEntries < 0 {
+		errs = append(errs, &ValidationError{Key: "embedding.cache.max_entries", Message: "must not be negative"})
+	}
+	if config.Embedding.Cache.MaxAge < 0 {
+		errs = append(errs, &ValidationError{Key: "embedding.cache.max_age", Message: "must not be negative"})
+	}
+
+	if config.History.MaxVersions < 0 {
+		errs = append(errs, &ValidationError{Key: "history.max_versions", Message: "must not be negative"})
+	}
+	if config.History.MaxAge < 0 {
+		errs = append(errs, &ValidationError{Key: "history.max_age", Message: "must not be negative"})
+	}
+	if config.Cache.MaxSizeMB < 0 {
+		errs = append(errs, &ValidationError{Key: "cache.max_size_mb", Message: "must be positive"})
+	}
+
+	priced := make([]string, 0, len(config.Embedding.Prices))
+	for m := range config.Embedding.Prices {
+		priced = append(priced, m)
+	}
+	sort.Strings(priced)
+	for _, m := range priced {
+		if config.Embedding.Prices[m] < 0 {
+			errs = append(errs, &ValidationError{Key: "embedding.prices." + m, Message: "must not be negative"})
+		}
+	}
+
+	// Validate Index configuration
+	if config.Index.Type != IndexTypeHNSW && config.Index.Type != IndexTypeIVFFlat {
+		errs = append(errs, &ValidationError{Key: "index.type", Message: fmt.Sprintf("unsupported index type %q (supported: %s, %s)", config.Index.Type, IndexTypeHNSW, IndexTypeIVFFlat)})
+	}
+	if config.Index.M < 2 || config.Index.M > 100 {
+		errs = append(errs, &ValidationError{Key: "index.m", Message: "must be between 2 and 100"})
+	}
+	if config.Index.EfConstruction < 2*config.Index.M || config.Index.EfConstruction > 1000 {
+		errs = append(errs, &ValidationError{Key: "index.ef_construction", Message: "must be between 2*m and 1000"})
+	}
+	if config.Index.Lists < 0 || config.Index.Lists > 32768 {
+		errs = append(errs, &ValidationError{Key: "index.lists", Message: "must be between 0 and 32768"})
 	}
 
-	// Validate Memory configuration
-	if config.Memory.Repo == "" {
-		return fmt.Errorf("MEMORY_REPO is required")
+	// Validate Sync configuration
+	if config.Sync.BatchSize < 1 || config.Sync.BatchSize > 10000 {
+		errs = append(errs, &ValidationError{Key: "sync.batch_size", Message: "must be between 1 and 10000"})
 	}
 
+	// Validate Store configuration
+	for i, store := range config.Stores {
+		key := fmt.Sprintf("stores[%d]", i)
+		parse := model.ParseGitHubRepo
+		switch store.Type {
+		case StoreTypeGitHub:
+		case StoreTypeGit:
+			parse = model.ParseGitURL
+		default:
+			errs = append(errs, &ValidationError{Key: key + ".type", Message: fmt.Sprintf("unsupported store type %q (supported: %s, %s)", store.Type, StoreTypeGitHub, StoreTypeGit)})
+		}
+		if store.Repo == "" {
+			errs = append(errs, &ValidationError{Key: key + ".repo", Message: "is required"})
+		} else if _, err := parse(store.Repo); err != nil {
+			errs = append(errs, &ValidationError{Key: key + ".repo", Message: err.Error()})
+		}
+		errs = append(errs, validatePatterns(key+".include", store.Include)...)
+		errs = append(errs, validatePatterns(key+".exclude", store.Exclude)...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validatePatterns checks that every glob pattern is non-empty and well formed
+func validatePatterns(key string, patterns []string) ValidationErrors {
+	var errs ValidationErrors
+	for i, pattern := range patterns {
+		itemKey := fmt.Sprintf("%s[%d]", key, i)
+		if strings.TrimSpace(pattern) == "" {
+			errs = append(errs, &ValidationError{Key: itemKey, Message: "must not be empty"})
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, &ValidationError{Key: itemKey, Message: fmt.Sprintf("invalid pattern %q", pattern)})
+		}
+	}
+	return errs
+}
+
+// RequireMemory returns an error unless the memory repository is configured.
+// It is checked by the memory commands only, so that other commands work without it.
+func (c *Config) RequireMemory() error {
+	if c.Memory.Repo == "" {
+		return &ValidationError{Key: "memory.repo", Message: "is required (set it in the config file or MEMORY_REPO)"}
+	}
 	return nil
 }
 
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the variables read by LoadConfig for the duration of the test
+func clearEnv(t *testing.T) {
+	for _, key := range []string{"DB_USER", "DB_PASSWORD", "DB_NAME", "DB_HOST", "DB_PORT", "GOOSE_TABLE", "MEMORY_REPO", "OPENAI_API_KEY", ConfigFileEnv} {
+		t.Setenv(key, "")
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const validConfig = `
+database:
+  user: postgres
+  password: secret
+  name: personal_agent
+  host: localhost
+embedding:
+  cache:
+    max_age: 720h
+memory:
+  repo: owner/memories
+stores:
+  - repo: owner/notes
+    include: ["**/*.md"]
+  - repo: https://github.com/owner/handbook
+    ref: main
+`
+
+func TestLoadConfig_File(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := LoadConfig(writeConfig(t, validConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Database.Port != "5432" {
+		t.Errorf("got port %q, want default 5432", cfg.Database.Port)
+	}
+	if cfg.Embedding.Provider != EmbeddingProviderOpenAI {
+		t.Errorf("got provider %q, want %q", cfg.Embedding.Provider, EmbeddingProviderOpenAI)
+	}
+	if cfg.Embedding.Cache.MaxAge != 720*time.Hour {
+		t.Errorf("got cache max age %v, want 720h", cfg.Embedding.Cache.MaxAge)
+	}
+	if cfg.Sync.BatchSize != 100 {
+		t.Errorf("got batch size %d, want default 100", cfg.Sync.BatchSize)
+	}
+	if len(cfg.Stores) != 2 || cfg.Stores[1].Type != StoreTypeGitHub || cfg.Stores[1].Ref != "main" {
+		t.Errorf("unexpected stores: %+v", cfg.Stores)
+	}
+}
+
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	cfg, err := LoadConfig(writeConfig(t, validConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Database.Host != "db.internal" {
+		t.Errorf("got host %q, want db.internal", cfg.Database.Host)
+	}
+	if cfg.Embedding.APIKey != "sk-test" {
+		t.Errorf("got api key %q, want sk-test", cfg.Embedding.APIKey)
+	}
+}
+
+func TestLoadConfig_EnvOnly(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_PASSWORD", "secret")
+	t.Setenv("DB_NAME", "personal_agent")
+	t.Setenv("DB_HOST", "localhost")
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The memory repository is only required by the memory commands
+	if err := cfg.RequireMemory(); err == nil {
+		t.Error("expected RequireMemory to fail without memory.repo")
+	}
+}
+
+func TestLoadConfig_ValidationErrorKeys(t *testing.T) {
+	clearEnv(t)
+
+	content := `
+database:
+  user: postgres
+  name: personal_agent
+  host: localhost
+embedding:
+  provider: cohere
+  overflow: split
+  cache:
+    max_entries: -1
+index:
+  type: ivfflat
+  m: 8
+  ef_construction: 8
+sync:
+  batch_size: -5
+stores:
+  - repo: owner/notes
+  - type: gitlab
+    repo: owner
+    exclude: ["[bad"]
+  - type: git
+    repo: https://gitlab.com/owner/notes.git
+  - type: git
+    repo: owner/notes
+`
+	_, err := LoadConfig(writeConfig(t, content))
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want ValidationErrors", err)
+	}
+
+	keys := make([]string, len(errs))
+	for i, e := range errs {
+		keys[i] = e.Key
+	}
+	want := []string{"database.password", "embedding.provider", "embedding.overflow", "embedding.cache.max_entries", "index.ef_construction", "sync.batch_size", "stores[1].type", "stores[1].repo", "stores[1].exclude[0]", "stores[3].repo"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("got keys %v, want %v", keys, want)
+	}
+}
+
+func TestLoadConfig_UnknownKey(t *testing.T) {
+	clearEnv(t)
+
+	_, err := LoadConfig(writeConfig(t, validConfig+"\nstore:\n  - repo: owner/typo\n"))
+	if err == nil || !strings.Contains(err.Error(), "field store not found") {
+		t.Errorf("got %v, want unknown field error", err)
+	}
+}
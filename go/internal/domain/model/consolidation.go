@@ -0,0 +1,214 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConsolidationThreshold is the cosine similarity above which memories are considered near-duplicates
+const DefaultConsolidationThreshold = 0.9
+
+// MemorySimilarity is the cosine similarity of the embeddings of two memories
+type MemorySimilarity struct {
+	PathA      string  `json:"path_a"`
+	PathB      string  `json:"path_b"`
+	Similarity float64 `json:"similarity"`
+}
+
+// MemoryMerge merges a cluster of near-duplicate memories into the newest one
+type MemoryMerge struct {
+	Keep       string            `json:"keep"`           // Path of the newest memory, whose wording is kept
+	Merge      []string          `json:"merge"`          // Paths of the memories merged into Keep and deleted
+	Similarity float64           `json:"min_similarity"` // Lowest similarity between two memories of the cluster
+	SHAs       map[string]string `json:"shas"`           // SHA of every memory of the cluster when the plan was made
+	Content    string            `json:"content"`        // New content of Keep
+}
+
+// ConsolidationPlan is the reviewed list of merges applied by "memory consolidate apply"
+type ConsolidationPlan struct {
+	Threshold float64       `json:"threshold"`
+	CreatedAt time.Time     `json:"created_at"`
+	Merges    []MemoryMerge `json:"merges"`
+}
+
+// ClusterMemories groups paths whose pairwise similarities are all in pairs (complete linkage),
+// joining the most similar pairs first, so that a chain of similar memories does not join
+// memories that are not similar to each other. Clusters of one memory are omitted.
+func ClusterMemories(pairs []MemorySimilarity) [][]string {
+	// Order the paths of every pair so that each pair has a single key
+	sorted := make([]MemorySimilarity, len(pairs))
+	similar := make(map[[2]string]bool, len(pairs))
+	for i, p := range pairs {
+		if p.PathA > p.PathB {
+			p.PathA, p.PathB = p.PathB, p.PathA
+		}
+		sorted[i] = p
+		similar[[2]string{p.PathA, p.PathB}] = true
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Similarity != sorted[j].Similarity {
+			return sorted[i].Similarity > sorted[j].Similarity
+		}
+		if sorted[i].PathA != sorted[j].PathA {
+			return sorted[i].PathA < sorted[j].PathA
+		}
+		return sorted[i].PathB < sorted[j].PathB
+	})
+	isSimilar := func(x, y string) bool {
+		if x > y {
+			x, y = y, x
+		}
+		return similar[[2]string{x, y}]
+	}
+
+	// Every path starts in a cluster of its own
+	clusterOf := make(map[string]int)
+	var clusters [][]string
+	clusterIndex := func(path string) int {
+		if i, ok := clusterOf[path]; ok {
+			return i
+		}
+		clusterOf[path] = len(clusters)
+		clusters = append(clusters, []string{path})
+		return len(clusters) - 1
+	}
+
+	for _, p := range sorted {
+		a, b := clusterIndex(p.PathA), clusterIndex(p.PathB)
+		if a == b {
+			continue
+		}
+		linked := true
+		for _, x := range clusters[a] {
+			for _, y := range clusters[b] {
+				linked = linked && isSimilar(x, y)
+			}
+		}
+		if !linked {
+			continue
+		}
+		for _, y := range clusters[b] {
+			clusterOf[y] = a
+		}
+		clusters[a] = append(clusters[a], clusters[b]...)
+		clusters[b] = nil
+	}
+
+	var result [][]string
+	for _, c := range clusters {
+		if len(c) > 1 {
+			sort.Strings(c)
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// MergeMemories returns the content of keep after merging the others into it: the body of keep is
+// kept as the newest wording, and the frontmatter combines the provenance of all memories. Tags are
+// united, the highest importance wins, the type and source of keep are used unless it has none,
+// the memory only expires if all of them do, and supersedes lists the merged memories. When the
+// memories come from more than one source, sources lists all of them, including the sources
+// listed by earlier merges.
+func MergeMemories(keep *Memory, others []*Memory) (string, error) {
+	fm := map[string]interface{}{}
+	body := keep.Content
+	if block, ok := frontmatter(keep.Content); ok {
+		if err := yaml.Unmarshal([]byte(block), &fm); err != nil {
+			return "", fmt.Errorf("invalid frontmatter of %s: %w", keep.Path, err)
+		}
+		if fm == nil {
+			fm = map[string]interface{}{}
+		}
+		body = strings.TrimPrefix(keep.Content[3+len(block)+3:], "\n")
+	}
+
+	all := append([]*Memory{keep}, others...)
+	tags := map[string]bool{}
+	supersedes := map[string]bool{}
+	sources := map[string]bool{}
+	memoryType, source, importance := keep.Type, keep.Source, keep.Importance
+	var expiresAt time.Time
+	neverExpires := false
+	for _, m := range all {
+		for _, t := range m.Tags {
+			tags[t] = true
+		}
+		for _, p := range m.Supersedes {
+			supersedes[p] = true
+		}
+		if m != keep {
+			supersedes[m.Path] = true
+		}
+		if memoryType == "" {
+			memoryType = m.Type
+		}
+		if source == "" {
+			source = m.Source
+		}
+		if m.Source != "" {
+			sources[m.Source] = true
+		}
+		for _, s := range mergedSources(m.Content) {
+			sources[s] = true
+		}
+		importance = max(importance, m.Importance)
+		if m.ExpiresAt.IsZero() {
+			neverExpires = true
+		} else if m.ExpiresAt.After(expiresAt) {
+			expiresAt = m.ExpiresAt
+		}
+	}
+	if neverExpires {
+		expiresAt = time.Time{}
+	}
+	delete(supersedes, keep.Path)
+
+	setOrDelete := func(key string, value interface{}, set bool) {
+		if set {
+			fm[key] = value
+		} else {
+			delete(fm, key)
+		}
+	}
+	setOrDelete("tags", sortedKeys(tags), len(tags) > 0)
+	setOrDelete("supersedes", sortedKeys(supersedes), len(supersedes) > 0)
+	setOrDelete("type", string(memoryType), memoryType != "")
+	setOrDelete("source", source, source != "")
+	setOrDelete("sources", sortedKeys(sources), len(sources) > 1)
+	setOrDelete("importance", importance, importance != 0)
+	setOrDelete("expires_at", expiresAt.UTC().Format(time.RFC3339), !expiresAt.IsZero())
+
+	data, err := yaml.Marshal(fm)
+	if err != nil {
+		return "", err
+	}
+	return "---\n" + string(data) + "---\n" + body, nil
+}
+
+// mergedSources returns the sources listed in the frontmatter of content by an earlier merge
+func mergedSources(content string) []string {
+	block, ok := frontmatter(content)
+	if !ok {
+		return nil
+	}
+	var fm memoryFrontmatter
+	if err := yaml.Unmarshal([]byte(block), &fm); err != nil {
+		return nil
+	}
+	return fm.Sources
+}
+
+// sortedKeys returns the keys of set in ascending order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
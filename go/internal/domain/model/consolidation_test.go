@@ -0,0 +1,119 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClusterMemories(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []MemorySimilarity
+		want  [][]string
+	}{
+		{
+			name: "no pairs",
+		},
+		{
+			name:  "one pair",
+			pairs: []MemorySimilarity{{"b", "a", 0.95}},
+			want:  [][]string{{"a", "b"}},
+		},
+		{
+			name: "fully linked triple",
+			pairs: []MemorySimilarity{
+				{"a", "b", 0.95}, {"b", "c", 0.93}, {"a", "c", 0.91},
+			},
+			want: [][]string{{"a", "b", "c"}},
+		},
+		{
+			name: "a chain does not join dissimilar memories",
+			pairs: []MemorySimilarity{
+				{"a", "b", 0.95}, {"b", "c", 0.92},
+			},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "the most similar pair wins",
+			pairs: []MemorySimilarity{
+				{"a", "b", 0.91}, {"b", "c", 0.97},
+			},
+			want: [][]string{{"b", "c"}},
+		},
+		{
+			name: "separate clusters",
+			pairs: []MemorySimilarity{
+				{"a", "b", 0.95}, {"x", "y", 0.92},
+			},
+			want: [][]string{{"a", "b"}, {"x", "y"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClusterMemories(tt.pairs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeMemories(t *testing.T) {
+	newMemory := func(path, content string) *Memory {
+		m := &Memory{Path: path, Content: content}
+		if err := m.SetMetadataFromContent(); err != nil {
+			t.Fatalf("invalid memory %s: %v", path, err)
+		}
+		return m
+	}
+	keep := newMemory("prefs/go-backend", "---\ntype: preference\ntags: [go]\nmood: sunny\n---\nLikes Go for backend work.\n")
+	older := newMemory("prefs/go", "---\nimportance: 4\nsource: slack/C1/p1\nexpires_at: 2027-01-01\nsupersedes: prefs/golang\ntags: [lang]\n---\nPrefers Go.\n")
+
+	content, err := MergeMemories(keep, []*Memory{older})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	merged := newMemory(keep.Path, content)
+	if merged.Type != MemoryTypePreference || merged.Importance != 4 || merged.Source != "slack/C1/p1" {
+		t.Errorf("got type %q, importance %d, source %q", merged.Type, merged.Importance, merged.Source)
+	}
+	if !merged.ExpiresAt.IsZero() {
+		t.Errorf("got expiry %v, want none because the kept memory never expires", merged.ExpiresAt)
+	}
+	if want := []string{"prefs/go", "prefs/golang"}; !reflect.DeepEqual(merged.Supersedes, want) {
+		t.Errorf("Supersedes = %v, want %v", merged.Supersedes, want)
+	}
+	want := "---\nimportance: 4\nmood: sunny\nsource: slack/C1/p1\nsupersedes:\n    - prefs/go\n    - prefs/golang\n" +
+		"tags:\n    - go\n    - lang\ntype: preference\n---\nLikes Go for backend work.\n"
+	if content != want {
+		t.Errorf("got content\n%s\nwant\n%s", content, want)
+	}
+
+	// The memory expires only if all merged memories do, at the latest expiry
+	keep.ExpiresAt = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	content, err = MergeMemories(keep, []*Memory{older})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := newMemory(keep.Path, content).ExpiresAt; !got.Equal(older.ExpiresAt) {
+		t.Errorf("got expiry %v, want %v", got, older.ExpiresAt)
+	}
+
+	// The sources of all merged memories are kept, including those of earlier merges
+	keep = newMemory("prefs/go-backend", "---\nsource: chat/42\n---\nLikes Go for backend work.\n")
+	earlier := newMemory("prefs/go-lang", "---\nsource: chat/7\nsources: [chat/3, chat/7]\n---\nLikes Go.\n")
+	content, err = MergeMemories(keep, []*Memory{older, earlier})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged := newMemory(keep.Path, content); merged.Source != "chat/42" {
+		t.Errorf("got source %q, want the source of the kept memory", merged.Source)
+	}
+	if !strings.Contains(content, "sources:\n    - chat/3\n    - chat/42\n    - chat/7\n    - slack/C1/p1\n") {
+		t.Errorf("got content without all sources\n%s", content)
+	}
+}
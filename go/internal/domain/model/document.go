@@ -1,10 +1,13 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"regexp"
 	"strings"
 	"time"
 
+	"golang.org/x/text/unicode/norm"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,13 +21,115 @@ type Document struct {
 	Content   string
 	Embedding []float64
 	Tags      []string
-	SHA       string
+	SHA       string // SHA-256 of the normalized content
+
+	BodySHA     string // SHA-256 of the embedded text: the normalized content below the frontmatter, if any
+	MetadataSHA string // SHA-256 of the normalized frontmatter
+
+	EmbeddingModel string // Name of the model that produced Embedding
+	TokenCount     int    // Number of tokens of the embedded text; zero when unknown
+	Encoding       string // Encoding of the source file that Content was decoded from
 
 	ModifiedAt time.Time // The time when the document was last modified. This is used to detect changes in the document.
+	LastCommit Commit    // The last commit that changed the document; zero when the source has no history
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
 
+// Commit describes the last commit that changed a file
+type Commit struct {
+	SHA     string
+	Author  string
+	Message string    // Subject line of the commit message
+	Time    time.Time // Commit time
+}
+
+// SetLastCommit records the last commit that changed the document and takes its time as the
+// modification time
+func (d *Document) SetLastCommit(commit Commit) {
+	d.LastCommit = commit
+	if !commit.Time.IsZero() {
+		d.ModifiedAt = commit.Time
+	}
+}
+
+// CommitSubject returns the first line of a commit message
+func CommitSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(subject)
+}
+
+// Encodings of the source files that are decoded into UTF-8 content
+const (
+	EncodingUTF8     = "UTF-8"
+	EncodingUTF16LE  = "UTF-16LE"
+	EncodingUTF16BE  = "UTF-16BE"
+	EncodingShiftJIS = "Shift_JIS"
+	EncodingEUCJP    = "EUC-JP"
+)
+
+// SetContentHashes sets SHA, BodySHA and MetadataSHA from the normalized content, so that
+// changes of line endings or trailing whitespace do not change the document and changes of
+// the frontmatter alone keep BodySHA, unless the document has no body
+func (d *Document) SetContentHashes() {
+	d.SHA, d.BodySHA, d.MetadataSHA = ContentHashes(d.Content)
+}
+
+// ContentHashes returns the SHA-256 of the normalized content, of the text embedded for it and
+// of the normalized frontmatter. The embedded text is the whole content of a file without a body,
+// so that a change of its frontmatter changes the body SHA and the file is embedded again.
+func ContentHashes(content string) (sha, bodySHA, metadataSHA string) {
+	content = NormalizeText(content)
+	metadata, _ := SplitFrontmatter(content)
+	return hashText(content), hashText(embeddingText(content)), hashText(metadata)
+}
+
+// EmbeddingText returns the text embedded for the document: the normalized content below the
+// frontmatter, or the whole content when there is nothing below it
+func (d *Document) EmbeddingText() string {
+	return embeddingText(d.Content)
+}
+
+// embeddingText returns the normalized content below the frontmatter, or the whole normalized
+// content when there is nothing below it
+func embeddingText(content string) string {
+	content = NormalizeText(content)
+	if _, body := SplitFrontmatter(content); strings.TrimSpace(body) != "" {
+		return body
+	}
+	return content
+}
+
+// NormalizeText returns text in Unicode NFC with LF line endings and without whitespace at the
+// end of lines and of the text
+func NormalizeText(text string) string {
+	text = norm.NFC.String(text)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// SplitFrontmatter splits content into its frontmatter, including the "---" delimiters, and the body
+// below it. The frontmatter is empty when the content has none.
+func SplitFrontmatter(content string) (metadata, body string) {
+	block, ok := frontmatter(content)
+	if !ok {
+		return "", content
+	}
+	end := len("---") + len(block) + len("---")
+	return content[:end], strings.TrimLeft(content[end:], "\n")
+}
+
+// hashText returns the hex encoded SHA-256 of text
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
 // represent a document entry in the knowledge base
 type DocumentEntry struct {
 	Path       string
@@ -38,27 +143,27 @@ type MemoryEntry struct {
 
 // set document tag from its content (Obsidian markdown style, including YAML frontmatter)
 func (d *Document) SetTagsFromContent() {
+	d.Tags = tagsFromContent(d.Content)
+}
+
+// tagsFromContent returns the deduplicated tags of the frontmatter and the #tag style tags of content
+func tagsFromContent(content string) []string {
 	tagSet := make(map[string]struct{})
 
 	// 1. Extract tags from YAML frontmatter if present
-	content := d.Content
-	if strings.HasPrefix(content, "---") {
-		end := strings.Index(content[3:], "---")
-		if end != -1 {
-			yamlBlock := content[3 : 3+end]
-			var fm map[string]interface{}
-			if err := yaml.Unmarshal([]byte(yamlBlock), &fm); err == nil {
-				if tags, ok := fm["tags"]; ok {
-					switch v := tags.(type) {
-					case []interface{}:
-						for _, t := range v {
-							if tagStr, ok := t.(string); ok {
-								tagSet[tagStr] = struct{}{}
-							}
+	if yamlBlock, ok := frontmatter(content); ok {
+		var fm map[string]interface{}
+		if err := yaml.Unmarshal([]byte(yamlBlock), &fm); err == nil {
+			if tags, ok := fm["tags"]; ok {
+				switch v := tags.(type) {
+				case []interface{}:
+					for _, t := range v {
+						if tagStr, ok := t.(string); ok {
+							tagSet[tagStr] = struct{}{}
 						}
-					case string:
-						tagSet[v] = struct{}{}
 					}
+				case string:
+					tagSet[v] = struct{}{}
 				}
 			}
 		}
@@ -74,9 +179,57 @@ func (d *Document) SetTagsFromContent() {
 		}
 	}
 
-	// 3. Set to d.Tags (deduplicated)
-	d.Tags = make([]string, 0, len(tagSet))
+	// 3. Return the tags (deduplicated)
+	tags := make([]string, 0, len(tagSet))
 	for tag := range tagSet {
-		d.Tags = append(d.Tags, tag)
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// frontmatter returns the YAML block between the leading "---" delimiters of content
+func frontmatter(content string) (string, bool) {
+	if !strings.HasPrefix(content, "---") {
+		return "", false
+	}
+	end := strings.Index(content[3:], "---")
+	if end == -1 {
+		return "", false
+	}
+	return content[3 : 3+end], true
+}
+
+// DocumentVersion is a content of a document. Previous versions are kept when a sync changes the
+// content; the current content is the version after the last kept one.
+type DocumentVersion struct {
+	Number         int // Number of the version within the document, starting at 1
+	StoreId        StoreId
+	Path           string
+	Content        string
+	SHA            string
+	EmbeddingModel string
+	ModifiedAt     time.Time
+	LastCommit     Commit
+	ReplacedAt     *time.Time // When a sync replaced the version; nil for the current content
+}
+
+// CurrentVersion returns the current content of the document as the version numbered number
+func (d *Document) CurrentVersion(number int) *DocumentVersion {
+	return &DocumentVersion{
+		Number:         number,
+		StoreId:        d.StoreId,
+		Path:           d.Path,
+		Content:        d.Content,
+		SHA:            d.SHA,
+		EmbeddingModel: d.EmbeddingModel,
+		ModifiedAt:     d.ModifiedAt,
+		LastCommit:     d.LastCommit,
 	}
 }
+
+// DocumentDiff is the difference between two versions of a document
+type DocumentDiff struct {
+	From *DocumentVersion
+	To   *DocumentVersion
+	Diff string // Unified diff of the contents; empty when they are equal
+}
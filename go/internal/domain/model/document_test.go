@@ -104,3 +104,83 @@ func equalStringSliceIgnoreOrder(a, b []string) bool {
 	}
 	return reflect.DeepEqual(ma, mb)
 }
+
+func TestCommitSubject(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{message: "Add notes", want: "Add notes"},
+		{message: "Add notes\n\nLonger description", want: "Add notes"},
+		{message: "\n  Fix typo  \r\nbody", want: "Fix typo"},
+		{message: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := CommitSubject(tt.message); got != tt.want {
+			t.Errorf("CommitSubject(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestSetContentHashes(t *testing.T) {
+	const base = "---\ntags: [a]\n---\n# Café\n\nBody text.\n"
+	hashes := func(content string) Document {
+		d := Document{Content: content}
+		d.SetContentHashes()
+		return d
+	}
+	original := hashes(base)
+
+	tests := []struct {
+		name         string
+		content      string
+		sameSHA      bool
+		sameBody     bool
+		sameMetadata bool
+	}{
+		{name: "crlf line endings", content: "---\r\ntags: [a]\r\n---\r\n# Café\r\n\r\nBody text.\r\n", sameSHA: true, sameBody: true, sameMetadata: true},
+		{name: "trailing whitespace", content: "---\ntags: [a]  \n---\n# Café\t\n\nBody text. \n\n\n", sameSHA: true, sameBody: true, sameMetadata: true},
+		{name: "decomposed unicode", content: "---\ntags: [a]\n---\n# Cafe\u0301\n\nBody text.\n", sameSHA: true, sameBody: true, sameMetadata: true},
+		{name: "frontmatter change", content: "---\ntags: [a, b]\n---\n# Café\n\nBody text.\n", sameBody: true},
+		{name: "body change", content: "---\ntags: [a]\n---\n# Café\n\nOther text.\n", sameMetadata: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hashes(tt.content)
+			if (got.SHA == original.SHA) != tt.sameSHA {
+				t.Errorf("SHA equal = %v, want %v", got.SHA == original.SHA, tt.sameSHA)
+			}
+			if (got.BodySHA == original.BodySHA) != tt.sameBody {
+				t.Errorf("BodySHA equal = %v, want %v", got.BodySHA == original.BodySHA, tt.sameBody)
+			}
+			if (got.MetadataSHA == original.MetadataSHA) != tt.sameMetadata {
+				t.Errorf("MetadataSHA equal = %v, want %v", got.MetadataSHA == original.MetadataSHA, tt.sameMetadata)
+			}
+		})
+	}
+
+	// A file without a body embeds its frontmatter, so a change of it changes BodySHA
+	if hashes("---\ntags: [a]\n---\n").BodySHA == hashes("---\ntags: [b]\n---\n").BodySHA {
+		t.Error("a frontmatter change of a file without a body kept BodySHA")
+	}
+}
+
+func TestEmbeddingText(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "---\ntags: [a]\n---\n\n# Title\r\nBody  \n", want: "# Title\nBody"},
+		{content: "# Title\nBody\n", want: "# Title\nBody"},
+		{content: "---\ntitle: Only metadata\n---\n", want: "---\ntitle: Only metadata\n---"},
+	}
+
+	for _, tt := range tests {
+		d := Document{Content: tt.content}
+		if got := d.EmbeddingText(); got != tt.want {
+			t.Errorf("EmbeddingText() of %q = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// EmbeddingCacheStats describes the contents of the embedding cache
+type EmbeddingCacheStats struct {
+	Entries   int
+	SizeBytes int64
+	// Hits is the number of lookups served by the current entries
+	Hits         int64
+	OldestUsedAt *time.Time
+	NewestUsedAt *time.Time
+}
+
+// HitRate returns the share of embeddings served from the cache. Every entry was
+// created by one miss, so this is the hit rate over the lifetime of the current entries.
+func (s *EmbeddingCacheStats) HitRate() float64 {
+	return hitRate(s.Hits, int64(s.Entries))
+}
+
+// EmbeddingCacheUsage counts the cache lookups of a single run
+type EmbeddingCacheUsage struct {
+	Hits   int64
+	Misses int64
+}
+
+// HitRate returns the share of lookups served from the cache
+func (u EmbeddingCacheUsage) HitRate() float64 {
+	return hitRate(u.Hits, u.Misses)
+}
+
+func hitRate(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
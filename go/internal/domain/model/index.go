@@ -0,0 +1,111 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	IndexMethodHNSW    = "hnsw"
+	IndexMethodIVFFlat = "ivfflat"
+)
+
+// VectorTables are the tables that have an embedding column
+var VectorTables = []string{"documents", "memories"}
+
+var (
+	ErrUnsupportedIndexMethod = errors.New("unsupported index method")
+	ErrUnknownVectorTable     = errors.New("unknown vector table")
+)
+
+// VectorIndexSpec describes an approximate nearest-neighbour index on the embedding column of a table
+type VectorIndexSpec struct {
+	Table  string
+	Method string
+	// M and EfConstruction are used by HNSW indexes
+	M              int
+	EfConstruction int
+	// Lists is used by IVFFlat indexes
+	Lists int
+}
+
+// IndexName returns the name of the vector index of the table
+func (s VectorIndexSpec) IndexName() string {
+	return VectorIndexName(s.Table)
+}
+
+// Validate checks the table and the parameters of the selected method
+func (s VectorIndexSpec) Validate() error {
+	if !IsVectorTable(s.Table) {
+		return fmt.Errorf("%w: %q", ErrUnknownVectorTable, s.Table)
+	}
+	switch s.Method {
+	case IndexMethodHNSW:
+		if s.M < 2 || s.M > 100 {
+			return fmt.Errorf("m must be between 2 and 100, got %d", s.M)
+		}
+		if s.EfConstruction < 2*s.M || s.EfConstruction > 1000 {
+			return fmt.Errorf("ef_construction must be between 2*m and 1000, got %d", s.EfConstruction)
+		}
+	case IndexMethodIVFFlat:
+		if s.Lists < 1 || s.Lists > 32768 {
+			return fmt.Errorf("lists must be between 1 and 32768, got %d", s.Lists)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedIndexMethod, s.Method)
+	}
+	return nil
+}
+
+// VectorIndex is the state of the vector index of a table
+type VectorIndex struct {
+	Table string
+	// Name is empty when the table has no vector index
+	Name       string
+	Method     string
+	Definition string
+	// Valid is false when a build was interrupted; such an index is not used by queries
+	Valid     bool
+	SizeBytes int64
+	// Lists is the number of IVFFlat lists, parsed from the definition
+	Lists int
+	// Rows is the number of rows in the table and EmbeddedRows the number with an embedding
+	Rows         int
+	EmbeddedRows int
+}
+
+// Exists reports whether the table has a vector index
+func (i *VectorIndex) Exists() bool {
+	return i.Name != ""
+}
+
+// VectorIndexName returns the name of the vector index of a table
+func VectorIndexName(table string) string {
+	return "idx_" + table + "_embedding"
+}
+
+// IsVectorTable reports whether the table has an embedding column
+func IsVectorTable(table string) bool {
+	for _, t := range VectorTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// RecommendedLists returns the number of IVFFlat lists recommended by pgvector:
+// rows / 1000 up to one million rows and sqrt(rows) above
+func RecommendedLists(rows int) int {
+	var lists int
+	if rows <= 1000000 {
+		lists = rows / 1000
+	} else {
+		lists = int(math.Sqrt(float64(rows)))
+	}
+	if lists < 1 {
+		lists = 1
+	}
+	return lists
+}
@@ -1,9 +1,66 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 type MemoryId string
 
+// MemoryType tells what kind of information a memory holds
+type MemoryType string
+
+const (
+	MemoryTypePreference  MemoryType = "preference"  // How the user likes things done
+	MemoryTypeFact        MemoryType = "fact"        // Something true about the user or the world
+	MemoryTypeInstruction MemoryType = "instruction" // A standing order for the agent
+	MemoryTypeEvent       MemoryType = "event"       // Something that happened or will happen
+)
+
+// MemoryTypes lists the valid memory types
+var MemoryTypes = []MemoryType{MemoryTypePreference, MemoryTypeFact, MemoryTypeInstruction, MemoryTypeEvent}
+
+// ParseMemoryType validates a memory type; the empty string means no type
+func ParseMemoryType(s string) (MemoryType, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, t := range MemoryTypes {
+		if MemoryType(s) == t {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid memory type %q: must be one of %v", s, MemoryTypes)
+}
+
+// NormalizeMemoryPath returns the path of a memory relative to the memory directory and without
+// the .md extension, e.g. "preferences/editor" for ".memories/preferences/editor.md".
+// It fails for empty paths and paths outside the memory directory.
+func NormalizeMemoryPath(p string) (string, error) {
+	p = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(p), ".memories/"), ".md")
+	if p == "" {
+		return "", fmt.Errorf("memory path must not be empty")
+	}
+	if path.IsAbs(p) {
+		return "", fmt.Errorf("invalid memory path %q: must be relative", p)
+	}
+	cleaned := path.Clean(p)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid memory path %q: must be inside the memory directory", p)
+	}
+	return cleaned, nil
+}
+
+// Bounds of Memory.Importance
+const (
+	MinImportance = 1
+	MaxImportance = 5
+)
+
 // represent a memory
 type Memory struct {
 	ID        MemoryId
@@ -11,9 +68,134 @@ type Memory struct {
 	Content   string
 	Embedding []float64
 	Tags      []string
-	SHA       string
+	SHA       string // SHA-256 of the normalized content
+
+	BodySHA     string // SHA-256 of the embedded text: the normalized content below the frontmatter, if any
+	MetadataSHA string // SHA-256 of the normalized frontmatter
+
+	EmbeddingModel string // Name of the model that produced Embedding
+	TokenCount     int    // Number of tokens of the embedded text; zero when unknown
+
+	Type       MemoryType // Empty when not set
+	Importance int        // From MinImportance to MaxImportance; zero when not set
+	Source     string     // Where the memory came from, e.g. a conversation ID or Slack thread URL
+	ExpiresAt  time.Time  // Zero when the memory never expires
+	Supersedes []string   // Paths of the memories this memory replaces, as returned by NormalizeMemoryPath
+	ArchivedAt time.Time  // Set when the memory is swept; zero while it is active
 
 	ModifiedAt time.Time
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
+
+// SetContentHashes sets SHA, BodySHA and MetadataSHA from the normalized content, as for
+// documents, so that changes of the frontmatter alone keep BodySHA unless there is no body
+func (m *Memory) SetContentHashes() {
+	m.SHA, m.BodySHA, m.MetadataSHA = ContentHashes(m.Content)
+}
+
+// EmbeddingText returns the text embedded for the memory: the normalized content below the
+// frontmatter, or the whole content when there is nothing below it
+func (m *Memory) EmbeddingText() string {
+	return embeddingText(m.Content)
+}
+
+// memoryFrontmatter is the frontmatter of a memory file
+type memoryFrontmatter struct {
+	Type       string     `yaml:"type"`
+	Importance int        `yaml:"importance"`
+	Source     string     `yaml:"source"`
+	Sources    stringList `yaml:"sources"` // Every source of a memory merged from memories of several sources
+	ExpiresAt  string     `yaml:"expires_at"`
+	Supersedes stringList `yaml:"supersedes"`
+}
+
+// stringList accepts a single string as well as a list of strings
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = stringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// expiresAtLayouts are the accepted formats of expires_at; a date expires at the start of the day in UTC
+var expiresAtLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// SetMetadataFromContent sets the tags and the type, importance, source, expiry and
+// superseded memories from the frontmatter of the content. It fails if the frontmatter is invalid.
+func (m *Memory) SetMetadataFromContent() error {
+	m.Tags = tagsFromContent(m.Content)
+	m.Type, m.Importance, m.Source, m.ExpiresAt, m.Supersedes = "", 0, "", time.Time{}, nil
+
+	yamlBlock, ok := frontmatter(m.Content)
+	if !ok {
+		return nil
+	}
+	var fm memoryFrontmatter
+	if err := yaml.Unmarshal([]byte(yamlBlock), &fm); err != nil {
+		return fmt.Errorf("invalid frontmatter: %w", err)
+	}
+
+	memoryType, err := ParseMemoryType(strings.TrimSpace(fm.Type))
+	if err != nil {
+		return err
+	}
+	if fm.Importance != 0 && (fm.Importance < MinImportance || fm.Importance > MaxImportance) {
+		return fmt.Errorf("invalid importance %d: must be between %d and %d", fm.Importance, MinImportance, MaxImportance)
+	}
+	var expiresAt time.Time
+	if s := strings.TrimSpace(fm.ExpiresAt); s != "" {
+		if expiresAt, err = parseExpiresAt(s); err != nil {
+			return err
+		}
+	}
+	var supersedes []string
+	for _, p := range fm.Supersedes {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		superseded, err := NormalizeMemoryPath(p)
+		if err != nil {
+			return fmt.Errorf("invalid supersedes: %w", err)
+		}
+		if superseded != m.Path {
+			supersedes = append(supersedes, superseded)
+		}
+	}
+
+	m.Type = memoryType
+	m.Importance = fm.Importance
+	m.Source = strings.TrimSpace(fm.Source)
+	m.ExpiresAt = expiresAt
+	m.Supersedes = supersedes
+	return nil
+}
+
+// parseExpiresAt parses expires_at in one of expiresAtLayouts
+func parseExpiresAt(s string) (time.Time, error) {
+	for _, layout := range expiresAtLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expires_at %q: use a date (2006-01-02) or an RFC 3339 time", s)
+}
+
+// Expired reports whether the memory has an expiry that is not after now
+func (m *Memory) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// SweepResult is the outcome of archiving expired and superseded memories
+type SweepResult struct {
+	Expired    []string // Paths of the memories archived because they expired
+	Superseded []string // Paths of the memories archived because an active memory supersedes them
+}
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMemorySetMetadataFromContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Memory
+		wantErr bool
+	}{
+		{
+			name:    "no frontmatter",
+			content: "Likes green tea #drinks",
+			want:    Memory{Tags: []string{"drinks"}},
+		},
+		{
+			name: "all fields",
+			content: "---\ntype: preference\nimportance: 4\nsource: https://example.slack.com/archives/C1/p1\n" +
+				"expires_at: 2026-12-31\nsupersedes:\n  - prefs/tea.md\n  - prefs/coffee.md\ntags: [drinks]\n---\nLikes green tea",
+			want: Memory{
+				Tags:       []string{"drinks"},
+				Type:       MemoryTypePreference,
+				Importance: 4,
+				Source:     "https://example.slack.com/archives/C1/p1",
+				ExpiresAt:  time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+				Supersedes: []string{"prefs/tea", "prefs/coffee"},
+			},
+		},
+		{
+			name:    "RFC 3339 expiry and single superseded path",
+			content: "---\nexpires_at: 2026-10-19T09:30:00+09:00\nsupersedes: prefs/tea.md\n---\n",
+			want: Memory{
+				Tags:       []string{},
+				ExpiresAt:  time.Date(2026, 10, 19, 0, 30, 0, 0, time.UTC),
+				Supersedes: []string{"prefs/tea"},
+			},
+		},
+		{
+			name:    "a memory does not supersede itself",
+			content: "---\nsupersedes: [.memories/mem.md]\n---\n",
+			want:    Memory{Tags: []string{}},
+		},
+		{name: "unknown type", content: "---\ntype: rumor\n---\n", wantErr: true},
+		{name: "importance out of range", content: "---\nimportance: 6\n---\n", wantErr: true},
+		{name: "invalid expiry", content: "---\nexpires_at: next week\n---\n", wantErr: true},
+		{name: "superseded path outside the memory directory", content: "---\nsupersedes: ../x\n---\n", wantErr: true},
+		{name: "invalid YAML", content: "---\ntype: [fact\n---\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Memory{Path: "mem", Content: tt.content, Type: MemoryTypeEvent, Importance: 1}
+			err := m.SetMetadataFromContent()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			sort.Strings(m.Tags)
+			if !reflect.DeepEqual(m.Tags, tt.want.Tags) {
+				t.Errorf("Tags = %v, want %v", m.Tags, tt.want.Tags)
+			}
+			if m.Type != tt.want.Type || m.Importance != tt.want.Importance || m.Source != tt.want.Source {
+				t.Errorf("got type %q, importance %d, source %q; want %q, %d, %q",
+					m.Type, m.Importance, m.Source, tt.want.Type, tt.want.Importance, tt.want.Source)
+			}
+			if !m.ExpiresAt.Equal(tt.want.ExpiresAt) {
+				t.Errorf("ExpiresAt = %v, want %v", m.ExpiresAt, tt.want.ExpiresAt)
+			}
+			if !reflect.DeepEqual(m.Supersedes, tt.want.Supersedes) {
+				t.Errorf("Supersedes = %v, want %v", m.Supersedes, tt.want.Supersedes)
+			}
+		})
+	}
+}
+
+func TestMemoryExpired(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiresAt time.Time
+		want      bool
+	}{
+		{time.Time{}, false},
+		{now.Add(time.Second), false},
+		{now, true},
+		{now.Add(-time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		m := Memory{ExpiresAt: tt.expiresAt}
+		if got := m.Expired(now); got != tt.want {
+			t.Errorf("Expired with expiry %v = %v, want %v", tt.expiresAt, got, tt.want)
+		}
+	}
+}
+
+func TestMemorySetContentHashes(t *testing.T) {
+	const base = "---\ntype: preference\nimportance: 3\n---\nPrefers tabs.\n"
+	hashes := func(content string) Memory {
+		m := Memory{Content: content}
+		m.SetContentHashes()
+		return m
+	}
+	original := hashes(base)
+
+	tests := []struct {
+		name     string
+		content  string
+		sameSHA  bool
+		sameBody bool
+	}{
+		{name: "crlf line endings", content: "---\r\ntype: preference\r\nimportance: 3\r\n---\r\nPrefers tabs.\r\n", sameSHA: true, sameBody: true},
+		{name: "importance change", content: "---\ntype: preference\nimportance: 5\nexpires_at: 2027-01-01\n---\nPrefers tabs.\n", sameBody: true},
+		{name: "body change", content: "---\ntype: preference\nimportance: 3\n---\nPrefers spaces.\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hashes(tt.content)
+			if (got.SHA == original.SHA) != tt.sameSHA {
+				t.Errorf("SHA equal = %v, want %v", got.SHA == original.SHA, tt.sameSHA)
+			}
+			if (got.BodySHA == original.BodySHA) != tt.sameBody {
+				t.Errorf("BodySHA equal = %v, want %v", got.BodySHA == original.BodySHA, tt.sameBody)
+			}
+		})
+	}
+	if got := original.EmbeddingText(); got != "Prefers tabs." {
+		t.Errorf("EmbeddingText() = %q, want %q", got, "Prefers tabs.")
+	}
+}
+
+func TestNormalizeMemoryPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "preferences/editor", want: "preferences/editor"},
+		{path: ".memories/preferences/editor.md", want: "preferences/editor"},
+		{path: "facts//./home.md", want: "facts/home"},
+		{path: "a/../b", want: "b"},
+		{path: "", wantErr: true},
+		{path: ".md", wantErr: true},
+		{path: "/etc/passwd", wantErr: true},
+		{path: "../outside", wantErr: true},
+		{path: "a/../..", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeMemoryPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeMemoryPath(%q) error = %v, want error: %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeMemoryPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
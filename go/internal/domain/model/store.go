@@ -1,26 +1,118 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
 
 type StoreId uint
 
 const (
 	StoreTypeGitHub = "github"
+	StoreTypeGit    = "git"
 )
 
 var (
 	// ErrUnsupportedStoreType is returned when an unsupported store type is provided
 	ErrUnsupportedStoreType = errors.New("unsupported store type")
+
+	// ErrInvalidRepository is returned when a repository reference cannot be parsed
+	ErrInvalidRepository = errors.New("invalid repository")
 )
 
 type DocumentStore interface {
 	ID() StoreId
 	Type() string
+	Filters() StoreFilters
+	LastSyncedAt() *time.Time
+}
+
+// RevisionStore is a store that remembers the revision of its source at the last successful sync,
+// so that the next sync only reads the paths changed since then
+type RevisionStore interface {
+	DocumentStore
+	SyncedRevision() string
+}
+
+// StoreFilters restricts which paths of a store are synchronized.
+// Patterns use path.Match syntax, with "**" matching any number of directories.
+type StoreFilters struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Match reports whether the given path passes the include and exclude patterns.
+// An empty include list matches every path.
+func (f StoreFilters) Match(p string) bool {
+	p = strings.TrimPrefix(path.Clean(p), "/")
+
+	for _, pattern := range f.Exclude {
+		if matchPattern(pattern, p) {
+			return false
+		}
+	}
+
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if matchPattern(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether no filters are configured
+func (f StoreFilters) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// matchPattern matches a slash separated path against a glob pattern.
+// A pattern ending in "/" matches everything below that directory.
+func matchPattern(pattern, p string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// "**" consumes zero or more segments
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], segments[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	return len(segments) == 0
 }
 
 type GitHubStore struct {
-	id   StoreId
-	repo string // owner/repo
+	id           StoreId
+	repo         string // owner/repo
+	ref          string // branch, tag or commit; empty means the default branch
+	filters      StoreFilters
+	lastSyncedAt *time.Time
 }
 
 // NewGitHubStore creates a new GitHub store instance
@@ -45,3 +137,253 @@ func (s *GitHubStore) Type() string {
 func (s *GitHubStore) Repo() string {
 	return s.repo
 }
+
+// SetRepo changes the GitHub repository
+func (s *GitHubStore) SetRepo(repo string) {
+	s.repo = repo
+}
+
+// Ref returns the git ref to sync, or an empty string for the default branch
+func (s *GitHubStore) Ref() string {
+	return s.ref
+}
+
+// SetRef changes the git ref to sync
+func (s *GitHubStore) SetRef(ref string) {
+	s.ref = ref
+}
+
+// Filters returns the path filters of the store
+func (s *GitHubStore) Filters() StoreFilters {
+	return s.filters
+}
+
+// SetFilters changes the path filters of the store
+func (s *GitHubStore) SetFilters(filters StoreFilters) {
+	s.filters = filters
+}
+
+// LastSyncedAt returns the time of the last successful sync, or nil if never synced
+func (s *GitHubStore) LastSyncedAt() *time.Time {
+	return s.lastSyncedAt
+}
+
+// SetLastSyncedAt records the time of the last successful sync
+func (s *GitHubStore) SetLastSyncedAt(t *time.Time) {
+	s.lastSyncedAt = t
+}
+
+// githubNamePattern matches a valid GitHub owner or repository name
+var githubNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ParseGitHubRepo normalizes a GitHub repository reference into "owner/repo".
+// It accepts "owner/repo", HTTPS URLs, SSH remotes ("git@github.com:owner/repo.git")
+// and "ssh://" URLs.
+func ParseGitHubRepo(input string) (string, error) {
+	s := strings.TrimSpace(input)
+	if s == "" {
+		return "", fmt.Errorf("%w: repository cannot be empty", ErrInvalidRepository)
+	}
+
+	switch {
+	case strings.HasPrefix(s, "git@"):
+		// git@github.com:owner/repo.git
+		host, rest, ok := strings.Cut(strings.TrimPrefix(s, "git@"), ":")
+		if !ok || !isGitHubHost(host) {
+			return "", fmt.Errorf("%w: %q is not a GitHub SSH remote", ErrInvalidRepository, input)
+		}
+		s = rest
+	case strings.Contains(s, "://"):
+		// https://github.com/owner/repo, ssh://git@github.com/owner/repo.git
+		_, rest, _ := strings.Cut(s, "://")
+		if at := strings.Index(rest, "@"); at != -1 && at < strings.Index(rest+"/", "/") {
+			rest = rest[at+1:]
+		}
+		host, p, _ := strings.Cut(rest, "/")
+		if !isGitHubHost(host) {
+			return "", fmt.Errorf("%w: %q is not a GitHub URL", ErrInvalidRepository, input)
+		}
+		s = p
+	case strings.HasPrefix(s, "github.com/") || strings.HasPrefix(s, "www.github.com/"):
+		_, s, _ = strings.Cut(s, "/")
+	}
+
+	// Drop query, fragment, trailing slash and .git suffix
+	if i := strings.IndexAny(s, "?#"); i != -1 {
+		s = s[:i]
+	}
+	s = strings.TrimSuffix(strings.Trim(s, "/"), ".git")
+
+	parts := strings.Split(s, "/")
+	if len(parts) > 2 && (parts[2] == "tree" || parts[2] == "blob") {
+		// Browser URLs such as https://github.com/owner/repo/tree/main
+		parts = parts[:2]
+	}
+	if len(parts) != 2 || !githubNamePattern.MatchString(parts[0]) || !githubNamePattern.MatchString(parts[1]) {
+		return "", fmt.Errorf("%w: %q, expected owner/repo", ErrInvalidRepository, input)
+	}
+	if parts[1] == "." || parts[1] == ".." {
+		return "", fmt.Errorf("%w: %q, expected owner/repo", ErrInvalidRepository, input)
+	}
+
+	return parts[0] + "/" + parts[1], nil
+}
+
+func isGitHubHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "github.com" || host == "www.github.com"
+}
+
+// GitStore syncs documents from any git remote through a local mirror
+type GitStore struct {
+	id             StoreId
+	url            string // URL of the git remote
+	ref            string // branch or tag; empty means the default branch
+	filters        StoreFilters
+	lastSyncedAt   *time.Time
+	syncedRevision string // commit of the last successful sync; empty means a full sync is needed
+}
+
+// NewGitStore creates a new git store instance
+func NewGitStore(id StoreId, url string) *GitStore {
+	return &GitStore{
+		id:  id,
+		url: url,
+	}
+}
+
+// ID returns the store ID
+func (s *GitStore) ID() StoreId {
+	return s.id
+}
+
+// Type returns the store type
+func (s *GitStore) Type() string {
+	return StoreTypeGit
+}
+
+// URL returns the URL of the git remote
+func (s *GitStore) URL() string {
+	return s.url
+}
+
+// SetURL changes the git remote; a new remote forgets the synced revision
+func (s *GitStore) SetURL(url string) {
+	if url != s.url {
+		s.syncedRevision = ""
+	}
+	s.url = url
+}
+
+// Ref returns the branch or tag to sync, or an empty string for the default branch
+func (s *GitStore) Ref() string {
+	return s.ref
+}
+
+// SetRef changes the branch or tag to sync; a new ref forgets the synced revision
+func (s *GitStore) SetRef(ref string) {
+	if ref != s.ref {
+		s.syncedRevision = ""
+	}
+	s.ref = ref
+}
+
+// Filters returns the path filters of the store
+func (s *GitStore) Filters() StoreFilters {
+	return s.filters
+}
+
+// SetFilters changes the path filters of the store. The synced revision is forgotten because
+// paths that were excluded until now must be read although they did not change.
+func (s *GitStore) SetFilters(filters StoreFilters) {
+	s.syncedRevision = ""
+	s.filters = filters
+}
+
+// LastSyncedAt returns the time of the last successful sync, or nil if never synced
+func (s *GitStore) LastSyncedAt() *time.Time {
+	return s.lastSyncedAt
+}
+
+// SetLastSyncedAt records the time of the last successful sync
+func (s *GitStore) SetLastSyncedAt(t *time.Time) {
+	s.lastSyncedAt = t
+}
+
+// SyncedRevision returns the commit of the last successful sync, or an empty string
+func (s *GitStore) SyncedRevision() string {
+	return s.syncedRevision
+}
+
+// SetSyncedRevision records the commit of the last successful sync
+func (s *GitStore) SetSyncedRevision(revision string) {
+	s.syncedRevision = revision
+}
+
+// gitURLSchemes are the URL schemes accepted for git remotes
+var gitURLSchemes = []string{"http", "https", "ssh", "git", "file"}
+
+// ParseGitURL validates the URL of a git remote and removes trailing slashes.
+// It accepts http, https, ssh, git and file URLs and scp-like SSH remotes ("git@host:owner/repo.git").
+func ParseGitURL(input string) (string, error) {
+	s := strings.TrimRight(strings.TrimSpace(input), "/")
+	if s == "" {
+		return "", fmt.Errorf("%w: URL cannot be empty", ErrInvalidRepository)
+	}
+
+	if scheme, _, ok := strings.Cut(s, "://"); ok {
+		supported := false
+		for _, allowed := range gitURLSchemes {
+			supported = supported || strings.EqualFold(scheme, allowed)
+		}
+		if !supported {
+			return "", fmt.Errorf("%w: unsupported scheme %q in %q (supported: %s)", ErrInvalidRepository, scheme, input, strings.Join(gitURLSchemes, ", "))
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidRepository, err)
+		}
+		if u.Host == "" && !strings.EqualFold(scheme, "file") {
+			return "", fmt.Errorf("%w: %q has no host", ErrInvalidRepository, input)
+		}
+		if strings.Trim(u.Path, "/") == "" {
+			return "", fmt.Errorf("%w: %q has no repository path", ErrInvalidRepository, input)
+		}
+		return s, nil
+	}
+
+	// scp-like syntax: [user@]host:path
+	host, p, ok := strings.Cut(s, ":")
+	if !ok || host == "" || strings.ContainsAny(host, "/\\") || strings.Trim(p, "/") == "" {
+		return "", fmt.Errorf("%w: %q, expected a URL such as https://host/owner/repo.git or git@host:owner/repo.git", ErrInvalidRepository, input)
+	}
+	return s, nil
+}
+
+// RedactGitURL returns the URL of a git remote for display, with the userinfo of http, https
+// and git URLs replaced, since it may hold a password or token. The user of SSH remotes is kept.
+func RedactGitURL(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || strings.EqualFold(u.Scheme, "ssh") {
+		return remote
+	}
+	u.User = nil
+	scheme, rest, _ := strings.Cut(u.String(), "://")
+	return scheme + "://***@" + rest
+}
+
+// RepositoryInfo describes a remote repository verified before a store is created
+type RepositoryInfo struct {
+	FullName      string
+	DefaultBranch string
+	SizeKB        int
+	Private       bool
+}
+
+// StoreStats summarizes the documents held by a store
+type StoreStats struct {
+	DocumentCount     int
+	EmbeddedCount     int
+	LastModifiedAt    *time.Time
+	LastDocumentSaved *time.Time
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+type SyncEventType string
+
+const (
+	// SyncEventStarted is emitted once the entries of the source are known
+	SyncEventStarted SyncEventType = "started"
+	// SyncEventSaved is emitted for each new or changed entry that was saved
+	SyncEventSaved SyncEventType = "saved"
+	// SyncEventMoved is emitted for each entry that was renamed in the source and kept its embedding
+	SyncEventMoved SyncEventType = "moved"
+	// SyncEventUnchanged is emitted for each entry whose content did not change
+	SyncEventUnchanged SyncEventType = "unchanged"
+	// SyncEventFailed is emitted for each entry that could not be fetched, embedded or saved
+	SyncEventFailed SyncEventType = "failed"
+	// SyncEventCompleted is emitted once at the end of a sync
+	SyncEventCompleted SyncEventType = "completed"
+)
+
+// SyncEvent reports the progress of a document or memory sync
+type SyncEvent struct {
+	Type  SyncEventType
+	Path  string
+	From  string // previous path for moved events
+	Stage string // fetch, decode, parse, embed or save for failed events
+	Error error
+	Total int // number of entries for started events
+	Time  time.Time
+}
+
+// SyncEventHandler receives sync progress events
+type SyncEventHandler func(event SyncEvent)
+
+// SyncResult summarizes a document or memory sync
+type SyncResult struct {
+	StoreId      StoreId // zero for memory syncs
+	Total        int
+	Saved        int
+	MetadataOnly int // saved documents or memories whose body did not change, so that they kept their embedding
+	Moved        int
+	Unchanged    int
+	Failed       int
+	// Undecodable lists the failed paths of text files whose encoding could not be detected
+	Undecodable []string
+	Usage       EmbeddingUsage // embedding requests and tokens billed during the sync
+	StartedAt   time.Time
+	FinishedAt  time.Time
+}
+
+// StoredSHAs maps the paths of the entries already stored to the SHAs of their contents
+type StoredSHAs map[string]string
+
+// Unchanged reports whether the entry at path is stored with the same SHA
+func (s StoredSHAs) Unchanged(path, sha string) bool {
+	stored, ok := s[path]
+	return ok && stored == sha
+}
+
+// TakeRenamed returns the stored path of an entry that has been renamed to path: an entry with
+// the same SHA whose path no longer exists in the source. The returned path is removed from s
+// so that it is not taken twice.
+func (s StoredSHAs) TakeRenamed(path, sha string, present map[string]bool) (string, bool) {
+	if _, ok := s[path]; ok {
+		return "", false
+	}
+
+	var candidates []string
+	for storedPath, storedSHA := range s {
+		if storedSHA == sha && !present[storedPath] {
+			candidates = append(candidates, storedPath)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.Strings(candidates)
+	from := candidates[0]
+	delete(s, from)
+	return from, true
+}
@@ -0,0 +1,60 @@
+package model
+
+import "testing"
+
+func TestStoredSHAsTakeRenamed(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   StoredSHAs
+		present  map[string]bool
+		path     string
+		sha      string
+		wantFrom string
+		wantOK   bool
+	}{
+		{
+			name:     "renamed",
+			stored:   StoredSHAs{"old.md": "abc"},
+			present:  map[string]bool{"new.md": true},
+			path:     "new.md",
+			sha:      "abc",
+			wantFrom: "old.md",
+			wantOK:   true,
+		},
+		{
+			name:    "copied: the old path still exists",
+			stored:  StoredSHAs{"old.md": "abc"},
+			present: map[string]bool{"old.md": true, "new.md": true},
+			path:    "new.md",
+			sha:     "abc",
+		},
+		{
+			name:    "different content",
+			stored:  StoredSHAs{"old.md": "abc"},
+			present: map[string]bool{"new.md": true},
+			path:    "new.md",
+			sha:     "def",
+		},
+		{
+			name:    "path is already stored",
+			stored:  StoredSHAs{"old.md": "abc", "new.md": "def"},
+			present: map[string]bool{"new.md": true},
+			path:    "new.md",
+			sha:     "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, ok := tt.stored.TakeRenamed(tt.path, tt.sha, tt.present)
+			if from != tt.wantFrom || ok != tt.wantOK {
+				t.Errorf("got (%q, %v), want (%q, %v)", from, ok, tt.wantFrom, tt.wantOK)
+			}
+			if ok {
+				if _, still := tt.stored[from]; still {
+					t.Errorf("%q was not removed from the stored SHAs", from)
+				}
+			}
+		})
+	}
+}
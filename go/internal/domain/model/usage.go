@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+const (
+	UsageSourceDocument = "document"
+	UsageSourceMemory   = "memory"
+)
+
+// DefaultEmbeddingPrices are the OpenAI list prices in USD per million tokens
+var DefaultEmbeddingPrices = map[string]float64{
+	"text-embedding-3-small": 0.02,
+	"text-embedding-3-large": 0.13,
+	"text-embedding-ada-002": 0.10,
+}
+
+// EmbeddingResult is an embedding together with the tokens billed for it
+type EmbeddingResult struct {
+	Vector []float64
+	// Tokens is the number of tokens billed by the provider
+	Tokens int
+	// InputTokens is the number of tokens of the whole text, counted locally
+	InputTokens int
+	// Cached is true when the embedding was served from the cache without calling the provider
+	Cached bool
+	// Truncated is true when only the beginning of the text was embedded
+	Truncated bool
+	// Chunks is the number of chunks that were combined, or zero when the text was embedded at once
+	Chunks int
+}
+
+// EmbeddingUsage accumulates the embedding requests of a sync run
+type EmbeddingUsage struct {
+	Model    string
+	Requests int
+	Tokens   int
+}
+
+// Add counts an embedding; cached embeddings are not billed and are not counted
+func (u *EmbeddingUsage) Add(result *EmbeddingResult) {
+	if result.Cached {
+		return
+	}
+	if result.Chunks > 0 {
+		u.Requests += result.Chunks
+	} else {
+		u.Requests++
+	}
+	u.Tokens += result.Tokens
+}
+
+// UsageRecord is the embedding usage of one sync run as persisted in the database
+type UsageRecord struct {
+	Source     string  // UsageSourceDocument or UsageSourceMemory
+	StoreId    StoreId // zero for memory syncs
+	Model      string
+	Requests   int
+	Tokens     int
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// UsageSummary aggregates the usage records of a source, store and model
+type UsageSummary struct {
+	Source   string
+	StoreId  StoreId
+	Model    string
+	Runs     int
+	Requests int
+	Tokens   int
+}
+
+// EstimateCost returns the cost in USD of tokens at a price per million tokens
+func EstimateCost(tokens int, pricePerMillion float64) float64 {
+	return float64(tokens) / 1e6 * pricePerMillion
+}
@@ -1,5 +1,14 @@
 package embedding
 
+import (
+	"context"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
 type EmbeddingProvider interface {
-	Embed(text string) ([]float64, error)
+	// Embed creates the embedding of text and reports the tokens billed for it
+	Embed(ctx context.Context, text string) (*model.EmbeddingResult, error)
+	// Model returns the name of the model used to create embeddings
+	Model() string
 }
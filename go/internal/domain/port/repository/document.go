@@ -1,9 +1,45 @@
 package repository
 
-import "github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// ErrDocumentNotFound is returned when a document is not found in the repository
+var ErrDocumentNotFound = errors.New("document not found")
+
+// DocumentFilter narrows down the documents returned by ListDocuments.
+// Zero values are ignored.
+type DocumentFilter struct {
+	StoreId       model.StoreId
+	Tag           string
+	PathPrefix    string
+	ModifiedSince time.Time
+	Author        string // Part of the author of the last commit, matched case-insensitively
+	Limit         int
+}
 
 type DocumentRepository interface {
-	SaveDocument(document *model.Document) error
-	// FindExistingSHAs returns the IDs of documents that are unchanged
-	FindExistingSHAs(documents []*model.Document) ([]string, error)
+	SaveDocument(ctx context.Context, document *model.Document) error
+	// SaveDocuments saves or updates the documents in one transaction; paths must be unique within the batch.
+	// Documents without an embedding keep their stored embedding.
+	SaveDocuments(ctx context.Context, documents []*model.Document) error
+	// FindStoredSHAs returns the SHA of every document of the store keyed by path
+	FindStoredSHAs(ctx context.Context, storeId model.StoreId) (model.StoredSHAs, error)
+	// FindStoredBodySHAs returns the SHA of the body of every document of the store keyed by path,
+	// leaving out documents without an embedding of embeddingModel, whose body must be embedded again
+	FindStoredBodySHAs(ctx context.Context, storeId model.StoreId, embeddingModel string) (model.StoredSHAs, error)
+	// FindPathsWithoutCommit returns the paths of the documents of the store whose last commit is
+	// unknown, e.g. because the history lookup of an earlier sync failed
+	FindPathsWithoutCommit(ctx context.Context, storeId model.StoreId) (map[string]bool, error)
+	// MoveDocument renames the document at from to the path of document, keeping its content and
+	// embedding and taking its modification time and last commit
+	MoveDocument(ctx context.Context, from string, document *model.Document) error
+	// ListDocuments returns documents matching the filter without their content and embedding
+	ListDocuments(ctx context.Context, filter DocumentFilter) ([]*model.Document, error)
+	// GetDocument returns a single document including its content
+	GetDocument(ctx context.Context, storeId model.StoreId, path string) (*model.Document, error)
 }
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// ErrDocumentVersionNotFound is returned when a previous version of a document is not kept
+var ErrDocumentVersionNotFound = errors.New("document version not found")
+
+// DocumentVersionRepository reads the previous versions that SaveDocuments keeps when the
+// content of a document changes
+type DocumentVersionRepository interface {
+	// ListVersions returns the previous versions of the document at path, oldest first, without content.
+	// It returns ErrDocumentNotFound if there is no such document.
+	ListVersions(ctx context.Context, storeId model.StoreId, path string) ([]*model.DocumentVersion, error)
+	// GetVersion returns a previous version of the document at path including its content
+	GetVersion(ctx context.Context, storeId model.StoreId, path string, number int) (*model.DocumentVersion, error)
+	// Prune removes the versions replaced longer than maxAge ago and the oldest versions above
+	// maxVersions per document; zero limits are ignored. It returns the number of removed versions.
+	Prune(ctx context.Context, maxVersions int, maxAge time.Duration) (int, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// EmbeddingCacheRepository stores embeddings keyed by the SHA of the embedded text and the model
+type EmbeddingCacheRepository interface {
+	// GetEmbedding returns the cached embedding and records the hit; it returns nil when there is none
+	GetEmbedding(ctx context.Context, sha, model string) ([]float64, error)
+	PutEmbedding(ctx context.Context, sha, model string, embedding []float64) error
+	// Evict removes the entries unused for longer than maxAge, then the least recently used
+	// entries above maxEntries. A zero limit is not applied. It returns the number of removed entries.
+	Evict(ctx context.Context, maxEntries int, maxAge time.Duration) (int, error)
+	Stats(ctx context.Context) (*model.EmbeddingCacheStats, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// IndexRepository manages the vector indexes on the embedding columns
+type IndexRepository interface {
+	// GetVectorIndex returns the state of the vector index of the table; Name is empty when there is none
+	GetVectorIndex(ctx context.Context, table string) (*model.VectorIndex, error)
+	// CreateVectorIndex builds the index described by spec and replaces the existing index of the table
+	CreateVectorIndex(ctx context.Context, spec model.VectorIndexSpec) error
+	// RebuildVectorIndex rebuilds the existing index of the table with its current parameters
+	RebuildVectorIndex(ctx context.Context, table string) error
+}
+
+// ErrVectorIndexNotFound is returned when a table has no vector index to rebuild
+var ErrVectorIndexNotFound = errors.New("vector index not found")
@@ -1,9 +1,50 @@
 package repository
 
-import "github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// ErrMemoryNotFound is returned when a memory is not found in the repository
+var ErrMemoryNotFound = errors.New("memory not found")
+
+// MemoryFilter narrows down the memories returned by ListMemories.
+// Zero values are ignored; archived memories are only returned with IncludeArchived.
+type MemoryFilter struct {
+	Tag             string
+	PathPrefix      string
+	ModifiedSince   time.Time
+	Type            model.MemoryType
+	MinImportance   int
+	Source          string
+	IncludeArchived bool
+	Limit           int
+}
 
 type MemoryRepository interface {
-	SaveMemory(memory *model.Memory) error
-	ListMemories() ([]*model.Memory, error)
-	FindExistingSHAs(memories []*model.Memory) ([]string, error)
+	SaveMemory(ctx context.Context, memory *model.Memory) error
+	// SaveMemories saves or updates the memories in one transaction; paths must be unique within the batch
+	SaveMemories(ctx context.Context, memories []*model.Memory) error
+	ListMemories(ctx context.Context, filter MemoryFilter) ([]*model.Memory, error)
+	// GetMemory returns the memory at path; it returns ErrMemoryNotFound if there is none
+	GetMemory(ctx context.Context, path string) (*model.Memory, error)
+	// DeleteMemory deletes the memory at path; it returns ErrMemoryNotFound if there is none
+	DeleteMemory(ctx context.Context, path string) error
+	// FindStoredSHAs returns the SHA of every memory keyed by path
+	FindStoredSHAs(ctx context.Context) (model.StoredSHAs, error)
+	// FindStoredBodySHAs returns the SHA of the body of every memory keyed by path, leaving out
+	// memories without an embedding of embeddingModel, whose body must be embedded again
+	FindStoredBodySHAs(ctx context.Context, embeddingModel string) (model.StoredSHAs, error)
+	// MoveMemory renames a memory, keeping its content and embedding
+	MoveMemory(ctx context.Context, from, to string, modifiedAt time.Time) error
+	// FindSimilarMemories returns the pairs of active memories embedded with the same model
+	// whose cosine similarity is at least minSimilarity
+	FindSimilarMemories(ctx context.Context, minSimilarity float64) ([]model.MemorySimilarity, error)
+	// ArchiveExpired archives the active memories that expired at or before now and returns their paths
+	ArchiveExpired(ctx context.Context, now time.Time) ([]string, error)
+	// ArchiveSuperseded archives the memories superseded by an active memory and returns their paths
+	ArchiveSuperseded(ctx context.Context, now time.Time) ([]string, error)
 }
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 )
@@ -10,6 +12,14 @@ import (
 var ErrStoreNotFound = errors.New("store not found")
 
 type StoreRepository interface {
-	GetStore(storeId model.StoreId) (model.DocumentStore, error)
-	CreateStore(store model.DocumentStore) (model.DocumentStore, error)
+	GetStore(ctx context.Context, storeId model.StoreId) (model.DocumentStore, error)
+	ListStores(ctx context.Context) ([]model.DocumentStore, error)
+	CreateStore(ctx context.Context, store model.DocumentStore) (model.DocumentStore, error)
+	UpdateStore(ctx context.Context, store model.DocumentStore) error
+	// DeleteStore removes the store together with all of its documents
+	DeleteStore(ctx context.Context, storeId model.StoreId) error
+	GetStoreStats(ctx context.Context, storeId model.StoreId) (*model.StoreStats, error)
+	// MarkSynced records a successful sync; revision is the synced revision of the source, or empty
+	// when the next sync has to read every document
+	MarkSynced(ctx context.Context, storeId model.StoreId, syncedAt time.Time, revision string) error
 }
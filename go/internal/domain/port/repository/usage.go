@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// UsageRepository persists the embedding usage of sync runs
+type UsageRepository interface {
+	RecordUsage(ctx context.Context, record *model.UsageRecord) error
+	// SummarizeUsage aggregates the records of runs started at or after since by source, store and model
+	SummarizeUsage(ctx context.Context, since time.Time) ([]model.UsageSummary, error)
+}
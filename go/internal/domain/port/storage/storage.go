@@ -1,16 +1,50 @@
 package storage
 
 import (
+	"context"
+	"errors"
+
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 )
 
+var (
+	// ErrMemoryNotFound is returned when the file of a memory does not exist in the storage
+	ErrMemoryNotFound = errors.New("memory file not found")
+	// ErrUndecodable is returned for text files in an encoding that cannot be detected
+	ErrUndecodable = errors.New("text encoding not detected")
+)
+
 type Storage interface {
-	SaveDocument(document *model.Document) error
-	SaveMemory(memory *model.Memory) error
-	FetchDocument(storeId model.StoreId, path string) (*model.Document, error)
-	FetchMemory(path string) (*model.Memory, error)
-	GetDocumentEntries() ([]model.DocumentEntry, error)
-	GetMemoryEntries() ([]model.MemoryEntry, error)
+	SaveDocument(ctx context.Context, document *model.Document) error
+	// SaveMemory creates or updates the file of the memory and sets its SHA and ModifiedAt
+	SaveMemory(ctx context.Context, memory *model.Memory) error
+	// DeleteMemory deletes the file of the memory; it returns ErrMemoryNotFound if there is none
+	DeleteMemory(ctx context.Context, path string) error
+	FetchDocument(ctx context.Context, storeId model.StoreId, path string) (*model.Document, error)
+	// FetchMemory reads the file of the memory; it returns ErrMemoryNotFound if there is none
+	FetchMemory(ctx context.Context, path string) (*model.Memory, error)
+	GetDocumentEntries(ctx context.Context) ([]model.DocumentEntry, error)
+	GetMemoryEntries(ctx context.Context) ([]model.MemoryEntry, error)
+	// Close releases the local resources of the storage, such as downloaded files
+	Close() error
+}
+
+// RevisionStorage is a storage that reads the documents of one revision of a version control system
+type RevisionStorage interface {
+	Storage
+	// Revision returns the revision read by GetDocumentEntries; it is empty before the entries are read
+	Revision() string
+	// ChangedPaths returns the paths added, modified or deleted between since and Revision
+	ChangedPaths(ctx context.Context, since string) ([]string, error)
+}
+
+// HistoryStorage is a storage that knows the commit history of its files
+type HistoryStorage interface {
+	Storage
+	// LastCommits returns the last commit that changed each file at paths in the revision read
+	// by GetDocumentEntries, keyed by path. Paths without a known commit are left out. An error
+	// stops the lookup, and the commits found until then are returned with it.
+	LastCommits(ctx context.Context, paths []string) (map[string]model.Commit, error)
 }
 
 type StorageFactory interface {
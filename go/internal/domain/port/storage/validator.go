@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+var (
+	// ErrRepositoryNotFound is returned when a repository does not exist or is not readable
+	ErrRepositoryNotFound = errors.New("repository not found or not accessible")
+	// ErrRefNotFound is returned when the configured ref does not exist in the repository
+	ErrRefNotFound = errors.New("ref not found")
+)
+
+// StoreValidator checks that the source of a store exists and is readable
+// with the configured credentials before the store is saved.
+type StoreValidator interface {
+	Validate(ctx context.Context, store model.DocumentStore) (*model.RepositoryInfo, error)
+}
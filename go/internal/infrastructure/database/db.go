@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bonyuta0204/personal-agent/go/config"
@@ -9,7 +10,7 @@ import (
 )
 
 // NewDBConnection creates a new database connection
-func NewDBConnection(cfg *config.DatabaseConfig) (*sqlx.DB, error) {
+func NewDBConnection(ctx context.Context, cfg *config.DatabaseConfig) (*sqlx.DB, error) {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Host,
 		cfg.Port,
@@ -18,13 +19,13 @@ func NewDBConnection(cfg *config.DatabaseConfig) (*sqlx.DB, error) {
 		cfg.Name,
 	)
 
-	db, err := sqlx.Connect("postgres", dsn)
+	db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Test the connection
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
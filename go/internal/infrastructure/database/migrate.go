@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/config"
+	"github.com/bonyuta0204/personal-agent/go/migrations"
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+)
+
+// ErrSchemaOutdated is returned when the database has migrations that have not been applied
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// MigrationResult describes a migration that was applied or rolled back
+type MigrationResult struct {
+	Version   int64
+	Name      string
+	Direction string
+	Duration  time.Duration
+}
+
+// Migrator applies the embedded schema migrations
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// NewMigrator creates a migrator that records versions in the configured migrations table
+func NewMigrator(db *sqlx.DB, cfg *config.DatabaseConfig) (*Migrator, error) {
+	provider, err := goose.NewProvider(goose.DialectPostgres, db.DB, migrations.FS,
+		goose.WithTableName(cfg.MigrationsTable),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) ([]MigrationResult, error) {
+	results, err := m.provider.Up(ctx)
+	converted := convertResults(results)
+	if err != nil {
+		return converted, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return converted, nil
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) (*MigrationResult, error) {
+	result, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back migration: %w", err)
+	}
+	converted := convertResults([]*goose.MigrationResult{result})
+	return &converted[0], nil
+}
+
+// Status returns the state of every embedded migration ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration status: %w", err)
+	}
+
+	result := make([]MigrationStatus, 0, len(statuses))
+	for _, s := range statuses {
+		result = append(result, MigrationStatus{
+			Version:   s.Source.Version,
+			Name:      migrationName(s.Source.Path),
+			Applied:   s.State == goose.StateApplied,
+			AppliedAt: s.AppliedAt,
+		})
+	}
+	return result, nil
+}
+
+// CheckSchema returns ErrSchemaOutdated if the database is behind the embedded migrations
+func (m *Migrator) CheckSchema(ctx context.Context) error {
+	current, target, err := m.provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check schema version: %w", err)
+	}
+	if current < target {
+		return fmt.Errorf("%w (version %d, expected %d): run \"personal-agent migrate up\"", ErrSchemaOutdated, current, target)
+	}
+	return nil
+}
+
+func convertResults(results []*goose.MigrationResult) []MigrationResult {
+	converted := make([]MigrationResult, 0, len(results))
+	for _, r := range results {
+		if r == nil || r.Source == nil {
+			continue
+		}
+		converted = append(converted, MigrationResult{
+			Version:   r.Source.Version,
+			Name:      migrationName(r.Source.Path),
+			Direction: r.Direction,
+			Duration:  r.Duration,
+		})
+	}
+	return converted
+}
+
+// migrationName returns the file name of a SQL or Go migration without its extension
+func migrationName(p string) string {
+	name := path.Base(p)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
@@ -0,0 +1,71 @@
+package embedding
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/util"
+)
+
+// CachedProvider looks up embeddings in the cache by the SHA of the text and the model
+// before calling the wrapped provider. Cache errors are logged and do not fail embedding.
+type CachedProvider struct {
+	provider embedding.EmbeddingProvider
+	cache    repository.EmbeddingCacheRepository
+	hits     atomic.Int64
+	misses   atomic.Int64
+}
+
+// NewCachedProvider wraps provider with the embedding cache
+func NewCachedProvider(provider embedding.EmbeddingProvider, cache repository.EmbeddingCacheRepository) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		cache:    cache,
+	}
+}
+
+// Embed implements the embedding.EmbeddingProvider interface
+func (p *CachedProvider) Embed(ctx context.Context, text string) (*model.EmbeddingResult, error) {
+	sha := util.CalculateSHA256(text)
+	embeddingModel := p.provider.Model()
+
+	cached, err := p.cache.GetEmbedding(ctx, sha, embeddingModel)
+	if err != nil {
+		log.Printf("embedding cache lookup failed: %v", err)
+	}
+	if cached != nil {
+		p.hits.Add(1)
+		return &model.EmbeddingResult{Vector: cached, Cached: true}, nil
+	}
+
+	result, err := p.provider.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	p.misses.Add(1)
+
+	if err := p.cache.PutEmbedding(ctx, sha, embeddingModel, result.Vector); err != nil {
+		log.Printf("embedding cache store failed: %v", err)
+	}
+	return result, nil
+}
+
+// Model implements the embedding.EmbeddingProvider interface
+func (p *CachedProvider) Model() string {
+	return p.provider.Model()
+}
+
+// Usage returns the number of cache hits and misses since the provider was created
+func (p *CachedProvider) Usage() model.EmbeddingCacheUsage {
+	return model.EmbeddingCacheUsage{
+		Hits:   p.hits.Load(),
+		Misses: p.misses.Load(),
+	}
+}
+
+// Ensure CachedProvider implements the EmbeddingProvider interface
+var _ embedding.EmbeddingProvider = (*CachedProvider)(nil)
@@ -0,0 +1,93 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// fakeProvider counts the calls to Embed
+type fakeProvider struct {
+	calls int
+	err   error
+}
+
+func (p *fakeProvider) Embed(_ context.Context, text string) (*model.EmbeddingResult, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &model.EmbeddingResult{Vector: []float64{float64(len(text))}, Tokens: len(text)}, nil
+}
+
+func (p *fakeProvider) Model() string { return "test-model" }
+
+// fakeCache keeps embeddings in memory
+type fakeCache struct {
+	entries map[string][]float64
+	err     error
+}
+
+func (c *fakeCache) GetEmbedding(_ context.Context, sha, embeddingModel string) ([]float64, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.entries[sha+"/"+embeddingModel], nil
+}
+
+func (c *fakeCache) PutEmbedding(_ context.Context, sha, embeddingModel string, embedding []float64) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.entries[sha+"/"+embeddingModel] = embedding
+	return nil
+}
+
+func (c *fakeCache) Evict(_ context.Context, maxEntries int, maxAge time.Duration) (int, error) {
+	return 0, nil
+}
+
+func (c *fakeCache) Stats(_ context.Context) (*model.EmbeddingCacheStats, error) {
+	return &model.EmbeddingCacheStats{}, nil
+}
+
+func TestCachedProvider(t *testing.T) {
+	provider := &fakeProvider{}
+	cached := NewCachedProvider(provider, &fakeCache{entries: map[string][]float64{}})
+
+	var usage model.EmbeddingUsage
+	for _, text := range []string{"readme", "notes", "readme", "readme"} {
+		result, err := cached.Embed(context.Background(), text)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		usage.Add(result)
+	}
+
+	if provider.calls != 2 {
+		t.Errorf("got %d provider calls, want 2", provider.calls)
+	}
+	if cacheUsage := cached.Usage(); cacheUsage.Hits != 2 || cacheUsage.Misses != 2 {
+		t.Errorf("got cache usage %+v, want 2 hits and 2 misses", cacheUsage)
+	}
+	// Only the provider calls are billed
+	if usage.Requests != 2 || usage.Tokens != len("readme")+len("notes") {
+		t.Errorf("got usage %+v, want 2 billed requests", usage)
+	}
+}
+
+func TestCachedProvider_CacheErrorFallsBack(t *testing.T) {
+	provider := &fakeProvider{}
+	cached := NewCachedProvider(provider, &fakeCache{err: errors.New("connection refused")})
+
+	result, err := cached.Embed(context.Background(), "readme")
+	if err != nil || len(result.Vector) != 1 {
+		t.Fatalf("got (%v, %v), want the provider's embedding", result, err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("got %d provider calls, want 1", provider.calls)
+	}
+}
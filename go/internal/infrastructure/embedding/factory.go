@@ -1,11 +1,33 @@
 package embedding
 
 import (
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/config"
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
 	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/embedding/openai"
 )
 
+// NewProvider creates the embedding provider selected in the configuration
+func NewProvider(cfg *config.EmbeddingConfig) (embedding.EmbeddingProvider, error) {
+	switch cfg.Provider {
+	case config.EmbeddingProviderOpenAI, "":
+		return NewOpenAIProvider(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider: %s", cfg.Provider)
+	}
+}
+
 // NewOpenAIProvider creates a new OpenAI embedding provider
-func NewOpenAIProvider() (embedding.EmbeddingProvider, error) {
-	return openai.NewProvider()
+func NewOpenAIProvider(cfg *config.EmbeddingConfig) (embedding.EmbeddingProvider, error) {
+	return openai.NewProvider(cfg.APIKey, cfg.Model)
+}
+
+// WithTokenLimit wraps provider with the token limit and overflow policy of the configuration
+func WithTokenLimit(provider embedding.EmbeddingProvider, cfg *config.EmbeddingConfig) (*LimitedProvider, error) {
+	tokenizer, err := NewTokenizer(cfg.Encoding, provider.Model())
+	if err != nil {
+		return nil, err
+	}
+	return NewLimitedProvider(provider, tokenizer, cfg.MaxTokens, cfg.Overflow)
 }
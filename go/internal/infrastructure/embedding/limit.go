@@ -0,0 +1,123 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/bonyuta0204/personal-agent/go/config"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
+)
+
+// LimitedProvider counts the tokens of each text and applies the overflow policy to texts
+// above the input limit of the model, so that they do not fail at the API
+type LimitedProvider struct {
+	provider  embedding.EmbeddingProvider
+	tokenizer *Tokenizer
+	maxTokens int
+	overflow  string
+}
+
+// NewLimitedProvider wraps provider with a token limit and an overflow policy: config.OverflowReject
+// fails long texts, config.OverflowTruncate embeds their beginning and config.OverflowChunk embeds
+// them in chunks combined into one embedding
+func NewLimitedProvider(provider embedding.EmbeddingProvider, tokenizer *Tokenizer, maxTokens int, overflow string) (*LimitedProvider, error) {
+	if maxTokens <= 0 {
+		return nil, fmt.Errorf("invalid token limit: %d", maxTokens)
+	}
+	switch overflow {
+	case config.OverflowReject, config.OverflowTruncate, config.OverflowChunk:
+	default:
+		return nil, fmt.Errorf("invalid overflow policy: %q", overflow)
+	}
+	return &LimitedProvider{
+		provider:  provider,
+		tokenizer: tokenizer,
+		maxTokens: maxTokens,
+		overflow:  overflow,
+	}, nil
+}
+
+// Embed implements the embedding.EmbeddingProvider interface
+func (p *LimitedProvider) Embed(ctx context.Context, text string) (*model.EmbeddingResult, error) {
+	count := p.tokenizer.Count(text)
+	if count <= p.maxTokens {
+		return p.embed(ctx, text, count)
+	}
+
+	switch p.overflow {
+	case config.OverflowTruncate:
+		result, err := p.embed(ctx, p.tokenizer.Truncate(text, p.maxTokens), count)
+		if err != nil {
+			return nil, err
+		}
+		result.Truncated = true
+		return result, nil
+	case config.OverflowChunk:
+		return p.embedChunks(ctx, p.tokenizer.Split(text, p.maxTokens), count)
+	default:
+		return nil, fmt.Errorf("text too long for embedding: %d tokens (max %d)", count, p.maxTokens)
+	}
+}
+
+// embed calls the provider and records the token count of the original text
+func (p *LimitedProvider) embed(ctx context.Context, text string, inputTokens int) (*model.EmbeddingResult, error) {
+	result, err := p.provider.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	result.InputTokens = inputTokens
+	return result, nil
+}
+
+// embedChunks embeds every chunk and combines them into their token-weighted mean,
+// normalized to unit length like the embeddings returned by OpenAI
+func (p *LimitedProvider) embedChunks(ctx context.Context, chunks []string, inputTokens int) (*model.EmbeddingResult, error) {
+	combined := &model.EmbeddingResult{InputTokens: inputTokens, Cached: true, Chunks: len(chunks)}
+	var sum []float64
+	for i, chunk := range chunks {
+		// Cached chunks do not reach the API, so cancellation is checked between chunks
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := p.provider.Embed(ctx, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		if sum == nil {
+			sum = make([]float64, len(result.Vector))
+		}
+		if len(result.Vector) != len(sum) {
+			return nil, fmt.Errorf("chunk %d has %d dimensions, want %d", i+1, len(result.Vector), len(sum))
+		}
+
+		weight := float64(p.tokenizer.Count(chunk))
+		for j, v := range result.Vector {
+			sum[j] += v * weight
+		}
+		combined.Tokens += result.Tokens
+		combined.Cached = combined.Cached && result.Cached
+	}
+
+	var norm float64
+	for _, v := range sum {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for j := range sum {
+			sum[j] /= norm
+		}
+	}
+	combined.Vector = sum
+	return combined, nil
+}
+
+// Model implements the embedding.EmbeddingProvider interface
+func (p *LimitedProvider) Model() string {
+	return p.provider.Model()
+}
+
+// Ensure LimitedProvider implements the EmbeddingProvider interface
+var _ embedding.EmbeddingProvider = (*LimitedProvider)(nil)
@@ -0,0 +1,103 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bonyuta0204/personal-agent/go/config"
+)
+
+func newTestTokenizer(t *testing.T) *Tokenizer {
+	tokenizer, err := NewTokenizer("", "text-embedding-3-small")
+	if err != nil {
+		t.Fatalf("failed to create tokenizer: %v", err)
+	}
+	return tokenizer
+}
+
+func TestTokenizer(t *testing.T) {
+	tokenizer := newTestTokenizer(t)
+
+	if got := tokenizer.Count("hello world"); got != 2 {
+		t.Errorf("got %d tokens for %q, want 2", got, "hello world")
+	}
+
+	// Japanese characters often take more than one token each
+	text := strings.Repeat("日本語の文章を埋め込みます。", 50)
+	truncated := tokenizer.Truncate(text, 101)
+	if !utf8.ValidString(truncated) || !strings.HasPrefix(text, truncated) {
+		t.Errorf("truncated text is not a valid prefix: %q", truncated)
+	}
+	if n := tokenizer.Count(truncated); n > 101 || n < 95 {
+		t.Errorf("got %d tokens after truncation, want at most 101", n)
+	}
+
+	chunks := tokenizer.Split(text, 101)
+	if strings.Join(chunks, "") != text {
+		t.Error("chunks do not add up to the text")
+	}
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk) || tokenizer.Count(chunk) > 101 {
+			t.Errorf("chunk %d is invalid or too long", i)
+		}
+	}
+}
+
+func TestLimitedProvider(t *testing.T) {
+	tokenizer := newTestTokenizer(t)
+	long := strings.TrimSpace(strings.Repeat("word ", 30)) // 30 tokens
+
+	tests := []struct {
+		overflow  string
+		text      string
+		wantErr   bool
+		wantCalls int
+	}{
+		{config.OverflowReject, "short text", false, 1},
+		{config.OverflowReject, long, true, 0},
+		{config.OverflowTruncate, long, false, 1},
+		{config.OverflowChunk, long, false, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.overflow, func(t *testing.T) {
+			provider := &fakeProvider{}
+			limited, err := NewLimitedProvider(provider, tokenizer, 10, tt.overflow)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := limited.Embed(context.Background(), tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if provider.calls != tt.wantCalls {
+				t.Errorf("got %d provider calls, want %d", provider.calls, tt.wantCalls)
+			}
+			if err == nil && result.InputTokens != tokenizer.Count(tt.text) {
+				t.Errorf("got %d input tokens, want %d", result.InputTokens, tokenizer.Count(tt.text))
+			}
+		})
+	}
+}
+
+func TestLimitedProviderCancelled(t *testing.T) {
+	provider := &fakeProvider{}
+	limited, err := NewLimitedProvider(provider, newTestTokenizer(t), 10, config.OverflowChunk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = limited.Embed(ctx, strings.TrimSpace(strings.Repeat("word ", 30)))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if provider.calls != 0 {
+		t.Errorf("got %d provider calls after cancellation, want 0", provider.calls)
+	}
+}
@@ -3,9 +3,8 @@ package openai
 import (
 	"context"
 	"errors"
-	"fmt"
-	"os"
 
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
 	"github.com/sashabaranov/go-openai"
 )
@@ -16,36 +15,35 @@ type Provider struct {
 	model  openai.EmbeddingModel
 }
 
-// NewProvider creates a new OpenAI embedding provider
-func NewProvider() (*Provider, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
+// NewProvider creates a new OpenAI embedding provider for the given model.
+// An empty model selects text-embedding-ada-002.
+func NewProvider(apiKey string, model string) (*Provider, error) {
 	if apiKey == "" {
-		return nil, errors.New("OPENAI_API_KEY environment variable is not set")
+		return nil, errors.New("OpenAI API key is not set (embedding.api_key or OPENAI_API_KEY)")
+	}
+
+	embeddingModel := openai.AdaEmbeddingV2
+	if model != "" {
+		embeddingModel = openai.EmbeddingModel(model)
 	}
 
 	client := openai.NewClient(apiKey)
 	return &Provider{
 		client: client,
-		model:  openai.AdaEmbeddingV2,
+		model:  embeddingModel,
 	}, nil
 }
 
 // Embed implements the embedding.EmbeddingProvider interface
-// It creates an embedding for the given text using OpenAI's API
-func (p *Provider) Embed(text string) ([]float64, error) {
-	// OpenAI embedding API max: 300,000 tokens (see https://platform.openai.com/docs/guides/embeddings/what-are-embeddings)
-	// Roughly estimate: 1 token ≈ 4 chars (so 120,000 chars ≈ 300,000 tokens)
-	const maxChars = 120000
-	if len([]rune(text)) > maxChars {
-		return nil, fmt.Errorf("text too long for embedding: %d chars (max %d chars, ~300k tokens)", len([]rune(text)), maxChars)
-	}
-
+// It creates an embedding for the given text using OpenAI's API and reports the prompt tokens
+func (p *Provider) Embed(ctx context.Context, text string) (*model.EmbeddingResult, error) {
+	// The input limit of the model is enforced by embedding.LimitedProvider
 	req := openai.EmbeddingRequest{
 		Input: []string{text},
 		Model: p.model,
 	}
 
-	resp, err := p.client.CreateEmbeddings(context.Background(), req)
+	resp, err := p.client.CreateEmbeddings(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +57,15 @@ func (p *Provider) Embed(text string) ([]float64, error) {
 	for i, v := range resp.Data[0].Embedding {
 		embedding[i] = float64(v)
 	}
-	return embedding, nil
+	return &model.EmbeddingResult{
+		Vector: embedding,
+		Tokens: resp.Usage.PromptTokens,
+	}, nil
+}
+
+// Model implements the embedding.EmbeddingProvider interface
+func (p *Provider) Model() string {
+	return string(p.model)
 }
 
 // Ensure Provider implements the EmbeddingProvider interface
@@ -0,0 +1,90 @@
+package embedding
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+)
+
+func init() {
+	// Use the BPE ranks embedded in the binary instead of downloading them
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// Tokenizer counts and splits text with the BPE encoding used by OpenAI models
+type Tokenizer struct {
+	encoding *tiktoken.Tiktoken
+}
+
+// NewTokenizer creates a tokenizer for the named encoding. An empty encoding selects
+// the encoding of the model, falling back to cl100k_base for unknown models.
+func NewTokenizer(encoding, model string) (*Tokenizer, error) {
+	if encoding == "" {
+		if enc, err := tiktoken.EncodingForModel(model); err == nil {
+			return &Tokenizer{encoding: enc}, nil
+		}
+		encoding = EncodingCL100K
+	}
+
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer encoding %s: %w", encoding, err)
+	}
+	return &Tokenizer{encoding: enc}, nil
+}
+
+// Count returns the number of tokens in text
+func (t *Tokenizer) Count(text string) int {
+	return len(t.encoding.EncodeOrdinary(text))
+}
+
+// Truncate returns the longest prefix of text that has at most maxTokens tokens
+func (t *Tokenizer) Truncate(text string, maxTokens int) string {
+	tokens := t.encoding.EncodeOrdinary(text)
+	if len(tokens) <= maxTokens {
+		return text
+	}
+	prefix, _ := t.decodePrefix(tokens[:maxTokens])
+	return prefix
+}
+
+// Split divides text into chunks of at most maxTokens tokens
+func (t *Tokenizer) Split(text string, maxTokens int) []string {
+	tokens := t.encoding.EncodeOrdinary(text)
+
+	var chunks []string
+	for len(tokens) > 0 {
+		end := maxTokens
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunk, used := t.decodePrefix(tokens[:end])
+		if used == 0 {
+			// A single character spans more tokens than allowed; keep it whole
+			used = end
+			chunk = t.encoding.Decode(tokens[:end])
+		}
+		chunks = append(chunks, chunk)
+		tokens = tokens[used:]
+	}
+	return chunks
+}
+
+// decodePrefix decodes the longest prefix of tokens that forms valid UTF-8 and returns
+// the number of tokens used. A multi-byte character can span several tokens, for example
+// in Japanese text, so cutting at an arbitrary token could split it.
+func (t *Tokenizer) decodePrefix(tokens []int) (string, int) {
+	for n := len(tokens); n > 0 && n > len(tokens)-4; n-- {
+		if text := t.encoding.Decode(tokens[:n]); utf8.ValidString(text) {
+			return text, n
+		}
+	}
+	return "", 0
+}
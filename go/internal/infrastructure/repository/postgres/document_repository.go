@@ -2,17 +2,15 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
-	"strings"
 	"time"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 	repo "github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
 	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
 )
 
 // Ensure documentRepository implements repo.DocumentRepository
@@ -27,14 +25,23 @@ func NewDocumentRepository(db *sqlx.DB) repo.DocumentRepository {
 	return &documentRepository{db: db}
 }
 
+// documentColumns is the number of columns written per document by upsertDocuments
+const documentColumns = 15
+
 // SaveDocument saves or updates a document in the database
-func (r *documentRepository) SaveDocument(document *model.Document) error {
+func (r *documentRepository) SaveDocument(ctx context.Context, document *model.Document) error {
 	if document == nil {
 		return errors.New("document cannot be nil")
 	}
+	return r.SaveDocuments(ctx, []*model.Document{document})
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// SaveDocuments upserts the documents in a single transaction and sets their timestamps.
+// The stored content of a document whose SHA changes is kept as its previous version.
+func (r *documentRepository) SaveDocuments(ctx context.Context, documents []*model.Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -42,152 +49,310 @@ func (r *documentRepository) SaveDocument(document *model.Document) error {
 	}
 	defer tx.Rollback()
 
-	// Convert tags to JSONB (always as array, never null)
-	tags := document.Tags
-	if tags == nil {
-		tags = []string{}
-	}
-	tagsJSON, err := json.Marshal(tags)
-	if err != nil {
-		return err
-	}
-
-	// Convert embedding to PostgreSQL vector format
-	const expectedDim = 1536
-	var embeddingStr string = ""
-	if len(document.Embedding) > 0 {
-		if len(document.Embedding) != expectedDim {
-			return fmt.Errorf("invalid embedding dimension: got %d, want %d", len(document.Embedding), expectedDim)
+	for start := 0; start < len(documents); start += maxUpsertRows {
+		end := min(start+maxUpsertRows, len(documents))
+		if err := recordVersions(ctx, tx, documents[start:end]); err != nil {
+			return err
 		}
-		for i, v := range document.Embedding {
-			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
-				return fmt.Errorf("invalid embedding value at position %d: %v", i, v)
-			}
+		if err := upsertDocuments(ctx, tx, documents[start:end]); err != nil {
+			return err
 		}
-		// Convert to JSON array format
-		parts := make([]string, len(document.Embedding))
-		for i, v := range document.Embedding {
-			parts[i] = fmt.Sprintf("%f", v)
-		}
-		embeddingStr = "[" + strings.Join(parts, ",") + "]"
 	}
 
-	// Check if document exists
-	var exists bool
-	err = tx.GetContext(ctx, &exists,
-		`SELECT EXISTS(SELECT 1 FROM documents WHERE store_id = $1 AND path = $2)`,
-		document.StoreId, document.Path,
-	)
-	if err != nil {
-		return err
-	}
+	return tx.Commit()
+}
 
-	if exists {
-		// Update existing document
-		_, err = tx.ExecContext(ctx, `
-			UPDATE documents 
-			SET content = $1, 
-			    embedding = $2, 
-			    tags = $3,
-			    modified_at = $4,
-			    sha = $5,
-			    updated_at = NOW()
-			WHERE store_id = $6 AND path = $7`,
-			document.Content,
-			embeddingStr,
-			tagsJSON,
-			document.ModifiedAt,
-			document.SHA,
-			document.StoreId,
-			document.Path,
-		)
-	} else {
-		// Insert new document
-		_, err = tx.ExecContext(ctx, `
-			INSERT INTO documents (store_id, path, content, embedding, tags, modified_at, sha)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`,
+// upsertDocuments writes the documents with one INSERT ... ON CONFLICT statement. Documents
+// without an embedding keep the stored embedding, embedding model and token count.
+func upsertDocuments(ctx context.Context, tx *sqlx.Tx, documents []*model.Document) error {
+	type key struct {
+		storeID model.StoreId
+		path    string
+	}
+	byKey := make(map[key]*model.Document, len(documents))
+	args := make([]interface{}, 0, len(documents)*documentColumns)
+	for _, document := range documents {
+		if document == nil {
+			return errors.New("document cannot be nil")
+		}
+		tagsJSON, err := marshalTags(document.Tags)
+		if err != nil {
+			return err
+		}
+		embedding, err := newVector(document.Embedding)
+		if err != nil {
+			return err
+		}
+		byKey[key{document.StoreId, document.Path}] = document
+		args = append(args,
 			document.StoreId,
 			document.Path,
 			document.Content,
-			embeddingStr,
+			embedding,
 			tagsJSON,
 			document.ModifiedAt,
 			document.SHA,
+			document.EmbeddingModel,
+			nullInt(document.TokenCount),
+			document.LastCommit.SHA,
+			document.LastCommit.Author,
+			document.LastCommit.Message,
+			encodingOrDefault(document.Encoding),
+			document.BodySHA,
+			document.MetadataSHA,
 		)
 	}
 
+	query := `
+		INSERT INTO documents (store_id, path, content, embedding, tags, modified_at, sha, embedding_model, token_count,
+		                       commit_sha, author, commit_message, encoding, body_sha, metadata_sha)
+		VALUES ` + valuesList(len(documents), documentColumns) + `
+		ON CONFLICT (store_id, path) DO UPDATE
+		SET content = EXCLUDED.content,
+		    embedding = COALESCE(EXCLUDED.embedding, documents.embedding),
+		    tags = EXCLUDED.tags,
+		    modified_at = EXCLUDED.modified_at,
+		    sha = EXCLUDED.sha,
+		    embedding_model = CASE WHEN EXCLUDED.embedding IS NULL THEN documents.embedding_model ELSE EXCLUDED.embedding_model END,
+		    token_count = CASE WHEN EXCLUDED.embedding IS NULL THEN documents.token_count ELSE EXCLUDED.token_count END,
+		    commit_sha = EXCLUDED.commit_sha,
+		    author = EXCLUDED.author,
+		    commit_message = EXCLUDED.commit_message,
+		    encoding = EXCLUDED.encoding,
+		    body_sha = EXCLUDED.body_sha,
+		    metadata_sha = EXCLUDED.metadata_sha,
+		    updated_at = NOW()
+		RETURNING store_id, path, created_at, updated_at`
+
+	rows, err := tx.QueryxContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to upsert documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var saved struct {
+			StoreID   model.StoreId `db:"store_id"`
+			Path      string        `db:"path"`
+			CreatedAt time.Time     `db:"created_at"`
+			UpdatedAt time.Time     `db:"updated_at"`
+		}
+		if err := rows.StructScan(&saved); err != nil {
+			return err
+		}
+		if document, ok := byKey[key{saved.StoreID, saved.Path}]; ok {
+			document.CreatedAt = saved.CreatedAt
+			document.UpdatedAt = saved.UpdatedAt
+		}
+	}
+	return rows.Err()
+}
+
+// FindStoredSHAs returns the SHA of every document of the store keyed by path
+func (r *documentRepository) FindStoredSHAs(ctx context.Context, storeID model.StoreId) (model.StoredSHAs, error) {
+	var rows []struct {
+		Path string `db:"path"`
+		SHA  string `db:"sha"`
+	}
+	query := `SELECT path, COALESCE(sha, '') AS sha FROM documents WHERE store_id = $1`
+	if err := r.db.SelectContext(ctx, &rows, query, storeID); err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
 	}
 
-	// Get the updated/inserted document to set timestamps
-	var updatedDoc struct {
-		CreatedAt time.Time `db:"created_at"`
-		UpdatedAt time.Time `db:"updated_at"`
+	shas := make(model.StoredSHAs, len(rows))
+	for _, row := range rows {
+		shas[row.Path] = row.SHA
 	}
-	err = tx.GetContext(ctx, &updatedDoc,
-		`SELECT created_at, updated_at FROM documents WHERE store_id = $1 AND path = $2`,
-		document.StoreId, document.Path,
+	return shas, nil
+}
+
+// FindStoredBodySHAs returns the body SHA of every document of the store keyed by path, leaving
+// out documents saved before body SHAs were recorded and those without an embedding of the model
+func (r *documentRepository) FindStoredBodySHAs(ctx context.Context, storeID model.StoreId, embeddingModel string) (model.StoredSHAs, error) {
+	var rows []struct {
+		Path    string `db:"path"`
+		BodySHA string `db:"body_sha"`
+	}
+	query := `SELECT path, body_sha FROM documents
+		WHERE store_id = $1 AND body_sha <> '' AND embedding IS NOT NULL AND embedding_model = $2`
+	if err := r.db.SelectContext(ctx, &rows, query, storeID, embeddingModel); err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+
+	shas := make(model.StoredSHAs, len(rows))
+	for _, row := range rows {
+		shas[row.Path] = row.BodySHA
+	}
+	return shas, nil
+}
+
+// FindPathsWithoutCommit returns the paths of the documents of the store without a commit SHA
+func (r *documentRepository) FindPathsWithoutCommit(ctx context.Context, storeID model.StoreId) (map[string]bool, error) {
+	var paths []string
+	query := `SELECT path FROM documents WHERE store_id = $1 AND commit_sha = ''`
+	if err := r.db.SelectContext(ctx, &paths, query, storeID); err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+
+	missing := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		missing[p] = true
+	}
+	return missing, nil
+}
+
+// MoveDocument renames a document within its store, keeping its content and embedding
+func (r *documentRepository) MoveDocument(ctx context.Context, from string, document *model.Document) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE documents
+		SET path = $1, modified_at = $2, commit_sha = $3, author = $4, commit_message = $5,
+		    body_sha = $6, metadata_sha = $7, updated_at = NOW()
+		WHERE store_id = $8 AND path = $9`,
+		document.Path, document.ModifiedAt, document.LastCommit.SHA, document.LastCommit.Author,
+		document.LastCommit.Message, document.BodySHA, document.MetadataSHA, document.StoreId, from,
 	)
 	if err != nil {
 		return err
 	}
 
-	document.CreatedAt = updatedDoc.CreatedAt
-	document.UpdatedAt = updatedDoc.UpdatedAt
-
-	return tx.Commit()
+	return expectAffected(result, repo.ErrDocumentNotFound)
 }
 
-// FindExistingSHAs returns the SHAs of documents that exist in the database
-// This is used to find unchanged documents that don't need to be updated
-func (r *documentRepository) FindExistingSHAs(documents []*model.Document) ([]string, error) {
-	if len(documents) == 0 {
-		return nil, nil
+// encodingOrDefault returns the encoding to store for a document; documents from storages that
+// do not detect encodings are UTF-8
+func encodingOrDefault(encoding string) string {
+	if encoding == "" {
+		return model.EncodingUTF8
 	}
+	return encoding
+}
+
+// documentRow is the database representation of a document
+type documentRow struct {
+	ID             string         `db:"id"`
+	StoreID        uint           `db:"store_id"`
+	Path           string         `db:"path"`
+	Content        string         `db:"content"`
+	Embedding      Vector         `db:"embedding"`
+	Tags           []byte         `db:"tags"`
+	SHA            sql.NullString `db:"sha"`
+	BodySHA        string         `db:"body_sha"`
+	MetadataSHA    string         `db:"metadata_sha"`
+	EmbeddingModel sql.NullString `db:"embedding_model"`
+	TokenCount     sql.NullInt64  `db:"token_count"`
+	ModifiedAt     sql.NullTime   `db:"modified_at"`
+	CommitSHA      string         `db:"commit_sha"`
+	Author         string         `db:"author"`
+	CommitMessage  string         `db:"commit_message"`
+	Encoding       string         `db:"encoding"`
+	CreatedAt      time.Time      `db:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at"`
+}
 
-	var shas []string
+// toModel converts a database row into a domain document
+func (row *documentRow) toModel() (*model.Document, error) {
+	document := &model.Document{
+		ID:             model.DocumentId(row.ID),
+		StoreId:        model.StoreId(row.StoreID),
+		Path:           row.Path,
+		Content:        row.Content,
+		Embedding:      row.Embedding.Float64s(),
+		SHA:            row.SHA.String,
+		BodySHA:        row.BodySHA,
+		MetadataSHA:    row.MetadataSHA,
+		EmbeddingModel: row.EmbeddingModel.String,
+		TokenCount:     int(row.TokenCount.Int64),
+		Encoding:       row.Encoding,
+		ModifiedAt:     row.ModifiedAt.Time,
+		LastCommit: model.Commit{
+			SHA:     row.CommitSHA,
+			Author:  row.Author,
+			Message: row.CommitMessage,
+			Time:    row.ModifiedAt.Time,
+		},
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
 
-	for _, doc := range documents {
-		if doc != nil {
-			shas = append(shas, doc.SHA)
+	// Parse tags from JSON
+	if len(row.Tags) > 0 {
+		if err := json.Unmarshal(row.Tags, &document.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
 		}
 	}
 
-	if len(shas) == 0 {
-		return nil, nil
+	return document, nil
+}
+
+// ListDocuments returns documents matching the filter ordered by store and path.
+// Content and embedding are not loaded.
+func (r *documentRepository) ListDocuments(ctx context.Context, filter repo.DocumentFilter) ([]*model.Document, error) {
+	var where whereClause
+	if filter.StoreId != 0 {
+		where.add("store_id = ?", filter.StoreId)
+	}
+	if filter.Tag != "" {
+		tagJSON, err := json.Marshal([]string{filter.Tag})
+		if err != nil {
+			return nil, err
+		}
+		where.add("tags @> ?::jsonb", string(tagJSON))
+	}
+	if filter.PathPrefix != "" {
+		where.add("path LIKE ? || '%'", escapeLike(filter.PathPrefix))
+	}
+	if !filter.ModifiedSince.IsZero() {
+		where.add("modified_at >= ?", filter.ModifiedSince)
+	}
+	if filter.Author != "" {
+		where.add("author ILIKE '%' || ? || '%'", escapeLike(filter.Author))
 	}
 
-	// Get existing documents with their SHAs
 	query := `
-		SELECT sha
+		SELECT id::text AS id, store_id, path, '' AS content, NULL AS embedding, tags, sha,
+		       embedding_model, token_count, modified_at, commit_sha, author, commit_message,
+		       encoding, body_sha, metadata_sha, created_at, updated_at
 		FROM documents
-		WHERE sha = ANY($1)
+		` + where.String() + `
+		ORDER BY store_id, path
 	`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
 
-	rows, err := r.db.Query(query, pq.Array(shas))
-	if err != nil {
+	var rows []documentRow
+	if err := r.db.SelectContext(ctx, &rows, query, where.args...); err != nil {
 		return nil, fmt.Errorf("failed to query documents: %w", err)
 	}
-	defer rows.Close()
 
-	// Collect document IDs where SHA matches
-	var unchangedSHAs []string
-
-	for rows.Next() {
-		var sha string
-
-		if err := rows.Scan(&sha); err != nil {
-			return nil, fmt.Errorf("failed to scan document row: %w", err)
+	documents := make([]*model.Document, 0, len(rows))
+	for i := range rows {
+		document, err := rows[i].toModel()
+		if err != nil {
+			return nil, err
 		}
-
-		unchangedSHAs = append(unchangedSHAs, sha)
+		documents = append(documents, document)
 	}
+	return documents, nil
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating document rows: %w", err)
+// GetDocument returns the document at the given path of a store, including content and embedding
+func (r *documentRepository) GetDocument(ctx context.Context, storeID model.StoreId, path string) (*model.Document, error) {
+	query := `
+		SELECT id::text AS id, store_id, path, content, embedding, tags, sha,
+		       embedding_model, token_count, modified_at, commit_sha, author, commit_message,
+		       encoding, body_sha, metadata_sha, created_at, updated_at
+		FROM documents
+		WHERE store_id = $1 AND path = $2
+	`
+
+	var row documentRow
+	if err := r.db.GetContext(ctx, &row, query, storeID, path); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.ErrDocumentNotFound
+		}
+		return nil, fmt.Errorf("failed to query document: %w", err)
 	}
 
-	return unchangedSHAs, nil
+	return row.toModel()
 }
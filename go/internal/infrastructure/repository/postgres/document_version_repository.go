@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	repo "github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// Ensure documentVersionRepository implements repo.DocumentVersionRepository
+var _ repo.DocumentVersionRepository = (*documentVersionRepository)(nil)
+
+type documentVersionRepository struct {
+	db *sqlx.DB
+}
+
+// NewDocumentVersionRepository creates a new PostgreSQL document version repository
+func NewDocumentVersionRepository(db *sqlx.DB) repo.DocumentVersionRepository {
+	return &documentVersionRepository{db: db}
+}
+
+// recordVersions copies the stored rows of the documents whose SHA changes into document_versions,
+// numbering them after the last version of each document. It must run before the upsert.
+func recordVersions(ctx context.Context, tx *sqlx.Tx, documents []*model.Document) error {
+	args := make([]interface{}, 0, len(documents)*3)
+	for _, document := range documents {
+		if document == nil {
+			return errors.New("document cannot be nil")
+		}
+		args = append(args, document.StoreId, document.Path, document.SHA)
+	}
+
+	query := `
+		INSERT INTO document_versions (document_id, version, path, content, sha, embedding_model, modified_at,
+		                               commit_sha, author, commit_message)
+		SELECT d.id,
+		       COALESCE((SELECT MAX(v.version) FROM document_versions v WHERE v.document_id = d.id), 0) + 1,
+		       d.path, d.content, d.sha, d.embedding_model, d.modified_at, d.commit_sha, d.author, d.commit_message
+		FROM documents d
+		JOIN (VALUES ` + valuesList(len(documents), 3) + `) AS saved (store_id, path, sha)
+		  ON d.store_id = saved.store_id::integer AND d.path = saved.path
+		WHERE d.sha IS DISTINCT FROM saved.sha`
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record document versions: %w", err)
+	}
+	return nil
+}
+
+// documentVersionRow is the database representation of a document version
+type documentVersionRow struct {
+	Version        int            `db:"version"`
+	StoreID        uint           `db:"store_id"`
+	Path           string         `db:"path"`
+	Content        string         `db:"content"`
+	SHA            sql.NullString `db:"sha"`
+	EmbeddingModel sql.NullString `db:"embedding_model"`
+	ModifiedAt     sql.NullTime   `db:"modified_at"`
+	CommitSHA      string         `db:"commit_sha"`
+	Author         string         `db:"author"`
+	CommitMessage  string         `db:"commit_message"`
+	ReplacedAt     time.Time      `db:"replaced_at"`
+}
+
+// toModel converts a database row into a domain document version
+func (row *documentVersionRow) toModel() *model.DocumentVersion {
+	replacedAt := row.ReplacedAt
+	return &model.DocumentVersion{
+		Number:         row.Version,
+		StoreId:        model.StoreId(row.StoreID),
+		Path:           row.Path,
+		Content:        row.Content,
+		SHA:            row.SHA.String,
+		EmbeddingModel: row.EmbeddingModel.String,
+		ModifiedAt:     row.ModifiedAt.Time,
+		LastCommit: model.Commit{
+			SHA:     row.CommitSHA,
+			Author:  row.Author,
+			Message: row.CommitMessage,
+			Time:    row.ModifiedAt.Time,
+		},
+		ReplacedAt: &replacedAt,
+	}
+}
+
+// ListVersions returns the previous versions of the document at path, oldest first, without content
+func (r *documentVersionRepository) ListVersions(ctx context.Context, storeID model.StoreId, path string) ([]*model.DocumentVersion, error) {
+	var documentID int64
+	err := r.db.GetContext(ctx, &documentID, `SELECT id FROM documents WHERE store_id = $1 AND path = $2`, storeID, path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repo.ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document: %w", err)
+	}
+
+	query := `
+		SELECT v.version, d.store_id, v.path, '' AS content, v.sha, v.embedding_model, v.modified_at,
+		       v.commit_sha, v.author, v.commit_message, v.replaced_at
+		FROM document_versions v
+		JOIN documents d ON d.id = v.document_id
+		WHERE v.document_id = $1
+		ORDER BY v.version
+	`
+	var rows []documentVersionRow
+	if err := r.db.SelectContext(ctx, &rows, query, documentID); err != nil {
+		return nil, fmt.Errorf("failed to query document versions: %w", err)
+	}
+
+	versions := make([]*model.DocumentVersion, len(rows))
+	for i := range rows {
+		versions[i] = rows[i].toModel()
+	}
+	return versions, nil
+}
+
+// GetVersion returns a previous version of the document at path including its content
+func (r *documentVersionRepository) GetVersion(ctx context.Context, storeID model.StoreId, path string, number int) (*model.DocumentVersion, error) {
+	query := `
+		SELECT v.version, d.store_id, v.path, v.content, v.sha, v.embedding_model, v.modified_at,
+		       v.commit_sha, v.author, v.commit_message, v.replaced_at
+		FROM document_versions v
+		JOIN documents d ON d.id = v.document_id
+		WHERE d.store_id = $1 AND d.path = $2 AND v.version = $3
+	`
+	var row documentVersionRow
+	if err := r.db.GetContext(ctx, &row, query, storeID, path, number); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.ErrDocumentVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to query document version: %w", err)
+	}
+	return row.toModel(), nil
+}
+
+// Prune removes versions replaced before maxAge and then the oldest versions above maxVersions per document
+func (r *documentVersionRepository) Prune(ctx context.Context, maxVersions int, maxAge time.Duration) (int, error) {
+	var removed int64
+	if maxAge > 0 {
+		result, err := r.db.ExecContext(ctx,
+			`DELETE FROM document_versions WHERE replaced_at < $1`,
+			time.Now().Add(-maxAge),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to remove expired document versions: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		removed += affected
+	}
+
+	if maxVersions > 0 {
+		result, err := r.db.ExecContext(ctx, `
+			DELETE FROM document_versions
+			WHERE id IN (
+				SELECT id FROM (
+					SELECT id, ROW_NUMBER() OVER (PARTITION BY document_id ORDER BY version DESC) AS newer
+					FROM document_versions
+				) ranked
+				WHERE newer > $1
+			)`,
+			maxVersions,
+		)
+		if err != nil {
+			return int(removed), fmt.Errorf("failed to remove old document versions: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return int(removed), err
+		}
+		removed += affected
+	}
+	return int(removed), nil
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	repo "github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// Ensure embeddingCacheRepository implements repo.EmbeddingCacheRepository
+var _ repo.EmbeddingCacheRepository = (*embeddingCacheRepository)(nil)
+
+type embeddingCacheRepository struct {
+	db *sqlx.DB
+}
+
+// NewEmbeddingCacheRepository creates a new PostgreSQL embedding cache repository
+func NewEmbeddingCacheRepository(db *sqlx.DB) repo.EmbeddingCacheRepository {
+	return &embeddingCacheRepository{db: db}
+}
+
+// GetEmbedding returns the cached embedding and updates its hit count and last use time
+func (r *embeddingCacheRepository) GetEmbedding(ctx context.Context, sha, embeddingModel string) ([]float64, error) {
+	var embedding Vector
+	query := `
+		UPDATE embedding_cache
+		SET hits = hits + 1, last_used_at = NOW()
+		WHERE sha = $1 AND model = $2
+		RETURNING embedding
+	`
+	err := r.db.GetContext(ctx, &embedding, query, sha, embeddingModel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query embedding cache: %w", err)
+	}
+
+	return embedding.Float64s(), nil
+}
+
+// PutEmbedding stores an embedding; an existing entry for the same key is kept
+func (r *embeddingCacheRepository) PutEmbedding(ctx context.Context, sha, embeddingModel string, embedding []float64) error {
+	vector, err := newVector(embedding)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO embedding_cache (sha, model, embedding)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (sha, model) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, sha, embeddingModel, vector); err != nil {
+		return fmt.Errorf("failed to store embedding in cache: %w", err)
+	}
+	return nil
+}
+
+// Evict removes expired entries and then the least recently used entries above maxEntries
+func (r *embeddingCacheRepository) Evict(ctx context.Context, maxEntries int, maxAge time.Duration) (int, error) {
+	var removed int64
+	if maxAge > 0 {
+		result, err := r.db.ExecContext(ctx,
+			`DELETE FROM embedding_cache WHERE last_used_at < $1`,
+			time.Now().Add(-maxAge),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to evict expired embeddings: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		removed += affected
+	}
+
+	if maxEntries > 0 {
+		query := `
+			DELETE FROM embedding_cache
+			WHERE (sha, model) IN (
+				SELECT sha, model FROM embedding_cache
+				ORDER BY last_used_at DESC
+				OFFSET $1
+			)
+		`
+		result, err := r.db.ExecContext(ctx, query, maxEntries)
+		if err != nil {
+			return 0, fmt.Errorf("failed to evict least recently used embeddings: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		removed += affected
+	}
+
+	return int(removed), nil
+}
+
+// Stats returns the number, size and hit count of the cached embeddings
+func (r *embeddingCacheRepository) Stats(ctx context.Context) (*model.EmbeddingCacheStats, error) {
+	var row struct {
+		Entries      int        `db:"entries"`
+		SizeBytes    int64      `db:"size_bytes"`
+		Hits         int64      `db:"hits"`
+		OldestUsedAt *time.Time `db:"oldest_used_at"`
+		NewestUsedAt *time.Time `db:"newest_used_at"`
+	}
+	query := `
+		SELECT
+			COUNT(*) AS entries,
+			pg_total_relation_size('embedding_cache') AS size_bytes,
+			COALESCE(SUM(hits), 0) AS hits,
+			MIN(last_used_at) AS oldest_used_at,
+			MAX(last_used_at) AS newest_used_at
+		FROM embedding_cache
+	`
+	if err := r.db.GetContext(ctx, &row, query); err != nil {
+		return nil, fmt.Errorf("failed to query embedding cache stats: %w", err)
+	}
+
+	return &model.EmbeddingCacheStats{
+		Entries:      row.Entries,
+		SizeBytes:    row.SizeBytes,
+		Hits:         row.Hits,
+		OldestUsedAt: row.OldestUsedAt,
+		NewestUsedAt: row.NewestUsedAt,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	repo "github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// Ensure indexRepository implements repo.IndexRepository
+var _ repo.IndexRepository = (*indexRepository)(nil)
+
+// vectorOperatorClass matches the cosine distance operator (<=>) used by the agent's similarity queries
+const vectorOperatorClass = "vector_cosine_ops"
+
+var listsPattern = regexp.MustCompile(`lists\s*=\s*'?(\d+)`)
+
+type indexRepository struct {
+	db *sqlx.DB
+}
+
+// NewIndexRepository creates a new PostgreSQL index repository
+func NewIndexRepository(db *sqlx.DB) repo.IndexRepository {
+	return &indexRepository{db: db}
+}
+
+// GetVectorIndex returns the state of the vector index of the table
+func (r *indexRepository) GetVectorIndex(ctx context.Context, table string) (*model.VectorIndex, error) {
+	if !model.IsVectorTable(table) {
+		return nil, fmt.Errorf("%w: %q", model.ErrUnknownVectorTable, table)
+	}
+
+	index := &model.VectorIndex{Table: table}
+
+	var row struct {
+		Name       string `db:"name"`
+		Method     string `db:"method"`
+		Definition string `db:"definition"`
+		Valid      bool   `db:"valid"`
+		SizeBytes  int64  `db:"size_bytes"`
+	}
+	query := `
+		SELECT
+			i.relname AS name,
+			am.amname AS method,
+			pg_get_indexdef(i.oid) AS definition,
+			ix.indisvalid AS valid,
+			pg_relation_size(i.oid) AS size_bytes
+		FROM pg_class i
+		JOIN pg_index ix ON ix.indexrelid = i.oid
+		JOIN pg_am am ON am.oid = i.relam
+		WHERE i.relname = $1 AND ix.indrelid = $2::regclass
+	`
+	err := r.db.GetContext(ctx, &row, query, model.VectorIndexName(table), table)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// The table has no vector index
+	case err != nil:
+		return nil, fmt.Errorf("failed to query index of %s: %w", table, err)
+	default:
+		index.Name = row.Name
+		index.Method = row.Method
+		index.Definition = row.Definition
+		index.Valid = row.Valid
+		index.SizeBytes = row.SizeBytes
+		if m := listsPattern.FindStringSubmatch(row.Definition); m != nil {
+			index.Lists, _ = strconv.Atoi(m[1])
+		}
+	}
+
+	// The table name is one of model.VectorTables, so it is safe to format into the query
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) AS rows, COUNT(embedding) AS embedded_rows FROM %s`, table)
+	var counts struct {
+		Rows         int `db:"rows"`
+		EmbeddedRows int `db:"embedded_rows"`
+	}
+	if err := r.db.GetContext(ctx, &counts, countQuery); err != nil {
+		return nil, fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+	index.Rows = counts.Rows
+	index.EmbeddedRows = counts.EmbeddedRows
+
+	return index, nil
+}
+
+// CreateVectorIndex builds the new index concurrently under a temporary name and then swaps it
+// with the existing one, so that queries can use the old index until the new one is ready
+func (r *indexRepository) CreateVectorIndex(ctx context.Context, spec model.VectorIndexSpec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	var params string
+	switch spec.Method {
+	case model.IndexMethodHNSW:
+		params = fmt.Sprintf("m = %d, ef_construction = %d", spec.M, spec.EfConstruction)
+	case model.IndexMethodIVFFlat:
+		params = fmt.Sprintf("lists = %d", spec.Lists)
+	}
+
+	name := spec.IndexName()
+	tmpName := name + "_new"
+
+	// Remove the leftover of an interrupted build
+	if _, err := r.db.ExecContext(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+tmpName); err != nil {
+		return fmt.Errorf("failed to drop %s: %w", tmpName, err)
+	}
+
+	create := fmt.Sprintf(`CREATE INDEX CONCURRENTLY %s ON %s USING %s (embedding %s) WITH (%s)`,
+		tmpName, spec.Table, spec.Method, vectorOperatorClass, params)
+	if _, err := r.db.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("failed to build index on %s: %w", spec.Table, err)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS `+name); err != nil {
+		return fmt.Errorf("failed to drop %s: %w", name, err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER INDEX %s RENAME TO %s`, tmpName, name)); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", tmpName, err)
+	}
+
+	return tx.Commit()
+}
+
+// RebuildVectorIndex rebuilds the vector index of the table without blocking writes
+func (r *indexRepository) RebuildVectorIndex(ctx context.Context, table string) error {
+	index, err := r.GetVectorIndex(ctx, table)
+	if err != nil {
+		return err
+	}
+	if !index.Exists() {
+		return fmt.Errorf("%w on %s", repo.ErrVectorIndexNotFound, table)
+	}
+
+	if _, err := r.db.ExecContext(ctx, `REINDEX INDEX CONCURRENTLY `+index.Name); err != nil {
+		return fmt.Errorf("failed to rebuild %s: %w", index.Name, err)
+	}
+	return nil
+}
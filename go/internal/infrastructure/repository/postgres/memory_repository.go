@@ -2,17 +2,15 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
-	"strings"
 	"time"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 	repo "github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
 	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
 )
 
 // Ensure memoryRepository implements repo.MemoryRepository
@@ -27,14 +25,22 @@ func NewMemoryRepository(db *sqlx.DB) repo.MemoryRepository {
 	return &memoryRepository{db: db}
 }
 
+// memoryColumns is the number of columns written per memory by upsertMemories
+const memoryColumns = 15
+
 // SaveMemory saves or updates a memory in the database
-func (r *memoryRepository) SaveMemory(memory *model.Memory) error {
+func (r *memoryRepository) SaveMemory(ctx context.Context, memory *model.Memory) error {
 	if memory == nil {
 		return errors.New("memory cannot be nil")
 	}
+	return r.SaveMemories(ctx, []*model.Memory{memory})
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// SaveMemories upserts the memories in a single transaction and sets their timestamps
+func (r *memoryRepository) SaveMemories(ctx context.Context, memories []*model.Memory) error {
+	if len(memories) == 0 {
+		return nil
+	}
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -42,123 +48,138 @@ func (r *memoryRepository) SaveMemory(memory *model.Memory) error {
 	}
 	defer tx.Rollback()
 
-	// Convert tags to JSONB (always as array, never null)
-	tags := memory.Tags
-	if tags == nil {
-		tags = []string{}
-	}
-	tagsJSON, err := json.Marshal(tags)
-	if err != nil {
-		return err
+	for start := 0; start < len(memories); start += maxUpsertRows {
+		end := min(start+maxUpsertRows, len(memories))
+		if err := upsertMemories(ctx, tx, memories[start:end]); err != nil {
+			return err
+		}
 	}
 
-	// Convert embedding to PostgreSQL vector format
-	const expectedDim = 1536
-	var embeddingStr string = ""
-	if len(memory.Embedding) > 0 {
-		if len(memory.Embedding) != expectedDim {
-			return fmt.Errorf("invalid embedding dimension: got %d, want %d", len(memory.Embedding), expectedDim)
+	return tx.Commit()
+}
+
+// upsertMemories writes the memories with one INSERT ... ON CONFLICT statement. Memories
+// without an embedding keep the stored embedding, embedding model and token count.
+func upsertMemories(ctx context.Context, tx *sqlx.Tx, memories []*model.Memory) error {
+	byPath := make(map[string]*model.Memory, len(memories))
+	args := make([]interface{}, 0, len(memories)*memoryColumns)
+	for _, memory := range memories {
+		if memory == nil {
+			return errors.New("memory cannot be nil")
 		}
-		for i, v := range memory.Embedding {
-			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
-				return fmt.Errorf("invalid embedding value at position %d: %v", i, v)
-			}
+		tagsJSON, err := marshalTags(memory.Tags)
+		if err != nil {
+			return err
 		}
-		// Convert to JSON array format
-		parts := make([]string, len(memory.Embedding))
-		for i, v := range memory.Embedding {
-			parts[i] = fmt.Sprintf("%f", v)
+		embedding, err := newVector(memory.Embedding)
+		if err != nil {
+			return err
 		}
-		embeddingStr = "[" + strings.Join(parts, ",") + "]"
-	}
-
-	// Check if memory exists
-	var exists bool
-	err = tx.GetContext(ctx, &exists,
-		`SELECT EXISTS(SELECT 1 FROM memories WHERE path = $1)`,
-		memory.Path,
-	)
-	if err != nil {
-		return err
-	}
-
-	if exists {
-		// Update existing memory
-		_, err = tx.ExecContext(ctx, `
-			UPDATE memories 
-			SET content = $1, 
-			    embedding = $2, 
-			    tags = $3,
-			    modified_at = $4,
-			    sha = $5,
-			    updated_at = NOW()
-			WHERE path = $6`,
-			memory.Content,
-			embeddingStr,
-			tagsJSON,
-			memory.ModifiedAt,
-			memory.SHA,
-			memory.Path,
-		)
-	} else {
-		// Insert new memory
-		_, err = tx.ExecContext(ctx, `
-			INSERT INTO memories (path, content, embedding, tags, modified_at, sha)
-			VALUES ($1, $2, $3, $4, $5, $6)
-		`,
+		supersedesJSON, err := marshalTags(memory.Supersedes)
+		if err != nil {
+			return err
+		}
+		byPath[memory.Path] = memory
+		args = append(args,
 			memory.Path,
 			memory.Content,
-			embeddingStr,
+			embedding,
 			tagsJSON,
 			memory.ModifiedAt,
 			memory.SHA,
+			memory.EmbeddingModel,
+			nullInt(memory.TokenCount),
+			string(memory.Type),
+			nullInt(memory.Importance),
+			memory.Source,
+			nullTime(memory.ExpiresAt),
+			supersedesJSON,
+			memory.BodySHA,
+			memory.MetadataSHA,
 		)
 	}
 
+	query := `
+		INSERT INTO memories (path, content, embedding, tags, modified_at, sha, embedding_model, token_count,
+			type, importance, source, expires_at, supersedes, body_sha, metadata_sha)
+		VALUES ` + valuesList(len(memories), memoryColumns) + `
+		ON CONFLICT (path) DO UPDATE
+		SET content = EXCLUDED.content,
+		    embedding = COALESCE(EXCLUDED.embedding, memories.embedding),
+		    tags = EXCLUDED.tags,
+		    modified_at = EXCLUDED.modified_at,
+		    sha = EXCLUDED.sha,
+		    embedding_model = CASE WHEN EXCLUDED.embedding IS NULL THEN memories.embedding_model ELSE EXCLUDED.embedding_model END,
+		    token_count = CASE WHEN EXCLUDED.embedding IS NULL THEN memories.token_count ELSE EXCLUDED.token_count END,
+		    type = EXCLUDED.type,
+		    importance = EXCLUDED.importance,
+		    source = EXCLUDED.source,
+		    expires_at = EXCLUDED.expires_at,
+		    supersedes = EXCLUDED.supersedes,
+		    body_sha = EXCLUDED.body_sha,
+		    metadata_sha = EXCLUDED.metadata_sha,
+		    archived_at = NULL,
+		    updated_at = NOW()
+		RETURNING path, created_at, updated_at`
+
+	rows, err := tx.QueryxContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to upsert memories: %w", err)
 	}
+	defer rows.Close()
 
-	// Get the updated/inserted memory to set timestamps
-	var updatedMem struct {
-		CreatedAt time.Time `db:"created_at"`
-		UpdatedAt time.Time `db:"updated_at"`
-	}
-	err = tx.GetContext(ctx, &updatedMem,
-		`SELECT created_at, updated_at FROM memories WHERE path = $1`,
-		memory.Path,
-	)
-	if err != nil {
-		return err
+	for rows.Next() {
+		var saved struct {
+			Path      string    `db:"path"`
+			CreatedAt time.Time `db:"created_at"`
+			UpdatedAt time.Time `db:"updated_at"`
+		}
+		if err := rows.StructScan(&saved); err != nil {
+			return err
+		}
+		if memory, ok := byPath[saved.Path]; ok {
+			memory.CreatedAt = saved.CreatedAt
+			memory.UpdatedAt = saved.UpdatedAt
+		}
 	}
-
-	memory.CreatedAt = updatedMem.CreatedAt
-	memory.UpdatedAt = updatedMem.UpdatedAt
-
-	return tx.Commit()
+	return rows.Err()
 }
 
-// ListMemories retrieves all memories from the database
-func (r *memoryRepository) ListMemories() ([]*model.Memory, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// ListMemories retrieves the memories matching the filter, newest first
+func (r *memoryRepository) ListMemories(ctx context.Context, filter repo.MemoryFilter) ([]*model.Memory, error) {
+	var where whereClause
+	if filter.Tag != "" {
+		tagJSON, err := json.Marshal([]string{filter.Tag})
+		if err != nil {
+			return nil, err
+		}
+		where.add("tags @> ?::jsonb", string(tagJSON))
+	}
+	if filter.PathPrefix != "" {
+		where.add("path LIKE ? || '%'", escapeLike(filter.PathPrefix))
+	}
+	if !filter.ModifiedSince.IsZero() {
+		where.add("modified_at >= ?", filter.ModifiedSince)
+	}
+	if filter.Type != "" {
+		where.add("type = ?", string(filter.Type))
+	}
+	if filter.MinImportance > 0 {
+		where.add("importance >= ?", filter.MinImportance)
+	}
+	if filter.Source != "" {
+		where.add("source = ?", filter.Source)
+	}
+	if !filter.IncludeArchived {
+		where.conditions = append(where.conditions, "archived_at IS NULL")
+	}
+
+	query := `SELECT ` + memoryColumnList + ` FROM memories ` + where.String() + ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
 
-	query := `
-		SELECT 
-			id,
-			path,
-			content,
-			embedding,
-			tags,
-			sha,
-			modified_at,
-			created_at,
-			updated_at
-		FROM memories
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query memories: %w", err)
 	}
@@ -166,49 +187,11 @@ func (r *memoryRepository) ListMemories() ([]*model.Memory, error) {
 
 	var memories []*model.Memory
 	for rows.Next() {
-		var memory model.Memory
-		var embeddingStr string
-		var tagsJSON []byte
-
-		err := rows.Scan(
-			&memory.ID,
-			&memory.Path,
-			&memory.Content,
-			&embeddingStr,
-			&tagsJSON,
-			&memory.SHA,
-			&memory.ModifiedAt,
-			&memory.CreatedAt,
-			&memory.UpdatedAt,
-		)
+		memory, err := scanMemory(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan memory row: %w", err)
-		}
-
-		// Parse tags from JSON
-		if len(tagsJSON) > 0 {
-			if err := json.Unmarshal(tagsJSON, &memory.Tags); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
-			}
+			return nil, err
 		}
-
-		// Parse embedding from vector string format
-		if embeddingStr != "" && embeddingStr != "[]" {
-			// Remove brackets and split by comma
-			embeddingStr = strings.Trim(embeddingStr, "[]")
-			parts := strings.Split(embeddingStr, ",")
-			memory.Embedding = make([]float64, len(parts))
-			for i, part := range parts {
-				var val float64
-				_, err := fmt.Sscanf(strings.TrimSpace(part), "%f", &val)
-				if err != nil {
-					return nil, fmt.Errorf("failed to parse embedding value: %w", err)
-				}
-				memory.Embedding[i] = val
-			}
-		}
-
-		memories = append(memories, &memory)
+		memories = append(memories, memory)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -218,54 +201,199 @@ func (r *memoryRepository) ListMemories() ([]*model.Memory, error) {
 	return memories, nil
 }
 
-// FindExistingSHAs returns the SHAs of memories that exist in the database
-// This is used to find unchanged memories that don't need to be updated
-func (r *memoryRepository) FindExistingSHAs(memories []*model.Memory) ([]string, error) {
-	if len(memories) == 0 {
-		return nil, nil
+// GetMemory returns the memory at path, including content and embedding
+func (r *memoryRepository) GetMemory(ctx context.Context, path string) (*model.Memory, error) {
+	query := `SELECT ` + memoryColumnList + ` FROM memories WHERE path = $1`
+	memory, err := scanMemory(r.db.QueryRowContext(ctx, query, path))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repo.ErrMemoryNotFound
 	}
+	return memory, err
+}
 
-	var shas []string
+// DeleteMemory deletes the memory at path
+func (r *memoryRepository) DeleteMemory(ctx context.Context, path string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM memories WHERE path = $1`, path)
+	if err != nil {
+		return fmt.Errorf("failed to delete memory: %w", err)
+	}
+	return expectAffected(result, repo.ErrMemoryNotFound)
+}
 
-	for _, mem := range memories {
-		if mem != nil {
-			shas = append(shas, mem.SHA)
+// memoryColumnList is the column list read by scanMemory
+const memoryColumnList = `
+	id::text, path, content, embedding, tags, sha, body_sha, metadata_sha, embedding_model, token_count,
+	type, importance, source, expires_at, supersedes, archived_at,
+	modified_at, created_at, updated_at`
+
+// scanMemory scans a row of memoryColumnList
+func scanMemory(row rowScanner) (*model.Memory, error) {
+	var memory model.Memory
+	var embedding Vector
+	var sha, embeddingModel sql.NullString
+	var tokenCount, importance sql.NullInt64
+	var memoryType string
+	var expiresAt, archivedAt, modifiedAt sql.NullTime
+	var tagsJSON, supersedesJSON []byte
+
+	err := row.Scan(
+		&memory.ID,
+		&memory.Path,
+		&memory.Content,
+		&embedding,
+		&tagsJSON,
+		&sha,
+		&memory.BodySHA,
+		&memory.MetadataSHA,
+		&embeddingModel,
+		&tokenCount,
+		&memoryType,
+		&importance,
+		&memory.Source,
+		&expiresAt,
+		&supersedesJSON,
+		&archivedAt,
+		&modifiedAt,
+		&memory.CreatedAt,
+		&memory.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan memory row: %w", err)
+	}
+	memory.Embedding = embedding.Float64s()
+	memory.SHA = sha.String
+	memory.EmbeddingModel = embeddingModel.String
+	memory.TokenCount = int(tokenCount.Int64)
+	memory.Type = model.MemoryType(memoryType)
+	memory.Importance = int(importance.Int64)
+	memory.ExpiresAt = expiresAt.Time
+	memory.ArchivedAt = archivedAt.Time
+	memory.ModifiedAt = modifiedAt.Time
+
+	// Parse tags and superseded paths from JSON
+	if len(tagsJSON) > 0 {
+		if err := json.Unmarshal(tagsJSON, &memory.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
 		}
 	}
+	if len(supersedesJSON) > 0 {
+		if err := json.Unmarshal(supersedesJSON, &memory.Supersedes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal supersedes: %w", err)
+		}
+	}
+
+	return &memory, nil
+}
 
-	if len(shas) == 0 {
-		return nil, nil
+// FindStoredSHAs returns the SHA of every memory keyed by path
+func (r *memoryRepository) FindStoredSHAs(ctx context.Context) (model.StoredSHAs, error) {
+	var rows []struct {
+		Path string `db:"path"`
+		SHA  string `db:"sha"`
+	}
+	query := `SELECT path, COALESCE(sha, '') AS sha FROM memories`
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to query memories: %w", err)
 	}
 
-	// Get existing memories with their SHAs
-	query := `
-		SELECT sha
-		FROM memories
-		WHERE sha = ANY($1)
-	`
+	shas := make(model.StoredSHAs, len(rows))
+	for _, row := range rows {
+		shas[row.Path] = row.SHA
+	}
+	return shas, nil
+}
 
-	rows, err := r.db.Query(query, pq.Array(shas))
-	if err != nil {
+// FindStoredBodySHAs returns the body SHA of every memory with an embedding of the model keyed by path
+func (r *memoryRepository) FindStoredBodySHAs(ctx context.Context, embeddingModel string) (model.StoredSHAs, error) {
+	var rows []struct {
+		Path    string `db:"path"`
+		BodySHA string `db:"body_sha"`
+	}
+	query := `SELECT path, body_sha FROM memories
+		WHERE body_sha <> '' AND embedding IS NOT NULL AND embedding_model = $1`
+	if err := r.db.SelectContext(ctx, &rows, query, embeddingModel); err != nil {
 		return nil, fmt.Errorf("failed to query memories: %w", err)
 	}
-	defer rows.Close()
 
-	// Collect memory SHAs where SHA matches
-	var unchangedSHAs []string
+	shas := make(model.StoredSHAs, len(rows))
+	for _, row := range rows {
+		shas[row.Path] = row.BodySHA
+	}
+	return shas, nil
+}
 
-	for rows.Next() {
-		var sha string
+// MoveMemory renames a memory, keeping its content and embedding
+func (r *memoryRepository) MoveMemory(ctx context.Context, from, to string, modifiedAt time.Time) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE memories SET path = $1, modified_at = $2, updated_at = NOW() WHERE path = $3`,
+		to, modifiedAt, from,
+	)
+	if err != nil {
+		return err
+	}
 
-		if err := rows.Scan(&sha); err != nil {
-			return nil, fmt.Errorf("failed to scan memory row: %w", err)
-		}
+	return expectAffected(result, repo.ErrMemoryNotFound)
+}
 
-		unchangedSHAs = append(unchangedSHAs, sha)
+// FindSimilarMemories returns the pairs of active memories embedded with the same model
+// whose cosine similarity is at least minSimilarity. Every pair is compared, which is
+// fine for the number of memories of one agent.
+func (r *memoryRepository) FindSimilarMemories(ctx context.Context, minSimilarity float64) ([]model.MemorySimilarity, error) {
+	var rows []struct {
+		PathA      string  `db:"path_a"`
+		PathB      string  `db:"path_b"`
+		Similarity float64 `db:"similarity"`
+	}
+	query := `
+		SELECT a.path AS path_a, b.path AS path_b, 1 - (a.embedding <=> b.embedding) AS similarity
+		FROM memories a
+		JOIN memories b ON a.path < b.path AND a.embedding_model IS NOT DISTINCT FROM b.embedding_model
+		WHERE a.archived_at IS NULL AND b.archived_at IS NULL
+		  AND a.embedding IS NOT NULL AND b.embedding IS NOT NULL
+		  AND (a.embedding <=> b.embedding) <= $1
+		ORDER BY similarity DESC`
+	if err := r.db.SelectContext(ctx, &rows, query, 1-minSimilarity); err != nil {
+		return nil, fmt.Errorf("failed to find similar memories: %w", err)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating memory rows: %w", err)
+	pairs := make([]model.MemorySimilarity, len(rows))
+	for i, row := range rows {
+		pairs[i] = model.MemorySimilarity{PathA: row.PathA, PathB: row.PathB, Similarity: row.Similarity}
 	}
+	return pairs, nil
+}
 
-	return unchangedSHAs, nil
-}
\ No newline at end of file
+// ArchiveExpired archives the active memories that expired at or before now and returns their paths
+func (r *memoryRepository) ArchiveExpired(ctx context.Context, now time.Time) ([]string, error) {
+	var paths []string
+	query := `
+		UPDATE memories SET archived_at = $1
+		WHERE archived_at IS NULL AND expires_at <= $1
+		RETURNING path`
+	if err := r.db.SelectContext(ctx, &paths, query, now); err != nil {
+		return nil, fmt.Errorf("failed to archive expired memories: %w", err)
+	}
+	return paths, nil
+}
+
+// ArchiveSuperseded archives the memories superseded by an active memory and returns their paths
+func (r *memoryRepository) ArchiveSuperseded(ctx context.Context, now time.Time) ([]string, error) {
+	var paths []string
+	query := `
+		UPDATE memories m SET archived_at = $1
+		WHERE m.archived_at IS NULL
+		  AND EXISTS (
+		    SELECT 1 FROM memories s
+		    WHERE s.archived_at IS NULL
+		      AND s.path <> m.path
+		      AND s.supersedes @> jsonb_build_array(m.path)
+		  )
+		RETURNING m.path`
+	if err := r.db.SelectContext(ctx, &paths, query, now); err != nil {
+		return nil, fmt.Errorf("failed to archive superseded memories: %w", err)
+	}
+	return paths, nil
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// whereClause accumulates SQL conditions together with their positional arguments
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition; "?" in cond is replaced by the placeholder of arg
+func (w *whereClause) add(cond string, arg interface{}) {
+	w.args = append(w.args, arg)
+	placeholder := fmt.Sprintf("$%d", len(w.args))
+	w.conditions = append(w.conditions, strings.ReplaceAll(cond, "?", placeholder))
+}
+
+// String returns the WHERE clause, or an empty string if there are no conditions
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// escapeLike escapes the LIKE wildcards in s so it can be used as a literal prefix
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// nullInt stores zero as NULL
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// maxUpsertRows bounds the rows of a single multi-row INSERT so that its
+// parameters stay well below the PostgreSQL limit of 65535
+const maxUpsertRows = 1000
+
+// valuesList returns the placeholders of a multi-row VALUES clause, e.g. ($1, $2), ($3, $4)
+func valuesList(rows, columns int) string {
+	var b strings.Builder
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := 0; j < columns; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", i*columns+j+1)
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// marshalTags converts tags to JSONB, always as an array and never null
+func marshalTags(tags []string) ([]byte, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	return json.Marshal(tags)
+}
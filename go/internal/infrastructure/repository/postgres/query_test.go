@@ -0,0 +1,20 @@
+package postgres
+
+import "testing"
+
+func TestValuesList(t *testing.T) {
+	tests := []struct {
+		rows, columns int
+		want          string
+	}{
+		{1, 1, "($1)"},
+		{1, 3, "($1, $2, $3)"},
+		{3, 2, "($1, $2), ($3, $4), ($5, $6)"},
+	}
+
+	for _, tt := range tests {
+		if got := valuesList(tt.rows, tt.columns); got != tt.want {
+			t.Errorf("valuesList(%d, %d) = %q, want %q", tt.rows, tt.columns, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
@@ -23,19 +25,52 @@ func NewStoreRepository(db *sqlx.DB) repo.StoreRepository {
 	return &storeRepository{db: db}
 }
 
-// GetStore retrieves a store by ID
-func (r *storeRepository) GetStore(storeID model.StoreId) (model.DocumentStore, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// storeRow is the database representation of a store
+type storeRow struct {
+	ID             uint       `db:"id"`
+	Type           string     `db:"type"`
+	Repo           string     `db:"repo"`
+	Ref            string     `db:"ref"`
+	Filters        []byte     `db:"filters"`
+	LastSyncedAt   *time.Time `db:"last_synced_at"`
+	SyncedRevision string     `db:"synced_revision"`
+}
 
-	var store struct {
-		ID   uint   `db:"id"`
-		Type string `db:"type"`
-		Repo string `db:"repo"`
+const storeColumns = `id, type, COALESCE(repo, '') AS repo, ref, filters, last_synced_at, synced_revision`
+
+// toModel converts a database row into a domain store
+func (row *storeRow) toModel() (model.DocumentStore, error) {
+	var filters model.StoreFilters
+	if len(row.Filters) > 0 {
+		if err := json.Unmarshal(row.Filters, &filters); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal filters of store %d: %w", row.ID, err)
+		}
 	}
 
-	query := `SELECT id, type, repo FROM stores WHERE id = $1`
-	err := r.db.GetContext(ctx, &store, query, storeID)
+	switch row.Type {
+	case model.StoreTypeGitHub:
+		store := model.NewGitHubStore(model.StoreId(row.ID), row.Repo)
+		store.SetRef(row.Ref)
+		store.SetFilters(filters)
+		store.SetLastSyncedAt(row.LastSyncedAt)
+		return store, nil
+	case model.StoreTypeGit:
+		store := model.NewGitStore(model.StoreId(row.ID), row.Repo)
+		store.SetRef(row.Ref)
+		store.SetFilters(filters)
+		store.SetLastSyncedAt(row.LastSyncedAt)
+		store.SetSyncedRevision(row.SyncedRevision)
+		return store, nil
+	default:
+		return nil, model.ErrUnsupportedStoreType
+	}
+}
+
+// GetStore retrieves a store by ID
+func (r *storeRepository) GetStore(ctx context.Context, storeID model.StoreId) (model.DocumentStore, error) {
+	var row storeRow
+	query := `SELECT ` + storeColumns + ` FROM stores WHERE id = $1`
+	err := r.db.GetContext(ctx, &row, query, storeID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, repo.ErrStoreNotFound
@@ -43,29 +78,146 @@ func (r *storeRepository) GetStore(storeID model.StoreId) (model.DocumentStore,
 		return nil, err
 	}
 
-	switch store.Type {
-	case model.StoreTypeGitHub:
-		return model.NewGitHubStore(model.StoreId(store.ID), store.Repo), nil
-	default:
-		return nil, model.ErrUnsupportedStoreType
+	return row.toModel()
+}
+
+// ListStores retrieves all stores ordered by ID
+func (r *storeRepository) ListStores(ctx context.Context) ([]model.DocumentStore, error) {
+	var rows []storeRow
+	query := `SELECT ` + storeColumns + ` FROM stores ORDER BY id`
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to query stores: %w", err)
+	}
+
+	stores := make([]model.DocumentStore, 0, len(rows))
+	for i := range rows {
+		store, err := rows[i].toModel()
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
 	}
+	return stores, nil
 }
 
 // CreateStore creates a new store
-func (r *storeRepository) CreateStore(store model.DocumentStore) (model.DocumentStore, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (r *storeRepository) CreateStore(ctx context.Context, store model.DocumentStore) (model.DocumentStore, error) {
+	filtersJSON, err := json.Marshal(store.Filters())
+	if err != nil {
+		return nil, err
+	}
 
 	switch s := store.(type) {
 	case *model.GitHubStore:
 		var id uint
-		query := `INSERT INTO stores (type, repo) VALUES ($1, $2) RETURNING id`
-		err := r.db.QueryRowContext(ctx, query, store.Type(), s.Repo()).Scan(&id)
+		query := `INSERT INTO stores (type, repo, ref, filters) VALUES ($1, $2, $3, $4) RETURNING id`
+		err := r.db.QueryRowContext(ctx, query, store.Type(), s.Repo(), s.Ref(), filtersJSON).Scan(&id)
 		if err != nil {
 			return nil, err
 		}
-		return model.NewGitHubStore(model.StoreId(id), s.Repo()), nil
+		created := model.NewGitHubStore(model.StoreId(id), s.Repo())
+		created.SetRef(s.Ref())
+		created.SetFilters(s.Filters())
+		return created, nil
+	case *model.GitStore:
+		var id uint
+		query := `INSERT INTO stores (type, repo, ref, filters) VALUES ($1, $2, $3, $4) RETURNING id`
+		err := r.db.QueryRowContext(ctx, query, store.Type(), s.URL(), s.Ref(), filtersJSON).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		created := model.NewGitStore(model.StoreId(id), s.URL())
+		created.SetRef(s.Ref())
+		created.SetFilters(s.Filters())
+		return created, nil
 	default:
 		return nil, model.ErrUnsupportedStoreType
 	}
 }
+
+// UpdateStore persists the settings of an existing store
+func (r *storeRepository) UpdateStore(ctx context.Context, store model.DocumentStore) error {
+	filtersJSON, err := json.Marshal(store.Filters())
+	if err != nil {
+		return err
+	}
+
+	var result sql.Result
+	switch s := store.(type) {
+	case *model.GitHubStore:
+		query := `UPDATE stores SET repo = $1, ref = $2, filters = $3 WHERE id = $4`
+		result, err = r.db.ExecContext(ctx, query, s.Repo(), s.Ref(), filtersJSON, s.ID())
+	case *model.GitStore:
+		query := `UPDATE stores SET repo = $1, ref = $2, filters = $3, synced_revision = $4 WHERE id = $5`
+		result, err = r.db.ExecContext(ctx, query, s.URL(), s.Ref(), filtersJSON, s.SyncedRevision(), s.ID())
+	default:
+		return model.ErrUnsupportedStoreType
+	}
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, repo.ErrStoreNotFound)
+}
+
+// DeleteStore deletes a store; its documents are removed by the ON DELETE CASCADE constraint
+func (r *storeRepository) DeleteStore(ctx context.Context, storeID model.StoreId) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM stores WHERE id = $1`, storeID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, repo.ErrStoreNotFound)
+}
+
+// GetStoreStats returns document statistics for a store
+func (r *storeRepository) GetStoreStats(ctx context.Context, storeID model.StoreId) (*model.StoreStats, error) {
+	var row struct {
+		DocumentCount     int        `db:"document_count"`
+		EmbeddedCount     int        `db:"embedded_count"`
+		LastModifiedAt    *time.Time `db:"last_modified_at"`
+		LastDocumentSaved *time.Time `db:"last_document_saved"`
+	}
+	query := `
+		SELECT
+			COUNT(*) AS document_count,
+			COUNT(embedding) AS embedded_count,
+			MAX(modified_at) AS last_modified_at,
+			MAX(updated_at) AS last_document_saved
+		FROM documents
+		WHERE store_id = $1
+	`
+	if err := r.db.GetContext(ctx, &row, query, storeID); err != nil {
+		return nil, fmt.Errorf("failed to query store stats: %w", err)
+	}
+
+	return &model.StoreStats{
+		DocumentCount:     row.DocumentCount,
+		EmbeddedCount:     row.EmbeddedCount,
+		LastModifiedAt:    row.LastModifiedAt,
+		LastDocumentSaved: row.LastDocumentSaved,
+	}, nil
+}
+
+// MarkSynced records the time and the source revision of the last successful sync of a store
+func (r *storeRepository) MarkSynced(ctx context.Context, storeID model.StoreId, syncedAt time.Time, revision string) error {
+	query := `UPDATE stores SET last_synced_at = $1, synced_revision = $2 WHERE id = $3`
+	result, err := r.db.ExecContext(ctx, query, syncedAt, revision, storeID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, repo.ErrStoreNotFound)
+}
+
+// expectAffected returns notFound when the statement did not touch any row
+func expectAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	repo "github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// Ensure usageRepository implements repo.UsageRepository
+var _ repo.UsageRepository = (*usageRepository)(nil)
+
+type usageRepository struct {
+	db *sqlx.DB
+}
+
+// NewUsageRepository creates a new PostgreSQL usage repository
+func NewUsageRepository(db *sqlx.DB) repo.UsageRepository {
+	return &usageRepository{db: db}
+}
+
+// RecordUsage stores the embedding usage of a sync run
+func (r *usageRepository) RecordUsage(ctx context.Context, record *model.UsageRecord) error {
+	// Memory syncs are not tied to a store
+	storeID := sql.NullInt64{Int64: int64(record.StoreId), Valid: record.StoreId != 0}
+
+	query := `
+		INSERT INTO embedding_usage (source, store_id, model, requests, tokens, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		record.Source,
+		storeID,
+		record.Model,
+		record.Requests,
+		record.Tokens,
+		record.StartedAt,
+		record.FinishedAt,
+	)
+	return err
+}
+
+// SummarizeUsage aggregates the usage records by source, store and model
+func (r *usageRepository) SummarizeUsage(ctx context.Context, since time.Time) ([]model.UsageSummary, error) {
+	var rows []struct {
+		Source   string `db:"source"`
+		StoreID  int64  `db:"store_id"`
+		Model    string `db:"model"`
+		Runs     int    `db:"runs"`
+		Requests int    `db:"requests"`
+		Tokens   int    `db:"tokens"`
+	}
+	query := `
+		SELECT
+			source,
+			COALESCE(store_id, 0) AS store_id,
+			model,
+			COUNT(*) AS runs,
+			SUM(requests) AS requests,
+			SUM(tokens) AS tokens
+		FROM embedding_usage
+		WHERE started_at >= $1
+		GROUP BY source, COALESCE(store_id, 0), model
+		ORDER BY source, store_id, model
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, since); err != nil {
+		return nil, fmt.Errorf("failed to query embedding usage: %w", err)
+	}
+
+	summaries := make([]model.UsageSummary, 0, len(rows))
+	for _, row := range rows {
+		summaries = append(summaries, model.UsageSummary{
+			Source:   row.Source,
+			StoreId:  model.StoreId(row.StoreID),
+			Model:    row.Model,
+			Runs:     row.Runs,
+			Requests: row.Requests,
+			Tokens:   row.Tokens,
+		})
+	}
+	return summaries, nil
+}
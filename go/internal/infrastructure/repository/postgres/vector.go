@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// embeddingDimension is the dimension of the VECTOR columns
+const embeddingDimension = 1536
+
+// Ensure Vector implements the database/sql and binary encoding interfaces
+var (
+	_ driver.Valuer              = Vector(nil)
+	_ sql.Scanner                = (*Vector)(nil)
+	_ encoding.BinaryMarshaler   = Vector(nil)
+	_ encoding.BinaryUnmarshaler = (*Vector)(nil)
+)
+
+// Vector is a pgvector value with the float32 precision of the vector type.
+// A nil Vector is stored as NULL.
+//
+// lib/pq sends parameters and receives results in the text format, so Value writes
+// the text format and Scan reads both formats. MarshalBinary and UnmarshalBinary
+// implement the binary format of vector_send and vector_recv, used by binary COPY
+// and drivers that request binary results.
+type Vector []float32
+
+// newVector converts an embedding to a Vector after checking its dimension and values.
+// An empty embedding is returned as nil.
+func newVector(embedding []float64) (Vector, error) {
+	if len(embedding) == 0 {
+		return nil, nil
+	}
+	if len(embedding) != embeddingDimension {
+		return nil, fmt.Errorf("invalid embedding dimension: got %d, want %d", len(embedding), embeddingDimension)
+	}
+	v := make(Vector, len(embedding))
+	for i, f := range embedding {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("invalid embedding value at position %d: %v", i, f)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
+
+// Float64s returns the vector as an embedding; nil for a nil vector
+func (v Vector) Float64s() []float64 {
+	if v == nil {
+		return nil
+	}
+	embedding := make([]float64, len(v))
+	for i, f := range v {
+		embedding[i] = float64(f)
+	}
+	return embedding
+}
+
+// Value implements driver.Valuer. Every element is written in the shortest form
+// that parses back to the same float32.
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	buf := make([]byte, 0, 2+len(v)*12)
+	buf = append(buf, '[')
+	for i, f := range v {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, float64(f), 'g', -1, 32)
+	}
+	buf = append(buf, ']')
+	return string(buf), nil
+}
+
+// Scan implements sql.Scanner for the text and binary formats
+func (v *Vector) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case string:
+		return v.parseText([]byte(src))
+	case []byte:
+		// The text format starts with '['; the binary format starts with the dimension,
+		// whose first byte is never '[' within the 16000 dimensions supported by pgvector
+		if len(src) > 0 && src[0] == '[' {
+			return v.parseText(src)
+		}
+		return v.UnmarshalBinary(src)
+	default:
+		return fmt.Errorf("cannot scan %T into Vector", src)
+	}
+}
+
+// parseText parses the text format, e.g. [1,2.5,-3e-05]
+func (v *Vector) parseText(text []byte) error {
+	if len(text) < 2 || text[0] != '[' || text[len(text)-1] != ']' {
+		return fmt.Errorf("invalid vector %q", text)
+	}
+	text = text[1 : len(text)-1]
+	if len(bytes.TrimSpace(text)) == 0 {
+		*v = nil
+		return nil
+	}
+
+	parsed := make(Vector, 0, bytes.Count(text, []byte{','})+1)
+	for len(text) > 0 {
+		element := text
+		if i := bytes.IndexByte(text, ','); i >= 0 {
+			element, text = text[:i], text[i+1:]
+		} else {
+			text = nil
+		}
+		f, err := strconv.ParseFloat(string(bytes.TrimSpace(element)), 32)
+		if err != nil {
+			return fmt.Errorf("failed to parse vector element %d: %w", len(parsed), err)
+		}
+		parsed = append(parsed, float32(f))
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler: the dimension and an unused
+// field as int16, followed by the elements as float32, all in network byte order
+func (v Vector) MarshalBinary() ([]byte, error) {
+	if len(v) > math.MaxInt16 {
+		return nil, fmt.Errorf("vector has %d dimensions, at most %d are supported", len(v), math.MaxInt16)
+	}
+	data := make([]byte, 4+4*len(v))
+	binary.BigEndian.PutUint16(data[0:], uint16(len(v)))
+	for i, f := range v {
+		binary.BigEndian.PutUint32(data[4+4*i:], math.Float32bits(f))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler for the format of MarshalBinary
+func (v *Vector) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return errors.New("invalid binary vector: missing header")
+	}
+	dim := int(binary.BigEndian.Uint16(data[0:]))
+	if len(data) != 4+4*dim {
+		return fmt.Errorf("invalid binary vector: got %d bytes for %d dimensions", len(data), dim)
+	}
+
+	parsed := make(Vector, dim)
+	for i := range parsed {
+		parsed[i] = math.Float32frombits(binary.BigEndian.Uint32(data[4+4*i:]))
+	}
+	*v = parsed
+	return nil
+}
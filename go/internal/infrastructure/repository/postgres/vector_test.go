@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestVectorTextRoundTrip(t *testing.T) {
+	tests := []Vector{
+		{1, 2.5, -3},
+		{0.0123456789, -1e-8, 3.4028235e38, math.SmallestNonzeroFloat32},
+		{-0.0026040673, 0.019214123, 1},
+	}
+
+	for _, want := range tests {
+		value, err := want.Value()
+		if err != nil {
+			t.Fatalf("Value(%v): %v", want, err)
+		}
+
+		var got Vector
+		if err := got.Scan([]byte(value.(string))); err != nil {
+			t.Fatalf("Scan(%q): %v", value, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("text round trip of %v via %q = %v", want, value, got)
+		}
+	}
+}
+
+func TestVectorBinaryRoundTrip(t *testing.T) {
+	want := Vector{1, -0.5, 0.0123456789, float32(math.Inf(1))}
+
+	data, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	if len(data) != 4+4*len(want) || data[0] != 0 || data[1] != byte(len(want)) {
+		t.Fatalf("unexpected binary header % x", data[:4])
+	}
+
+	var got Vector
+	if err := got.Scan(data); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("binary round trip of %v = %v", want, got)
+	}
+}
+
+func TestVectorScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Vector
+		wantErr bool
+	}{
+		{"null", nil, nil, false},
+		{"string", "[1, 2,3]", Vector{1, 2, 3}, false},
+		{"empty", "[]", nil, false},
+		{"unbracketed", "1,2,3", nil, true},
+		{"bad element", "[1,x]", nil, true},
+		{"short binary", []byte{0, 2, 0, 0, 0}, nil, true},
+		{"unsupported type", 42, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := Vector{9}
+			err := v.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(v, tt.want) {
+				t.Errorf("got %v, want %v", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVector(t *testing.T) {
+	embedding := make([]float64, embeddingDimension)
+	embedding[0] = 0.1
+
+	v, err := newVector(embedding)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := v.Float64s()[0]; got != float64(float32(0.1)) {
+		t.Errorf("got %v, want the float32 value of 0.1", got)
+	}
+	if value, _ := Vector(nil).Value(); value != nil {
+		t.Errorf("nil vector stored as %v, want NULL", value)
+	}
+
+	if _, err := newVector(embedding[:3]); err == nil {
+		t.Error("expected an error for a wrong dimension")
+	}
+	embedding[1] = math.NaN()
+	if _, err := newVector(embedding); err == nil {
+		t.Error("expected an error for NaN")
+	}
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Limits of the extracted repository archives
+const (
+	DefaultMaxArchiveFileSize  = 32 << 20 // Files larger than this are skipped
+	DefaultMaxArchiveTotalSize = 4 << 30  // Extraction fails when the files exceed this in total
+)
+
+var (
+	// ErrUnsafeArchiveEntry is returned for archive entries that would be written outside the destination
+	ErrUnsafeArchiveEntry = errors.New("unsafe archive entry")
+	// ErrArchiveTooLarge is returned when the extracted files exceed the total size limit
+	ErrArchiveTooLarge = errors.New("archive too large")
+)
+
+// extractOptions controls extractTarGz
+type extractOptions struct {
+	StripComponents int               // Number of leading path components removed from every entry
+	MaxFileSize     int64             // Regular files larger than this are skipped
+	MaxTotalSize    int64             // Extraction fails when the regular files exceed this in total
+	Skip            func(string) bool // Reports whether the file at a slash separated path is not needed; may be nil
+}
+
+// extractTarGz streams the gzip compressed tar archive from r into dest. Entries with absolute
+// paths or paths leaving dest fail the extraction; symlinks, hard links and special files are
+// skipped, so that nothing outside dest can be read through the extracted tree.
+// The extraction stops when ctx is done.
+func extractTarGz(ctx context.Context, r io.Reader, dest string, opts extractOptions) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("error opening gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+
+		name, err := archiveEntryPath(header.Name, opts.StripComponents)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("error creating directory %s: %w", name, err)
+			}
+		case tar.TypeReg:
+			if opts.Skip != nil && opts.Skip(name) {
+				continue
+			}
+			if header.Size > opts.MaxFileSize {
+				log.Printf("skipping %s: %d bytes exceeds the limit of %d bytes", name, header.Size, opts.MaxFileSize)
+				continue
+			}
+			total += header.Size
+			if total > opts.MaxTotalSize {
+				return fmt.Errorf("%w: files exceed %d bytes", ErrArchiveTooLarge, opts.MaxTotalSize)
+			}
+			if err := writeArchiveFile(tr, target, header.Size); err != nil {
+				return fmt.Errorf("error extracting %s: %w", name, err)
+			}
+		case tar.TypeXGlobalHeader:
+			// Holds the commit ID of GitHub archives
+		default:
+			log.Printf("skipping %s: unsupported entry type %q", name, header.Typeflag)
+		}
+	}
+}
+
+// archiveEntryPath returns the cleaned slash separated path of an entry without its first strip
+// components; it is empty for entries above the stripped directory
+func archiveEntryPath(name string, strip int) (string, error) {
+	if path.IsAbs(name) || strings.HasPrefix(name, `\`) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: absolute path %q", ErrUnsafeArchiveEntry, name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %q leaves the destination", ErrUnsafeArchiveEntry, name)
+		}
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", nil
+	}
+	segments := strings.Split(strings.Trim(cleaned, "/"), "/")
+	if len(segments) <= strip {
+		return "", nil
+	}
+	return strings.Join(segments[strip:], "/"), nil
+}
+
+// writeArchiveFile writes the next size bytes of r to a new file at target
+func writeArchiveFile(r io.Reader, target string, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, r, size); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
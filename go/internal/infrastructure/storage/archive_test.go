@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// archiveEntry is an entry of a test archive; a non-empty link makes it a symlink
+type archiveEntry struct {
+	name    string
+	content string
+	link    string
+}
+
+func tarGz(t *testing.T, entries []archiveEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.content))}
+		switch {
+		case e.link != "":
+			header.Typeflag, header.Linkname, header.Size = tar.TypeSymlink, e.link, 0
+		case strings.HasSuffix(e.name, "/"):
+			header.Typeflag, header.Mode = tar.TypeDir, 0o755
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			tw.Write([]byte(e.content))
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return &buf
+}
+
+func TestExtractTarGz(t *testing.T) {
+	opts := extractOptions{
+		StripComponents: 1,
+		MaxFileSize:     8,
+		MaxTotalSize:    16,
+		Skip:            func(p string) bool { return strings.HasPrefix(p, "archive/") },
+	}
+
+	tests := []struct {
+		name      string
+		entries   []archiveEntry
+		wantFiles []string
+		wantErr   error
+	}{
+		{
+			name: "skips links, large and excluded files",
+			entries: []archiveEntry{
+				{name: "owner-repo-abc123/"},
+				{name: "owner-repo-abc123/notes/a.md", content: "# A"},
+				{name: "owner-repo-abc123/passwd", link: "/etc/passwd"},
+				{name: "owner-repo-abc123/big.bin", content: "0123456789"},
+				{name: "owner-repo-abc123/archive/old.md", content: "# Old"},
+				{name: "owner-repo-abc123/b.md", content: "# B"},
+			},
+			wantFiles: []string{"b.md", "notes/a.md"},
+		},
+		{
+			name:    "rejects parent directory",
+			entries: []archiveEntry{{name: "owner-repo-abc123/../../evil.md", content: "x"}},
+			wantErr: ErrUnsafeArchiveEntry,
+		},
+		{
+			name:    "rejects absolute path",
+			entries: []archiveEntry{{name: "/tmp/evil.md", content: "x"}},
+			wantErr: ErrUnsafeArchiveEntry,
+		},
+		{
+			name: "enforces total size",
+			entries: []archiveEntry{
+				{name: "owner-repo-abc123/a.md", content: "12345678"},
+				{name: "owner-repo-abc123/b.md", content: "12345678"},
+				{name: "owner-repo-abc123/c.md", content: "12345678"},
+			},
+			wantErr: ErrArchiveTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := extractTarGz(context.Background(), tarGz(t, tt.entries), dest, opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var files []string
+			filepath.WalkDir(dest, func(p string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dest, p)
+					files = append(files, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			sort.Strings(files)
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("got files %v, want %v", files, tt.wantFiles)
+			}
+		})
+	}
+}
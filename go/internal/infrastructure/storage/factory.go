@@ -8,12 +8,17 @@ import (
 // StorageFactoryProvider provides the appropriate storage factory based on store type
 type StorageFactoryProvider struct {
 	githubFactory *GitHubStorageFactory
+	gitFactory    *GitStorageFactory
 }
 
-// NewStorageFactoryProvider creates a new storage factory provider
-func NewStorageFactoryProvider() *StorageFactoryProvider {
+// NewStorageFactoryProvider creates a new storage factory provider.
+// Git stores keep their mirrors below gitMirrorDir, or DefaultGitMirrorDir if it is empty,
+// and GitHub stores read their repositories from snapshots, including their submodules when
+// submodules is set.
+func NewStorageFactoryProvider(gitMirrorDir string, snapshots *SnapshotCache, submodules bool) *StorageFactoryProvider {
 	return &StorageFactoryProvider{
-		githubFactory: NewGitHubStorageFactory(),
+		githubFactory: NewGitHubStorageFactory(snapshots, submodules),
+		gitFactory:    NewGitStorageFactory(gitMirrorDir),
 	}
 }
 
@@ -22,6 +27,8 @@ func (p *StorageFactoryProvider) GetFactory(storeType string) (port.StorageFacto
 	switch storeType {
 	case model.StoreTypeGitHub:
 		return p.githubFactory, nil
+	case model.StoreTypeGit:
+		return p.gitFactory, nil
 	default:
 		return nil, model.ErrUnsupportedStoreType
 	}
@@ -32,17 +39,27 @@ var _ port.MemoryStorageFactory = (*MemoryStorageFactory)(nil)
 
 // MemoryStorageFactory implements the MemoryStorageFactory interface for memory
 type MemoryStorageFactory struct {
-	repo string
+	repo      string
+	snapshots *SnapshotCache
 }
 
-// NewMemoryStorageFactory creates a new memory storage factory
-func NewMemoryStorageFactory(repo string) *MemoryStorageFactory {
+// NewMemoryStorageFactory creates a new memory storage factory reading the repository from
+// snapshots; a nil snapshots downloads it into temporary workspaces
+func NewMemoryStorageFactory(repo string, snapshots *SnapshotCache) *MemoryStorageFactory {
 	return &MemoryStorageFactory{
-		repo: repo,
+		repo:      repo,
+		snapshots: snapshots,
 	}
 }
 
 // CreateMemoryStorage creates a new memory storage instance
 func (f *MemoryStorageFactory) CreateMemoryStorage() (port.Storage, error) {
-	return NewGitHubStorage(f.repo)
+	storage, err := NewGitHubStorage(f.repo, "")
+	if err != nil {
+		return nil, err
+	}
+	if f.snapshots != nil {
+		storage.WithCache(f.snapshots)
+	}
+	return storage, nil
 }
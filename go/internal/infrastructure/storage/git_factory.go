@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	port "github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// GitStorageFactory implements the StorageFactory interface for git remotes
+type GitStorageFactory struct {
+	mirrorRoot string // Directory of the local mirrors
+}
+
+// NewGitStorageFactory creates a git storage factory that keeps mirrors below mirrorRoot;
+// an empty mirrorRoot means DefaultGitMirrorDir
+func NewGitStorageFactory(mirrorRoot string) *GitStorageFactory {
+	if mirrorRoot == "" {
+		mirrorRoot = DefaultGitMirrorDir()
+	}
+	return &GitStorageFactory{mirrorRoot: mirrorRoot}
+}
+
+// CreateStorage creates a new git storage instance
+func (f *GitStorageFactory) CreateStorage(store model.DocumentStore) (port.Storage, error) {
+	gitStore, ok := store.(*model.GitStore)
+	if !ok {
+		return nil, fmt.Errorf("unsupported store type: %s", store.Type())
+	}
+
+	return NewGitStorage(gitStore.URL(), gitStore.Ref(), f.mirrorRoot), nil
+}
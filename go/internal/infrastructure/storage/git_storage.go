@@ -0,0 +1,582 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	port "github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// Ensure GitStorage implements port.RevisionStorage and port.HistoryStorage
+var (
+	_ port.RevisionStorage = (*GitStorage)(nil)
+	_ port.HistoryStorage  = (*GitStorage)(nil)
+)
+
+// ErrReadOnlyStorage is returned when writing to a storage that can only be read
+var ErrReadOnlyStorage = errors.New("storage is read-only")
+
+// mirrorRef is the reference of the mirror that holds the fetched commit
+const mirrorRef = plumbing.ReferenceName("refs/mirror/head")
+
+// shallowDepth is the number of commits of the first fetch into a new mirror. The history is
+// deepened when the last commits of the files are needed.
+const shallowDepth = 1
+
+// fullDepth deepens a shallow mirror to the full history, as "git fetch --unshallow" does
+const fullDepth = math.MaxInt32
+
+// GitStorage implements the storage.Storage interface for any git remote.
+// The remote is fetched with a pure-Go git client into a bare mirror that is kept between
+// syncs, so that only new commits are transferred, and files are read from the fetched commit.
+type GitStorage struct {
+	url       string
+	ref       string // Branch or tag to fetch; empty means the default branch
+	auth      transport.AuthMethod
+	mirrorDir string // Path of the bare mirror repository
+	repo      *git.Repository
+	source    plumbing.ReferenceName  // Remote reference of the fetched commit
+	commit    *object.Commit          // Commit read by GetDocumentEntries
+	history   map[string]model.Commit // Last commit of each file of commit, computed on first use
+}
+
+// DefaultGitMirrorDir returns the directory of the git mirrors in the user cache directory
+func DefaultGitMirrorDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "personal-agent", "git")
+}
+
+// NewGitStorage creates a git storage for the ref of the remote, mirrored below mirrorRoot
+func NewGitStorage(url, ref, mirrorRoot string) *GitStorage {
+	return &GitStorage{
+		url:       url,
+		ref:       ref,
+		auth:      gitAuth(url),
+		mirrorDir: filepath.Join(mirrorRoot, mirrorName(url, ref)),
+	}
+}
+
+// gitAuth returns the credentials of HTTP remotes set in GIT_USERNAME and GIT_PASSWORD (a password
+// or access token), so that they need not be stored in the URL. Credentials in the URL take
+// precedence, and SSH remotes authenticate with the SSH agent.
+func gitAuth(remote string) transport.AuthMethod {
+	password := os.Getenv("GIT_PASSWORD")
+	if password == "" {
+		return nil
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.User != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	username := os.Getenv("GIT_USERNAME")
+	if username == "" {
+		username = "git" // Ignored by hosts that authenticate with the token alone
+	}
+	return &githttp.BasicAuth{Username: username, Password: password}
+}
+
+// mirrorName returns the directory name of the mirror of a remote and ref
+func mirrorName(url, ref string) string {
+	sum := sha256.Sum256([]byte(url + "\x00" + ref))
+	return hex.EncodeToString(sum[:16])
+}
+
+// fetch updates the mirror with the history of the ref and reads the fetched commit.
+// The mirror is created on first use with a shallow fetch; later fetches only transfer the new objects.
+func (s *GitStorage) fetch(ctx context.Context) error {
+	start := time.Now()
+	defer logDuration(start, "git fetch")
+
+	repo, err := s.openMirror()
+	if err != nil {
+		return err
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("error opening remote of mirror %s: %w", s.mirrorDir, err)
+	}
+
+	source, _, err := resolveRemoteRef(ctx, remote, s.ref, s.auth)
+	if err != nil {
+		return err
+	}
+
+	depth := 0
+	if _, err := repo.Reference(mirrorRef, true); err != nil {
+		depth = shallowDepth
+	}
+	log.Printf("Fetching %s from %s into %s", source, model.RedactGitURL(s.url), s.mirrorDir)
+	if err := s.fetchRef(ctx, remote, source, depth); err != nil {
+		return err
+	}
+
+	ref, err := repo.Reference(mirrorRef, true)
+	if err != nil {
+		return fmt.Errorf("error reading fetched ref: %w", err)
+	}
+	commit, err := peelCommit(repo, ref.Hash())
+	if err != nil {
+		return fmt.Errorf("error reading fetched commit %s: %w", ref.Hash(), err)
+	}
+
+	s.repo = repo
+	s.source = source
+	s.commit = commit
+	s.history = nil
+	return nil
+}
+
+// fetchRef fetches the remote reference source into the mirror reference; a depth other than 0
+// limits the history to that many commits from the fetched commit
+func (s *GitStorage) fetchRef(ctx context.Context, remote *git.Remote, source plumbing.ReferenceName, depth int) error {
+	err := remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", source, mirrorRef))},
+		Depth:    depth,
+		Auth:     s.auth,
+		Tags:     git.NoTags,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("error fetching %s: %w", model.RedactGitURL(s.url), err)
+	}
+	return nil
+}
+
+// shallow reports whether the history of the mirror is incomplete
+func (s *GitStorage) shallow() bool {
+	commits, err := s.repo.Storer.Shallow()
+	return err == nil && len(commits) > 0
+}
+
+// deepen fetches the history of the fetched commit up to depth commits, beyond the boundary of a
+// shallow mirror
+func (s *GitStorage) deepen(ctx context.Context, depth int) error {
+	remote, err := s.repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("error opening remote of mirror %s: %w", s.mirrorDir, err)
+	}
+	log.Printf("Deepening the history of %s to %d commits", model.RedactGitURL(s.url), depth)
+	return s.fetchRef(ctx, remote, s.source, depth)
+}
+
+// openMirror opens the bare mirror, creating it with the remote as origin if it does not exist
+func (s *GitStorage) openMirror() (*git.Repository, error) {
+	repo, err := git.PlainOpen(s.mirrorDir)
+	if err == nil {
+		return repo, nil
+	}
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("error opening mirror %s: %w", s.mirrorDir, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.mirrorDir), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating mirror directory: %w", err)
+	}
+	repo, err = git.PlainInit(s.mirrorDir, true)
+	if err != nil {
+		return nil, fmt.Errorf("error creating mirror %s: %w", s.mirrorDir, err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{s.url}})
+	if err != nil {
+		os.RemoveAll(s.mirrorDir) // Clean up so that the next fetch creates the mirror again
+		return nil, fmt.Errorf("error configuring mirror %s: %w", s.mirrorDir, err)
+	}
+	return repo, nil
+}
+
+// resolveRemoteRef returns the name of the remote reference to fetch for ref: the branch or tag
+// with that name, or the branch HEAD points at when ref is empty. It also returns the default branch.
+func resolveRemoteRef(ctx context.Context, remote *git.Remote, ref string, auth transport.AuthMethod) (source plumbing.ReferenceName, defaultBranch string, err error) {
+	url := model.RedactGitURL(remote.Config().URLs[0])
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		if errors.Is(err, transport.ErrRepositoryNotFound) || errors.Is(err, transport.ErrAuthenticationRequired) ||
+			errors.Is(err, transport.ErrAuthorizationFailed) {
+			return "", "", fmt.Errorf("%w: %s", port.ErrRepositoryNotFound, url)
+		}
+		return "", "", fmt.Errorf("error listing references of %s: %w", url, err)
+	}
+
+	names := make(map[plumbing.ReferenceName]bool, len(refs))
+	for _, r := range refs {
+		names[r.Name()] = true
+		if r.Name() == plumbing.HEAD && r.Type() == plumbing.SymbolicReference {
+			defaultBranch = r.Target().Short()
+		}
+	}
+
+	if ref == "" {
+		if defaultBranch == "" {
+			return plumbing.HEAD, "", nil
+		}
+		return plumbing.NewBranchReferenceName(defaultBranch), defaultBranch, nil
+	}
+	for _, candidate := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(ref),
+		plumbing.NewTagReferenceName(ref),
+		plumbing.ReferenceName(ref),
+	} {
+		if names[candidate] {
+			return candidate, defaultBranch, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: %s in %s", port.ErrRefNotFound, ref, url)
+}
+
+// peelCommit returns the commit of hash, following an annotated tag
+func peelCommit(repo *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	if tag, err := repo.TagObject(hash); err == nil {
+		return tag.Commit()
+	}
+	return repo.CommitObject(hash)
+}
+
+// ensureFetched fetches the remote unless it was fetched by this storage already
+func (s *GitStorage) ensureFetched(ctx context.Context) error {
+	if s.commit != nil {
+		return nil
+	}
+	return s.fetch(ctx)
+}
+
+// Revision implements the RevisionStorage interface
+func (s *GitStorage) Revision() string {
+	if s.commit == nil {
+		return ""
+	}
+	return s.commit.Hash.String()
+}
+
+// ChangedPaths implements the RevisionStorage interface by diffing the trees of the two commits.
+// A shallow mirror that lacks since, e.g. because the mirror was deleted, fetches the full history
+// first; it fails if since is still not found.
+func (s *GitStorage) ChangedPaths(ctx context.Context, since string) ([]string, error) {
+	if err := s.ensureFetched(ctx); err != nil {
+		return nil, err
+	}
+	if !plumbing.IsHash(since) {
+		return nil, fmt.Errorf("invalid revision %q", since)
+	}
+	if since == s.Revision() {
+		return nil, nil
+	}
+
+	previous, err := s.repo.CommitObject(plumbing.NewHash(since))
+	if errors.Is(err, plumbing.ErrObjectNotFound) && s.shallow() {
+		if err := s.deepen(ctx, fullDepth); err != nil {
+			return nil, err
+		}
+		previous, err = s.repo.CommitObject(plumbing.NewHash(since))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %s: %w", since, err)
+	}
+	from, err := previous.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of %s: %w", since, err)
+	}
+	to, err := s.commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of %s: %w", s.Revision(), err)
+	}
+
+	changes, err := object.DiffTreeContext(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error comparing %s with %s: %w", since, s.Revision(), err)
+	}
+	seen := make(map[string]bool, len(changes))
+	var paths []string
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				paths = append(paths, name)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// LastCommits implements the HistoryStorage interface. The history of the mirror is walked once
+// per fetch for all files, deepening a shallow mirror until the last commit of every file is found.
+func (s *GitStorage) LastCommits(ctx context.Context, paths []string) (map[string]model.Commit, error) {
+	if err := s.ensureFetched(ctx); err != nil {
+		return nil, err
+	}
+	if s.history == nil {
+		history, err := lastCommits(ctx, s.repo, s.commit, s.deepen)
+		if err != nil {
+			return nil, err
+		}
+		s.history = history
+	}
+	found := make(map[string]model.Commit, len(paths))
+	for _, p := range paths {
+		if commit, ok := s.history[p]; ok {
+			found[p] = commit
+		}
+	}
+	return found, nil
+}
+
+// lastCommits walks the first-parent history from head and returns the last commit that changed
+// each file of its tree. Changes merged from other branches are attributed to the merge commit.
+// At the boundary of a shallow mirror, deepen is called with twice the number of commits walked;
+// files older than the history it fetches are attributed to the oldest commit.
+func lastCommits(ctx context.Context, repo *git.Repository, head *object.Commit, deepen func(ctx context.Context, depth int) error) (map[string]model.Commit, error) {
+	tree, err := head.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of %s: %w", head.Hash, err)
+	}
+	pending := make(map[string]bool)
+	err = tree.Files().ForEach(func(f *object.File) error {
+		pending[f.Name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing files of %s: %w", head.Hash, err)
+	}
+
+	history := make(map[string]model.Commit, len(pending))
+	for commit, walked := head, 1; len(pending) > 0; walked++ {
+		parent, err := firstParent(repo, commit)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil && len(commit.ParentHashes) > 0 && deepen != nil {
+			if err := deepen(ctx, 2*walked); err != nil {
+				return nil, err
+			}
+			if parent, err = firstParent(repo, commit); err != nil {
+				return nil, err
+			}
+		}
+		if parent == nil {
+			for path := range pending {
+				history[path] = newCommit(commit)
+			}
+			break
+		}
+
+		parentTree, err := parent.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("error reading tree of %s: %w", parent.Hash, err)
+		}
+		changes, err := object.DiffTreeContext(ctx, parentTree, tree)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing %s with its parent: %w", commit.Hash, err)
+		}
+		for _, change := range changes {
+			if pending[change.To.Name] {
+				history[change.To.Name] = newCommit(commit)
+				delete(pending, change.To.Name)
+			}
+		}
+		commit, tree = parent, parentTree
+	}
+	return history, nil
+}
+
+// firstParent returns the first parent of commit, or nil for the first commit and the oldest
+// commit of a shallow mirror
+func firstParent(repo *git.Repository, commit *object.Commit) (*object.Commit, error) {
+	if len(commit.ParentHashes) == 0 {
+		return nil, nil
+	}
+	parent, err := repo.CommitObject(commit.ParentHashes[0])
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading parent of %s: %w", commit.Hash, err)
+	}
+	return parent, nil
+}
+
+// newCommit converts a git commit into its domain representation
+func newCommit(commit *object.Commit) model.Commit {
+	return model.Commit{
+		SHA:     commit.Hash.String(),
+		Author:  commit.Author.Name,
+		Message: model.CommitSubject(commit.Message),
+		Time:    commit.Committer.When,
+	}
+}
+
+// readFile reads a file of the fetched commit and decodes it into UTF-8 from the detected encoding
+func (s *GitStorage) readFile(ctx context.Context, path string) (content, encoding string, err error) {
+	if err := s.ensureFetched(ctx); err != nil {
+		return "", "", err
+	}
+	file, err := s.commit.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", "", fmt.Errorf("%s: %w", path, fs.ErrNotExist)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return "", "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return decodeFile(path, data)
+}
+
+// files returns the regular files of the fetched commit below dir; an empty dir means all files
+func (s *GitStorage) files(ctx context.Context, dir string) ([]string, error) {
+	if err := s.ensureFetched(ctx); err != nil {
+		return nil, err
+	}
+	tree, err := s.commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of %s: %w", s.Revision(), err)
+	}
+
+	var paths []string
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if f.Mode == filemode.Regular || f.Mode == filemode.Executable {
+			if dir == "" || strings.HasPrefix(f.Name, dir+"/") {
+				paths = append(paths, f.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing files of %s: %w", s.Revision(), err)
+	}
+	return paths, nil
+}
+
+// Close implements the Storage interface. The mirror is kept for the next fetch.
+func (s *GitStorage) Close() error {
+	if s.repo == nil {
+		return nil
+	}
+	closer, ok := s.repo.Storer.(io.Closer)
+	s.repo, s.commit, s.history = nil, nil, nil
+	if ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// SaveDocument implements the Storage interface; git stores are read-only
+func (s *GitStorage) SaveDocument(ctx context.Context, document *model.Document) error {
+	return fmt.Errorf("%w: %s", ErrReadOnlyStorage, model.RedactGitURL(s.url))
+}
+
+// SaveMemory implements the Storage interface; git stores are read-only
+func (s *GitStorage) SaveMemory(ctx context.Context, memory *model.Memory) error {
+	return fmt.Errorf("%w: %s", ErrReadOnlyStorage, model.RedactGitURL(s.url))
+}
+
+// DeleteMemory implements the Storage interface; git stores are read-only
+func (s *GitStorage) DeleteMemory(ctx context.Context, path string) error {
+	return fmt.Errorf("%w: %s", ErrReadOnlyStorage, model.RedactGitURL(s.url))
+}
+
+// FetchDocument implements the Storage interface. The modification time is the commit time.
+func (s *GitStorage) FetchDocument(ctx context.Context, storeId model.StoreId, path string) (*model.Document, error) {
+	content, encoding, err := s.readFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &model.Document{
+		Path:       path,
+		StoreId:    storeId,
+		Content:    content,
+		Encoding:   encoding,
+		ModifiedAt: s.commit.Committer.When,
+	}
+	document.SetContentHashes()
+	return document, nil
+}
+
+// FetchMemory implements the Storage interface
+func (s *GitStorage) FetchMemory(ctx context.Context, path string) (*model.Memory, error) {
+	content, _, err := s.readFile(ctx, memoryFilePath(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, port.ErrMemoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	modTime := s.commit.Committer.When
+	memory := &model.Memory{
+		Path:       strings.TrimSuffix(strings.TrimPrefix(memoryFilePath(path), ".memories/"), ".md"),
+		Content:    content,
+		ModifiedAt: modTime,
+		CreatedAt:  modTime,
+		UpdatedAt:  modTime,
+	}
+	memory.SetContentHashes()
+	return memory, nil
+}
+
+// GetDocumentEntries implements the Storage interface. It fetches the remote, so that
+// Revision returns the commit the entries were read from.
+func (s *GitStorage) GetDocumentEntries(ctx context.Context) ([]model.DocumentEntry, error) {
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	paths, err := s.files(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.DocumentEntry, len(paths))
+	for i, path := range paths {
+		entries[i] = model.DocumentEntry{Path: path, ModifiedAt: s.commit.Committer.When}
+	}
+	log.Printf("Found %d document entries at %s", len(entries), s.Revision())
+	return entries, nil
+}
+
+// GetMemoryEntries implements the Storage interface for the markdown files of the .memories directory,
+// with paths relative to that directory and without the .md extension
+func (s *GitStorage) GetMemoryEntries(ctx context.Context) ([]model.MemoryEntry, error) {
+	paths, err := s.files(ctx, ".memories")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []model.MemoryEntry{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".md") {
+			memoryPath := strings.TrimSuffix(strings.TrimPrefix(path, ".memories/"), ".md")
+			entries = append(entries, model.MemoryEntry{Path: memoryPath, ModifiedAt: s.commit.Committer.When})
+		}
+	}
+	return entries, nil
+}
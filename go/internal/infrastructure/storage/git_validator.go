@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	port "github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// Ensure GitStoreValidator implements port.StoreValidator
+var _ port.StoreValidator = (*GitStoreValidator)(nil)
+
+// GitStoreValidator verifies git stores by listing the references of the remote
+type GitStoreValidator struct{}
+
+// NewGitStoreValidator creates a new git store validator
+func NewGitStoreValidator() *GitStoreValidator {
+	return &GitStoreValidator{}
+}
+
+// Validate checks that the remote is readable and that the ref is one of its branches or tags
+func (v *GitStoreValidator) Validate(ctx context.Context, store model.DocumentStore) (*model.RepositoryInfo, error) {
+	gitStore, ok := store.(*model.GitStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", model.ErrUnsupportedStoreType, store.Type())
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{gitStore.URL()}})
+	_, defaultBranch, err := resolveRemoteRef(ctx, remote, gitStore.Ref(), gitAuth(gitStore.URL()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.RepositoryInfo{
+		FullName:      model.RedactGitURL(gitStore.URL()),
+		DefaultBranch: defaultBranch,
+	}, nil
+}
+
+// Ensure StoreValidator implements port.StoreValidator
+var _ port.StoreValidator = (*StoreValidator)(nil)
+
+// StoreValidator verifies every store with the validator of its type. The GitHub validator
+// is created on first use, so that git stores can be verified without GITHUB_TOKEN.
+type StoreValidator struct {
+	git    *GitStoreValidator
+	github *GitHubStoreValidator
+}
+
+// NewStoreValidator creates a validator for all supported store types
+func NewStoreValidator() *StoreValidator {
+	return &StoreValidator{git: NewGitStoreValidator()}
+}
+
+// Validate verifies the store with the validator of its type
+func (v *StoreValidator) Validate(ctx context.Context, store model.DocumentStore) (*model.RepositoryInfo, error) {
+	switch store.Type() {
+	case model.StoreTypeGitHub:
+		if v.github == nil {
+			github, err := NewGitHubStoreValidator()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create store validator: %w", err)
+			}
+			v.github = github
+		}
+		return v.github.Validate(ctx, store)
+	case model.StoreTypeGit:
+		return v.git.Validate(ctx, store)
+	default:
+		return nil, fmt.Errorf("%w: %s", model.ErrUnsupportedStoreType, store.Type())
+	}
+}
@@ -8,11 +8,16 @@ import (
 )
 
 // GitHubStorageFactory implements the StorageFactory interface for GitHub
-type GitHubStorageFactory struct{}
+type GitHubStorageFactory struct {
+	snapshots  *SnapshotCache
+	submodules bool
+}
 
-// NewGitHubStorageFactory creates a new GitHub storage factory
-func NewGitHubStorageFactory() *GitHubStorageFactory {
-	return &GitHubStorageFactory{}
+// NewGitHubStorageFactory creates a new GitHub storage factory whose storages read the
+// repositories from snapshots; a nil snapshots downloads them into temporary workspaces.
+// With submodules, the GitHub submodules of the repositories are synced at their paths.
+func NewGitHubStorageFactory(snapshots *SnapshotCache, submodules bool) *GitHubStorageFactory {
+	return &GitHubStorageFactory{snapshots: snapshots, submodules: submodules}
 }
 
 // CreateStorage creates a new GitHub storage instance
@@ -27,5 +32,13 @@ func (f *GitHubStorageFactory) CreateStorage(store model.DocumentStore) (port.St
 		return nil, fmt.Errorf("invalid store type for GitHub")
 	}
 
-	return NewGitHubStorage(githubStore.Repo())
+	storage, err := NewGitHubStorage(githubStore.Repo(), githubStore.Ref())
+	if err != nil {
+		return nil, err
+	}
+	storage.WithFilters(githubStore.Filters()).WithSubmodules(f.submodules)
+	if f.snapshots != nil {
+		storage.WithCache(f.snapshots)
+	}
+	return storage, nil
 }
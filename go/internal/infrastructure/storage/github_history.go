@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"github.com/google/go-github/v58/github"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// historyPageSize is the number of commits, and of files of a commit, requested per page
+const historyPageSize = 100
+
+// LastCommits implements the HistoryStorage interface with the commits API. Instead of one
+// request per file, the history of the repository, and of each submodule holding some of the
+// paths, is walked once from the newest commit until the last commit of every path is found.
+// Commits are cached per snapshot, and no more lookups are sent once the rate limit is exceeded;
+// the commits found until then are returned with the error.
+func (s *GitHubStorage) LastCommits(ctx context.Context, paths []string) (map[string]model.Commit, error) {
+	found := make(map[string]model.Commit, len(paths))
+	// Paths to look up by submodule path ("" for the repository itself), keyed by their path in it
+	groups := map[string]map[string]string{}
+	modules := map[string]submodule{}
+	for _, p := range paths {
+		if commit, ok := s.commits[p]; ok {
+			found[p] = commit
+			continue
+		}
+		module, rel, ok := s.submoduleOf(p)
+		if !ok {
+			rel = p
+		}
+		if groups[module.Path] == nil {
+			groups[module.Path] = map[string]string{}
+			modules[module.Path] = module
+		}
+		groups[module.Path][rel] = p
+	}
+
+	for modulePath, group := range groups {
+		if s.historyErr != nil {
+			return found, s.historyErr
+		}
+		source, ref := s, s.ref
+		if s.snapshot != nil {
+			ref = s.snapshot.Commit
+		}
+		// Files of submodules are looked up in their own repository
+		if module := modules[modulePath]; modulePath != "" {
+			source, ref = s.repository(module.Owner, module.Repo), module.Commit
+		}
+
+		commits, err := source.walkHistory(ctx, ref, group)
+		for rel, commit := range commits {
+			s.addCommit(found, group[rel], commit)
+		}
+		if err != nil {
+			s.stopOnRateLimit(err)
+			return found, err
+		}
+		// Files that were not found in the file lists of the commits, which the API truncates
+		// for very large commits, are looked up one by one
+		for rel, p := range group {
+			if _, ok := commits[rel]; ok {
+				continue
+			}
+			commit, err := source.fetchLastCommit(ctx, ref, rel)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				s.stopOnRateLimit(err)
+				return found, err
+			}
+			s.addCommit(found, p, commit)
+		}
+	}
+	return found, nil
+}
+
+// addCommit records the last commit of the file at path in found and in the commits cached with the snapshot
+func (s *GitHubStorage) addCommit(found map[string]model.Commit, path string, commit model.Commit) {
+	found[path] = commit
+	if s.commits == nil {
+		s.commits = map[string]model.Commit{}
+	}
+	s.commits[path] = commit
+	s.commitsChanged = true
+}
+
+// stopOnRateLimit stops further history lookups when err reports an exceeded rate limit
+func (s *GitHubStorage) stopOnRateLimit(err error) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		s.historyErr = err
+	}
+}
+
+// walkHistory lists the commits of ref from the newest and returns the first commit that changed
+// each of the paths, stopping when all are found. Merge commits are skipped, so that changes are
+// attributed to the commits that made them, as "git log -- <path>" does.
+func (s *GitHubStorage) walkHistory(ctx context.Context, ref string, paths map[string]string) (map[string]model.Commit, error) {
+	pending := make(map[string]bool, len(paths))
+	for p := range paths {
+		pending[p] = true
+	}
+	log.Printf("Walking the history of %s/%s for the last commits of %d files", s.repoOwner, s.repoName, len(pending))
+
+	found := make(map[string]model.Commit, len(pending))
+	opts := &github.CommitsListOptions{SHA: ref, ListOptions: github.ListOptions{PerPage: historyPageSize}}
+	for len(pending) > 0 {
+		commits, resp, err := s.client.Repositories.ListCommits(ctx, s.repoOwner, s.repoName, opts)
+		if err != nil {
+			return found, fmt.Errorf("error listing the commits of %s/%s: %w", s.repoOwner, s.repoName, err)
+		}
+		for _, commit := range commits {
+			if len(commit.Parents) > 1 {
+				continue
+			}
+			files, err := s.commitFiles(ctx, commit.GetSHA())
+			if err != nil {
+				return found, err
+			}
+			for _, f := range files {
+				if pending[f] {
+					found[f] = newGitHubCommit(commit)
+					delete(pending, f)
+				}
+			}
+			if len(pending) == 0 {
+				break
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return found, nil
+}
+
+// commitFiles returns the paths of the files changed by the commit
+func (s *GitHubStorage) commitFiles(ctx context.Context, sha string) ([]string, error) {
+	var paths []string
+	opts := &github.ListOptions{PerPage: historyPageSize}
+	for {
+		commit, resp, err := s.client.Repositories.GetCommit(ctx, s.repoOwner, s.repoName, sha, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the files of commit %s: %w", sha, err)
+		}
+		for _, f := range commit.Files {
+			paths = append(paths, f.GetFilename())
+		}
+		if resp.NextPage == 0 {
+			return paths, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// newGitHubCommit converts a commit of the GitHub API into its domain representation
+func newGitHubCommit(commit *github.RepositoryCommit) model.Commit {
+	return model.Commit{
+		SHA:     commit.GetSHA(),
+		Author:  commit.GetCommit().GetAuthor().GetName(),
+		Message: model.CommitSubject(commit.GetCommit().GetMessage()),
+		Time:    commit.GetCommit().GetCommitter().GetDate().Time,
+	}
+}
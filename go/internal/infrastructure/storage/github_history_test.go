@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// newHistoryServer starts a stand-in of the commits API of owner/repo. The listed commits are
+// served two per page, newest first; files holds the files changed by each commit. The commits
+// requested one by one are counted in fetched.
+func newHistoryServer(t *testing.T, commits []map[string]any, files map[string][]string, fetched map[string]int) *GitHubStorage {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/owner/repo/commits", func(w http.ResponseWriter, r *http.Request) {
+		// Files missing from the file lists are looked up by path
+		if path := r.URL.Query().Get("path"); path != "" {
+			var last []map[string]any
+			for _, c := range commits {
+				for _, f := range files[c["sha"].(string)] {
+					if f == path && last == nil {
+						last = []map[string]any{c}
+					}
+				}
+			}
+			json.NewEncoder(w).Encode(last)
+			return
+		}
+		page := 1
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		end := min(2*page, len(commits))
+		if end < len(commits) {
+			next := url.Values{"page": {fmt.Sprint(page + 1)}}
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, server.URL, r.URL.Path, next.Encode()))
+		}
+		json.NewEncoder(w).Encode(commits[2*(page-1) : end])
+	})
+	mux.HandleFunc("GET /repos/owner/repo/commits/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		sha := r.PathValue("sha")
+		fetched[sha]++
+		var changed []map[string]string
+		for _, f := range files[sha] {
+			changed = append(changed, map[string]string{"filename": f})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"sha": sha, "files": changed})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return &GitHubStorage{client: client, repoOwner: "owner", repoName: "repo", ref: "main"}
+}
+
+func TestGitHubStorageLastCommits(t *testing.T) {
+	commit := func(sha, message string, parents int) map[string]any {
+		c := map[string]any{
+			"sha": sha,
+			"commit": map[string]any{
+				"message":   message,
+				"author":    map[string]any{"name": "yuta"},
+				"committer": map[string]any{"date": "2026-10-01T12:00:00Z"},
+			},
+		}
+		var p []map[string]string
+		for i := 0; i < parents; i++ {
+			p = append(p, map[string]string{"sha": fmt.Sprintf("parent%d", i)})
+		}
+		c["parents"] = p
+		return c
+	}
+	commits := []map[string]any{
+		commit("c4", "Update a", 1),
+		commit("m3", "Merge branch", 2),
+		commit("c2", "Add b\n\nDetails", 1),
+		commit("c1", "Add a and c", 0),
+	}
+	files := map[string][]string{
+		"c4": {"a.md"},
+		"m3": {"a.md", "b.md"},
+		"c2": {"b.md"},
+		"c1": {"a.md", "c.md"},
+	}
+	fetched := map[string]int{}
+	s := newHistoryServer(t, commits, files, fetched)
+
+	got, err := s.LastCommits(context.Background(), []string{"a.md", "b.md", "c.md", "missing.md"})
+	if err != nil {
+		t.Fatalf("LastCommits failed: %v", err)
+	}
+	want := map[string]string{"a.md": "c4", "b.md": "c2", "c.md": "c1"}
+	if len(got) != len(want) {
+		t.Errorf("got commits of %v, want %v", got, want)
+	}
+	for path, sha := range want {
+		if got[path].SHA != sha {
+			t.Errorf("last commit of %s = %q, want %q", path, got[path].SHA, sha)
+		}
+	}
+	if got["b.md"].Message != "Add b" || got["b.md"].Author != "yuta" {
+		t.Errorf("got message %q and author %q", got["b.md"].Message, got["b.md"].Author)
+	}
+	// Merge commits are skipped and every other commit is fetched once
+	if fetched["m3"] != 0 || fetched["c4"] != 1 || fetched["c1"] != 1 {
+		t.Errorf("got fetched commits %v", fetched)
+	}
+
+	// Commits are cached, so a second lookup sends no request
+	clear(fetched)
+	if _, err := s.LastCommits(context.Background(), []string{"a.md", "b.md"}); err != nil {
+		t.Fatalf("LastCommits failed: %v", err)
+	}
+	if len(fetched) != 0 {
+		t.Errorf("got fetched commits %v, want none", fetched)
+	}
+}
@@ -3,21 +3,21 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
-	"unicode/utf8"
-
-	"github.com/bonyuta0204/personal-agent/go/internal/infrastructure/util"
 
 	"github.com/google/go-github/v58/github"
 	"golang.org/x/oauth2"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	port "github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
 )
 
 // logDuration logs the time taken by a function with the given name
@@ -32,14 +32,30 @@ type GitHubStorage struct {
 	client     *github.Client
 	repoOwner  string
 	repoName   string
-	tmpDirPath string // Path to the local repository clone
+	ref        string             // Git ref to download; empty means the default branch
+	filters    model.StoreFilters // Paths excluded by the filters are not extracted into the workspace
+	cache      *SnapshotCache     // Cache of extracted commits shared with other storages; nil uses a workspace
+	workspace  string             // Temporary directory holding the extracted repository; removed by Close
+	tmpDirPath string             // Path to the local repository clone
+	snapshot   *Snapshot          // Cached snapshot read from; nil without a cache
+	lfs        *lfsClient         // Downloads the Git LFS objects of the extracted pointer files
+
+	followSubmodules bool        // Whether GitHub submodules are extracted into the tree
+	submodules       []submodule // Submodules extracted into the tree
+
+	commits        map[string]model.Commit // Last commits looked up so far, keyed by path
+	commitsChanged bool                    // Whether commits has entries that are not saved in the snapshot
+	historyErr     error                   // Rate limit error that stops further lookups
 }
 
-// NewGitHubStorage creates a new GitHub storage instance
-func NewGitHubStorage(repo string) (*GitHubStorage, error) {
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN environment variable is not set")
+// Ensure GitHubStorage implements port.HistoryStorage
+var _ port.HistoryStorage = (*GitHubStorage)(nil)
+
+// NewGitHubStorage creates a new GitHub storage instance for the given ref
+func NewGitHubStorage(repo string, ref string) (*GitHubStorage, error) {
+	token, err := githubToken()
+	if err != nil {
+		return nil, err
 	}
 
 	repoParts := strings.Split(repo, "/")
@@ -47,27 +63,84 @@ func NewGitHubStorage(repo string) (*GitHubStorage, error) {
 		return nil, fmt.Errorf("invalid repo format, expected 'owner/repo'")
 	}
 
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-
 	return &GitHubStorage{
-		client:    github.NewClient(tc),
+		client:    newGitHubClientWithToken(token),
 		repoOwner: repoParts[0],
 		repoName:  repoParts[1],
+		ref:       ref,
+		lfs:       newLFSClient(githubLFSEndpoint(repoParts[0], repoParts[1]), token),
 	}, nil
 }
 
+// WithFilters skips the paths excluded by filters when the repository is downloaded
+func (s *GitHubStorage) WithFilters(filters model.StoreFilters) *GitHubStorage {
+	s.filters = filters
+	return s
+}
+
+// WithCache reads the repository from snapshots in cache instead of a temporary workspace.
+// Cached snapshots hold every file, since they are shared with storages using other filters.
+func (s *GitHubStorage) WithCache(cache *SnapshotCache) *GitHubStorage {
+	s.cache = cache
+	return s
+}
+
+// Close implements the Storage interface by removing the downloaded repository.
+// Cached snapshots are kept for later runs, together with the commits looked up.
+func (s *GitHubStorage) Close() error {
+	s.tmpDirPath = ""
+	if s.snapshot != nil && s.commitsChanged {
+		if err := s.snapshot.SaveCommits(s.commits); err != nil {
+			log.Printf("warning: %v", err)
+		}
+	}
+	s.snapshot, s.commits, s.commitsChanged = nil, nil, false
+	s.submodules = nil
+	if s.workspace == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.workspace)
+	s.workspace = ""
+	if err != nil {
+		return fmt.Errorf("error removing workspace: %w", err)
+	}
+	return nil
+}
+
+// githubToken returns the token set in GITHUB_TOKEN
+func githubToken() (string, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("GITHUB_TOKEN environment variable is not set")
+	}
+	return token, nil
+}
+
+// newGitHubClient creates a GitHub API client authenticated with GITHUB_TOKEN
+func newGitHubClient() (*github.Client, error) {
+	token, err := githubToken()
+	if err != nil {
+		return nil, err
+	}
+	return newGitHubClientWithToken(token), nil
+}
+
+// newGitHubClientWithToken creates a GitHub API client authenticated with token
+func newGitHubClientWithToken(token string) *github.Client {
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	tc := oauth2.NewClient(context.Background(), ts)
+
+	return github.NewClient(tc)
+}
+
 // SaveDocument implements the Storage interface
-func (s *GitHubStorage) SaveDocument(document *model.Document) error {
+func (s *GitHubStorage) SaveDocument(ctx context.Context, document *model.Document) error {
 	if document == nil {
 		return fmt.Errorf("document cannot be nil")
 	}
 
-	ctx := context.Background()
-
 	// Prepare file content
 	content := []byte(document.Content)
 	message := fmt.Sprintf("Add/update document: %s", document.Path)
@@ -92,89 +165,189 @@ func (s *GitHubStorage) SaveDocument(document *model.Document) error {
 	return nil
 }
 
-// SaveMemory implements the Storage interface
-func (s *GitHubStorage) SaveMemory(memory *model.Memory) error {
-	if memory == nil {
-		return fmt.Errorf("memory cannot be nil")
+// memoryFilePath returns the path in the repository of the file of a memory
+func memoryFilePath(path string) string {
+	if !strings.HasPrefix(path, ".memories/") {
+		path = ".memories/" + path
 	}
-
-	// For memories, we'll store them in a .memories directory
-	path := filepath.Join(".memories", memory.Path)
 	if !strings.HasSuffix(path, ".md") {
 		path += ".md"
 	}
+	return path
+}
 
-	ctx := context.Background()
+// branch returns the branch to commit to; nil means the default branch
+func (s *GitHubStorage) branch() *string {
+	if s.ref == "" {
+		return nil
+	}
+	return github.String(s.ref)
+}
 
-	// Prepare file content
-	content := []byte(memory.Content)
-	message := fmt.Sprintf("Add/update memory: %s", path)
+// fileSHA returns the blob SHA of a file in the repository, or an empty string if it does not exist
+func (s *GitHubStorage) fileSHA(ctx context.Context, path string) (string, error) {
+	file, _, _, err := s.client.Repositories.GetContents(ctx, s.repoOwner, s.repoName, path, &github.RepositoryContentGetOptions{Ref: s.ref})
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error checking if %s exists: %w", path, err)
+	}
+	if file == nil {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	return file.GetSHA(), nil
+}
 
-	// Check if file exists to determine if this is an update
-	_, _, _, err := s.client.Repositories.GetContents(ctx, s.repoOwner, s.repoName, path, nil)
-	if err != nil && !strings.Contains(err.Error(), "404 Not Found") {
-		return fmt.Errorf("error checking if memory file exists: %w", err)
+// SaveMemory implements the Storage interface. The memory is stored as .memories/<path>.md
+// and committed to the configured ref, or the default branch if there is none.
+func (s *GitHubStorage) SaveMemory(ctx context.Context, memory *model.Memory) error {
+	if memory == nil {
+		return fmt.Errorf("memory cannot be nil")
 	}
+	path := memoryFilePath(memory.Path)
 
-	// Create or update file
-	_, _, err = s.client.Repositories.CreateFile(ctx, s.repoOwner, s.repoName, path, &github.RepositoryContentFileOptions{
-		Message: github.String(message),
-		Content: content,
-		SHA:     nil, // Will be populated by GitHub for updates
-	})
+	// An existing file is only replaced given its current blob SHA
+	sha, err := s.fileSHA(ctx, path)
+	if err != nil {
+		return err
+	}
+	opts := &github.RepositoryContentFileOptions{
+		Message: github.String(fmt.Sprintf("Add memory: %s", path)),
+		Content: []byte(memory.Content),
+		Branch:  s.branch(),
+	}
+	if sha != "" {
+		opts.Message = github.String(fmt.Sprintf("Update memory: %s", path))
+		opts.SHA = github.String(sha)
+	}
 
+	resp, _, err := s.client.Repositories.CreateFile(ctx, s.repoOwner, s.repoName, path, opts)
 	if err != nil {
 		return fmt.Errorf("error creating/updating memory file: %w", err)
 	}
 
+	memory.SetContentHashes()
+	memory.ModifiedAt = resp.Commit.GetCommitter().GetDate().Time
+	if memory.ModifiedAt.IsZero() {
+		memory.ModifiedAt = time.Now()
+	}
+	return nil
+}
+
+// DeleteMemory implements the Storage interface
+func (s *GitHubStorage) DeleteMemory(ctx context.Context, memoryPath string) error {
+	path := memoryFilePath(memoryPath)
+
+	sha, err := s.fileSHA(ctx, path)
+	if err != nil {
+		return err
+	}
+	if sha == "" {
+		return port.ErrMemoryNotFound
+	}
+
+	_, _, err = s.client.Repositories.DeleteFile(ctx, s.repoOwner, s.repoName, path, &github.RepositoryContentFileOptions{
+		Message: github.String(fmt.Sprintf("Delete memory: %s", path)),
+		SHA:     github.String(sha),
+		Branch:  s.branch(),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting memory file: %w", err)
+	}
 	return nil
 }
 
-// fetchFileContent is a helper method that handles fetching file content either from local file system or GitHub API
-func (s *GitHubStorage) fetchFileContent(path string) (content string, modTime time.Time, err error) {
+// fetchRemoteFile reads a single file with the contents API instead of downloading the
+// whole repository, together with the time of the last commit that changed it
+func (s *GitHubStorage) fetchRemoteFile(ctx context.Context, path string) (content, encoding string, modTime time.Time, err error) {
+	file, _, _, err := s.client.Repositories.GetContents(ctx, s.repoOwner, s.repoName, path, &github.RepositoryContentGetOptions{Ref: s.ref})
+	if isNotFound(err) {
+		return "", "", time.Time{}, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+	}
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("error getting %s: %w", path, err)
+	}
+	if file == nil {
+		return "", "", time.Time{}, fmt.Errorf("%s is a directory", path)
+	}
+	raw, err := file.GetContent()
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	content, encoding, err = decodeFile(path, []byte(raw))
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	commit, err := s.fetchLastCommit(ctx, s.ref, path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", "", time.Time{}, err
+	}
+	return content, encoding, commit.Time, nil
+}
+
+// fetchLastCommit returns the last commit of ref that changed the file at path
+func (s *GitHubStorage) fetchLastCommit(ctx context.Context, ref, path string) (model.Commit, error) {
+	commits, _, err := s.client.Repositories.ListCommits(ctx, s.repoOwner, s.repoName, &github.CommitsListOptions{
+		SHA:         ref,
+		Path:        path,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return model.Commit{}, fmt.Errorf("error getting the last commit of %s: %w", path, err)
+	}
+	if len(commits) == 0 {
+		return model.Commit{}, fmt.Errorf("no commit of %s: %w", path, fs.ErrNotExist)
+	}
+	return newGitHubCommit(commits[0]), nil
+}
+
+// fetchFileContent is a helper method that handles fetching file content either from local file system or GitHub API.
+// The content is decoded into UTF-8 from the detected encoding.
+func (s *GitHubStorage) fetchFileContent(ctx context.Context, path string) (content, encoding string, modTime time.Time, err error) {
 
 	if s.tmpDirPath == "" {
-		if err := s.downloadRepository(); err != nil {
-			return "", time.Time{}, fmt.Errorf("error downloading repository: %w", err)
+		if err := s.downloadRepository(ctx); err != nil {
+			return "", "", time.Time{}, fmt.Errorf("error downloading repository: %w", err)
 		}
 	}
 	// If we have a local clone, read from the file system
 	fullPath := filepath.Join(s.tmpDirPath, path)
 	contentBytes, err := os.ReadFile(fullPath)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("error reading file from local clone: %w", err)
+		return "", "", time.Time{}, fmt.Errorf("error reading file from local clone: %w", err)
 	}
 
-	// Check if the file is binary
-	if isBinary(contentBytes) {
-		return "", time.Time{}, fmt.Errorf("skipping binary file: %s", path)
+	// Skip binary files and decode text in other encodings
+	content, encoding, err = decodeFile(path, contentBytes)
+	if err != nil {
+		return "", "", time.Time{}, err
 	}
 
 	// Get file info for modification time
 	fileInfo, err := os.Stat(fullPath)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("error getting file info: %w", err)
+		return "", "", time.Time{}, fmt.Errorf("error getting file info: %w", err)
 	}
 
-	return string(contentBytes), fileInfo.ModTime(), nil
+	return content, encoding, fileInfo.ModTime(), nil
 }
 
 // isBinary checks if a byte slice contains binary data by looking for null bytes
-// and checking for common binary file signatures.
+// and checking for common binary file signatures. Text in other encodings than UTF-8
+// is detected by decodeText.
 func isBinary(data []byte) bool {
 	// Empty files are not binary
 	if len(data) == 0 {
 		return false
 	}
 
-	// Check if it's valid UTF-8 text
-	if !utf8.Valid(data) {
+	// Text files never contain null bytes, except in UTF-16, which is checked first
+	if bytes.IndexByte(data, 0) >= 0 {
 		return true
 	}
 
-	// Only consider files with .pptx, .xlsx, .docx, .pdf, .jpg, .png, etc. as binary
-	// For markdown files with Japanese text, we want to process them as text
-
 	// For image files and other known binary formats, check magic numbers
 	if len(data) > 8 {
 		// PNG signature
@@ -195,57 +368,57 @@ func isBinary(data []byte) bool {
 		}
 	}
 
-	// For text files with UTF-8 characters (like Japanese text),
-	// we'll be more lenient and not consider them binary
 	return false
 }
 
 // FetchDocument implements the Storage interface
-func (s *GitHubStorage) FetchDocument(storeId model.StoreId, path string) (*model.Document, error) {
-	content, modTime, err := s.fetchFileContent(path)
+func (s *GitHubStorage) FetchDocument(ctx context.Context, storeId model.StoreId, path string) (*model.Document, error) {
+	content, encoding, modTime, err := s.fetchFileContent(ctx, path)
 	if err != nil {
 		return nil, err
 	}
 
-	sha := util.CalculateSHA256(content)
-
-	return &model.Document{
+	document := &model.Document{
 		Path:       path,
 		StoreId:    storeId,
 		Content:    content,
-		SHA:        sha,
+		Encoding:   encoding,
 		ModifiedAt: modTime,
-	}, nil
+	}
+	document.SetContentHashes()
+	return document, nil
 }
 
-// FetchMemory implements the Storage interface
-func (s *GitHubStorage) FetchMemory(path string) (*model.Memory, error) {
+// FetchMemory implements the Storage interface. Without a local clone, the file is read
+// with the contents API so that a single memory does not download the repository.
+func (s *GitHubStorage) FetchMemory(ctx context.Context, path string) (*model.Memory, error) {
 	// For memories, we'll look in the .memories directory
-	if !strings.HasPrefix(path, ".memories/") {
-		path = filepath.Join(".memories", path)
+	path = memoryFilePath(path)
+
+	fetch := s.fetchFileContent
+	if s.tmpDirPath == "" {
+		fetch = s.fetchRemoteFile
 	}
-	if !strings.HasSuffix(path, ".md") {
-		path += ".md"
+	content, _, modTime, err := fetch(ctx, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, port.ErrMemoryNotFound
 	}
-
-	// Use fetchFileContent directly to avoid unnecessary document creation
-	content, modTime, err := s.fetchFileContent(path)
 	if err != nil {
 		return nil, err
 	}
 
-	sha := util.CalculateSHA256(content)
-
 	// Strip the .memories/ prefix and .md suffix for the memory path
 	memoryPath := strings.TrimSuffix(strings.TrimPrefix(path, ".memories/"), ".md")
 
-	return &model.Memory{
-		Path:      memoryPath,
-		Content:   content,
-		SHA:       sha,
-		CreatedAt: modTime,
-		UpdatedAt: modTime,
-	}, nil
+	memory := &model.Memory{
+		Path:       memoryPath,
+		Content:    content,
+		ModifiedAt: modTime,
+		CreatedAt:  modTime,
+		UpdatedAt:  modTime,
+	}
+	memory.SetContentHashes()
+	return memory, nil
 }
 
 // GetDocumentEntriesFromFS recursively gets all file paths from the local file system
@@ -287,10 +460,10 @@ func (s *GitHubStorage) GetDocumentEntriesFromFS(dir string) ([]model.DocumentEn
 }
 
 // GetDocumentEntries implements the Storage interface
-func (s *GitHubStorage) GetDocumentEntries() ([]model.DocumentEntry, error) {
+func (s *GitHubStorage) GetDocumentEntries(ctx context.Context) ([]model.DocumentEntry, error) {
 	if s.tmpDirPath == "" {
 		log.Printf("No local clone found, downloading repository...")
-		if err := s.downloadRepository(); err != nil {
+		if err := s.downloadRepository(ctx); err != nil {
 			return nil, fmt.Errorf("error downloading repository: %w", err)
 		}
 	} else {
@@ -307,87 +480,161 @@ func (s *GitHubStorage) GetDocumentEntries() ([]model.DocumentEntry, error) {
 	return paths, nil
 }
 
-// downloadRepository downloads the repository tarball and extracts it to a temporary directory
-func (s *GitHubStorage) downloadRepository() error {
+// downloadRepository streams the repository tarball into a temporary workspace, extracting it
+// in-process without the paths excluded by the filters, and downloads the LFS objects of the
+// files it reads. With a snapshot cache, the commit of the ref is resolved first and its cached
+// snapshot is used instead. The download and the extraction are aborted when ctx is done.
+func (s *GitHubStorage) downloadRepository(ctx context.Context) error {
 	start := time.Now()
 	defer logDuration(start, "downloadRepository")
 
-	ctx := context.Background()
+	if s.cache != nil {
+		return s.openSnapshot(ctx)
+	}
+
 	log.Printf("Starting repository download for %s/%s", s.repoOwner, s.repoName)
 
-	// Create a temporary directory to store the downloaded tarball
-	tmpDir, err := os.MkdirTemp("", "github-repo-*")
+	// Create a temporary workspace for the extracted repository
+	workspace, err := os.MkdirTemp("", "github-repo-*")
 	if err != nil {
 		return fmt.Errorf("error creating temp directory: %w", err)
 	}
 
-	// Get the tarball URL for the repository
-	url, _, err := s.client.Repositories.GetArchiveLink(ctx, s.repoOwner, s.repoName, github.Tarball, &github.RepositoryContentGetOptions{}, 1)
-	if err != nil {
-		os.RemoveAll(tmpDir) // Clean up temp dir on error
-		return fmt.Errorf("error getting archive link: %w", err)
+	extractDir := filepath.Join(workspace, "extracted")
+	skip := func(p string) bool { return !s.filters.Match(p) }
+	modules, err := s.extractTree(ctx, extractDir, s.ref, skip)
+	if err == nil {
+		_, err = s.resolveLFS(ctx, extractDir, modules)
 	}
-
-	// Download the tarball
-	resp, err := s.client.Client().Get(url.String())
 	if err != nil {
-		os.RemoveAll(tmpDir) // Clean up temp dir on error
-		return fmt.Errorf("error downloading repository: %w", err)
+		os.RemoveAll(workspace) // Clean up the workspace on error
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		os.RemoveAll(tmpDir) // Clean up temp dir on error
-		return fmt.Errorf("error downloading repository: %s", resp.Status)
+	// Remove the workspace of a previous download
+	if err := s.Close(); err != nil {
+		log.Printf("warning: %v", err)
 	}
+	s.workspace = workspace
+	s.tmpDirPath = extractDir
+	s.submodules = modules
+	return nil
+}
 
-	// Save the tarball to a temporary file
-	tarballPath := filepath.Join(tmpDir, "repo.tar.gz")
-	file, err := os.Create(tarballPath)
+// openSnapshot reads the repository from the cached snapshot of the current commit of the ref,
+// downloading the commit when it is not cached
+func (s *GitHubStorage) openSnapshot(ctx context.Context) error {
+	ref := s.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	commit, _, err := s.client.Repositories.GetCommitSHA1(ctx, s.repoOwner, s.repoName, ref, "")
 	if err != nil {
-		os.RemoveAll(tmpDir) // Clean up temp dir on error
-		return fmt.Errorf("error creating tarball file: %w", err)
+		return fmt.Errorf("error resolving %s: %w", ref, err)
+	}
+
+	repo := s.repoOwner + "/" + s.repoName
+	var modules []submodule
+	extracted := false
+	key := Snapshot{Repo: repo, Ref: s.ref, Commit: commit, Submodules: s.followSubmodules}
+	snapshot, err := s.cache.Open(key, func(dir string) error {
+		log.Printf("Starting repository download for %s at %s", repo, shortCommit(commit))
+		extracted = true
+		var err error
+		modules, err = s.extractTree(ctx, dir, commit, nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if extracted && len(modules) > 0 {
+		if err := snapshot.saveSubmodules(modules); err != nil {
+			log.Printf("warning: %v", err)
+		}
+	} else if !extracted && s.followSubmodules {
+		if modules, err = snapshot.submodules(); err != nil {
+			log.Printf("warning: %v", err)
+		}
 	}
-	defer file.Close()
 
-	_, err = file.ReadFrom(resp.Body)
+	// Snapshots keep the pointers of LFS objects until a storage that reads their files opens them
+	downloaded, err := s.resolveLFS(ctx, snapshot.TreeDir(), modules)
 	if err != nil {
-		os.RemoveAll(tmpDir) // Clean up temp dir on error
-		return fmt.Errorf("error saving tarball: %w", err)
+		return err
+	}
+	if downloaded > 0 {
+		if err := snapshot.updateSize(); err != nil {
+			log.Printf("warning: %v", err)
+		}
 	}
 
-	// Create a directory to extract the tarball
-	extractDir := filepath.Join(tmpDir, "extracted")
-	err = os.Mkdir(extractDir, 0755)
-	if err != nil {
-		os.RemoveAll(tmpDir) // Clean up temp dir on error
-		return fmt.Errorf("error creating extraction directory: %w", err)
+	// Remove the workspace of a previous download
+	if err := s.Close(); err != nil {
+		log.Printf("warning: %v", err)
+	}
+	s.tmpDirPath = snapshot.TreeDir()
+	s.snapshot = snapshot
+	s.submodules = modules
+	if s.commits, err = snapshot.Commits(); err != nil {
+		log.Printf("warning: %v", err)
 	}
+	return nil
+}
+
+// extractTree extracts the repository at ref into dir, together with its submodules when they
+// are followed, and returns the extracted submodules
+func (s *GitHubStorage) extractTree(ctx context.Context, dir string, ref string, skip func(string) bool) ([]submodule, error) {
+	if err := s.extractRepository(ctx, dir, ref, skip); err != nil {
+		return nil, err
+	}
+	if !s.followSubmodules {
+		return nil, nil
+	}
+	return s.extractSubmodules(ctx, dir, "", ref, skip, 1)
+}
 
-	// Extract the tarball
-	cmd := exec.Command("tar", "-xzf", tarballPath, "-C", extractDir, "--strip-components=1")
-	output, err := cmd.CombinedOutput()
+// extractRepository downloads the tarball of the ref and extracts it into dir, skipping the
+// paths for which skip reports true; skip may be nil
+func (s *GitHubStorage) extractRepository(ctx context.Context, dir string, ref string, skip func(string) bool) error {
+	// Get the tarball URL for the repository
+	url, _, err := s.client.Repositories.GetArchiveLink(ctx, s.repoOwner, s.repoName, github.Tarball, &github.RepositoryContentGetOptions{Ref: ref}, 1)
 	if err != nil {
-		os.RemoveAll(tmpDir) // Clean up temp dir on error
-		return fmt.Errorf("error extracting tarball: %v\nOutput: %s", err, string(output))
+		return fmt.Errorf("error getting archive link: %w", err)
 	}
 
-	// Clean up the tarball
-	err = os.Remove(tarballPath)
+	// Download the tarball
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
 	if err != nil {
-		// Non-fatal error, just log it
-		fmt.Printf("warning: failed to remove tarball: %v\n", err)
+		return fmt.Errorf("error creating download request: %w", err)
 	}
+	resp, err := s.client.Client().Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading repository: %w", err)
+	}
+	defer resp.Body.Close()
 
-	s.tmpDirPath = filepath.Join(extractDir)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading repository: %s", resp.Status)
+	}
+
+	// The entries of the tarball are below a directory named after the repository and commit
+	opts := extractOptions{
+		StripComponents: 1,
+		MaxFileSize:     DefaultMaxArchiveFileSize,
+		MaxTotalSize:    DefaultMaxArchiveTotalSize,
+		Skip:            skip,
+	}
+	if err := extractTarGz(ctx, resp.Body, dir, opts); err != nil {
+		return fmt.Errorf("error extracting tarball: %w", err)
+	}
 	return nil
 }
 
 // GetMemoryEntries implements the Storage interface for memories
-func (s *GitHubStorage) GetMemoryEntries() ([]model.MemoryEntry, error) {
+func (s *GitHubStorage) GetMemoryEntries(ctx context.Context) ([]model.MemoryEntry, error) {
 	if s.tmpDirPath == "" {
 		log.Printf("No local clone found, downloading repository...")
-		if err := s.downloadRepository(); err != nil {
+		if err := s.downloadRepository(ctx); err != nil {
 			return nil, fmt.Errorf("error downloading repository: %w", err)
 		}
 	} else {
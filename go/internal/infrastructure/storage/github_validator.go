@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	port "github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// Ensure GitHubStoreValidator implements port.StoreValidator
+var _ port.StoreValidator = (*GitHubStoreValidator)(nil)
+
+// GitHubStoreValidator verifies GitHub stores through the GitHub API
+type GitHubStoreValidator struct {
+	client *github.Client
+}
+
+// NewGitHubStoreValidator creates a validator authenticated with GITHUB_TOKEN
+func NewGitHubStoreValidator() (*GitHubStoreValidator, error) {
+	client, err := newGitHubClient()
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubStoreValidator{client: client}, nil
+}
+
+// Validate checks that the repository exists and is readable, and that the ref resolves
+func (v *GitHubStoreValidator) Validate(ctx context.Context, store model.DocumentStore) (*model.RepositoryInfo, error) {
+	githubStore, ok := store.(*model.GitHubStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", model.ErrUnsupportedStoreType, store.Type())
+	}
+
+	owner, name, _ := strings.Cut(githubStore.Repo(), "/")
+
+	repository, _, err := v.client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", port.ErrRepositoryNotFound, githubStore.Repo())
+		}
+		return nil, fmt.Errorf("error getting repository %s: %w", githubStore.Repo(), err)
+	}
+
+	if ref := githubStore.Ref(); ref != "" {
+		if _, _, err := v.client.Repositories.GetCommitSHA1(ctx, owner, name, ref, ""); err != nil {
+			if isNotFound(err) || isUnprocessable(err) {
+				return nil, fmt.Errorf("%w: %s in %s", port.ErrRefNotFound, ref, githubStore.Repo())
+			}
+			return nil, fmt.Errorf("error resolving ref %s: %w", ref, err)
+		}
+	}
+
+	return &model.RepositoryInfo{
+		FullName:      repository.GetFullName(),
+		DefaultBranch: repository.GetDefaultBranch(),
+		SizeKB:        repository.GetSize(),
+		Private:       repository.GetPrivate(),
+	}, nil
+}
+
+// isNotFound reports whether err is a 404 response from the GitHub API
+func isNotFound(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// isUnprocessable reports whether err is a 422 response, returned for malformed refs
+func isUnprocessable(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnprocessableEntity
+}
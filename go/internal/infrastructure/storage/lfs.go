@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// lfsPointerMaxSize is the size above which files are never Git LFS pointers
+	lfsPointerMaxSize = 1024
+	// lfsBatchSize is the number of objects requested per batch API call
+	lfsBatchSize      = 100
+	lfsMediaType      = "application/vnd.git-lfs+json"
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+)
+
+var lfsOIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// lfsPointer identifies a Git LFS object by the pointer file that replaces it in the repository
+type lfsPointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// parseLFSPointer parses a Git LFS pointer file; it reports false for other content
+func parseLFSPointer(data []byte) (lfsPointer, bool) {
+	if len(data) > lfsPointerMaxSize || !bytes.HasPrefix(data, []byte(lfsPointerVersion+"\n")) {
+		return lfsPointer{}, false
+	}
+	var pointer lfsPointer
+	hasSize := false
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n")[1:] {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if !ok {
+				return lfsPointer{}, false
+			}
+			pointer.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return lfsPointer{}, false
+			}
+			pointer.Size, hasSize = size, true
+		}
+	}
+	return pointer, hasSize && lfsOIDPattern.MatchString(pointer.OID)
+}
+
+// lfsClient downloads Git LFS objects through the batch API of a repository
+type lfsClient struct {
+	endpoint string // LFS endpoint of the repository, without the trailing "/objects/batch"
+	token    string // Sent with basic authentication to the batch API; empty sends none
+	http     *http.Client
+}
+
+// newLFSClient creates a client of the LFS endpoint authenticated with token
+func newLFSClient(endpoint, token string) *lfsClient {
+	return &lfsClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		http:     &http.Client{},
+	}
+}
+
+// githubLFSEndpoint returns the LFS endpoint of a GitHub repository
+func githubLFSEndpoint(owner, repo string) string {
+	return fmt.Sprintf("https://github.com/%s/%s.git/info/lfs", owner, repo)
+}
+
+// resolveLFS downloads the Git LFS objects of the files below dir that the storage reads: the
+// markdown files that match its filters. Files of the extracted submodules are downloaded from
+// the LFS endpoint of their repository. It returns the number of objects downloaded.
+func (s *GitHubStorage) resolveLFS(ctx context.Context, dir string, modules []submodule) (int, error) {
+	want := func(p string) bool { return strings.HasSuffix(p, ".md") && s.filters.Match(p) }
+	clientOf := func(p string) *lfsClient {
+		if m, _, ok := innermostSubmodule(modules, p); ok {
+			return newLFSClient(githubLFSEndpoint(m.Owner, m.Repo), s.lfs.token)
+		}
+		return s.lfs
+	}
+	opts := extractOptions{MaxFileSize: DefaultMaxArchiveFileSize, MaxTotalSize: DefaultMaxArchiveTotalSize}
+	downloaded, err := resolveLFSPointers(ctx, dir, opts, want, clientOf)
+	if err != nil {
+		return downloaded, fmt.Errorf("error resolving LFS objects: %w", err)
+	}
+	return downloaded, nil
+}
+
+// lfsAction is how to download an object, as returned by the batch API
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+type lfsBatchRequest struct {
+	Operation string       `json:"operation"`
+	Transfers []string     `json:"transfers"`
+	Objects   []lfsPointer `json:"objects"`
+}
+
+type lfsBatchResponse struct {
+	Objects []struct {
+		lfsPointer
+		Actions struct {
+			Download *lfsAction `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// batch requests the download actions of the objects, keyed by OID. Objects the server reports
+// an error for are left out and logged.
+func (c *lfsClient) batch(ctx context.Context, objects []lfsPointer) (map[string]lfsAction, error) {
+	body, err := json.Marshal(lfsBatchRequest{Operation: "download", Transfers: []string{"basic"}, Objects: objects})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating LFS batch request: %w", err)
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	if c.token != "" {
+		req.SetBasicAuth("x-access-token", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting LFS objects: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error requesting LFS objects: %s", resp.Status)
+	}
+
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("invalid LFS batch response: %w", err)
+	}
+	actions := make(map[string]lfsAction, len(batch.Objects))
+	for _, object := range batch.Objects {
+		switch {
+		case object.Error != nil:
+			log.Printf("warning: LFS object %s: %s (%d)", object.OID, object.Error.Message, object.Error.Code)
+		case object.Actions.Download == nil:
+			log.Printf("warning: LFS object %s has no download action", object.OID)
+		default:
+			actions[object.OID] = *object.Actions.Download
+		}
+	}
+	return actions, nil
+}
+
+// download fetches the object of pointer with its download action into target, checking its
+// size and SHA-256. Only the headers of the action are sent, since it usually points to another host.
+func (c *lfsClient) download(ctx context.Context, pointer lfsPointer, action lfsAction, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, action.Href, nil)
+	if err != nil {
+		return fmt.Errorf("error creating LFS download request: %w", err)
+	}
+	for key, value := range action.Header {
+		req.Header.Set(key, value)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading LFS object: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading LFS object: %s", resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".lfs-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(resp.Body, pointer.Size+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error downloading LFS object: %w", err)
+	}
+	if n != pointer.Size || hex.EncodeToString(hash.Sum(nil)) != pointer.OID {
+		return fmt.Errorf("LFS object %s does not match its pointer", pointer.OID)
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// resolveLFSPointers replaces the Git LFS pointer files below dir with the objects they point to,
+// for the slash separated paths relative to dir that want reports true. Objects are downloaded
+// from the client that clientOf returns for their path. As for archive entries, objects larger
+// than opts.MaxFileSize are skipped, and the resolution fails when the files below dir would
+// exceed opts.MaxTotalSize. The pointers of other paths, of skipped objects and of objects that
+// cannot be downloaded are left in place; they are skipped when the files are read. It returns
+// the number of objects downloaded.
+func resolveLFSPointers(ctx context.Context, dir string, opts extractOptions, want func(string) bool, clientOf func(string) *lfsClient) (int, error) {
+	pointers := map[string]lfsPointer{} // Keyed by the slash separated path of the pointer file
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if info.Size() > lfsPointerMaxSize || !want(name) {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if pointer, ok := parseLFSPointer(data); ok {
+			pointers[name] = pointer
+			total -= info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error finding LFS pointers: %w", err)
+	}
+	if len(pointers) == 0 {
+		return 0, nil
+	}
+
+	names := make([]string, 0, len(pointers))
+	for name := range pointers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The wanted pointers are grouped by the LFS endpoint of their repository
+	clients := map[string]*lfsClient{}
+	wanted := map[string][]string{}
+	var endpoints []string
+	for _, name := range names {
+		pointer := pointers[name]
+		if pointer.Size > opts.MaxFileSize {
+			log.Printf("skipping %s: LFS object of %d bytes exceeds the limit of %d bytes", name, pointer.Size, opts.MaxFileSize)
+			continue
+		}
+		total += pointer.Size
+		if total > opts.MaxTotalSize {
+			return 0, fmt.Errorf("%w: files exceed %d bytes", ErrArchiveTooLarge, opts.MaxTotalSize)
+		}
+		client := clientOf(name)
+		if _, ok := clients[client.endpoint]; !ok {
+			clients[client.endpoint] = client
+			endpoints = append(endpoints, client.endpoint)
+		}
+		wanted[client.endpoint] = append(wanted[client.endpoint], name)
+	}
+
+	downloaded := 0
+	for _, endpoint := range endpoints {
+		client, names := clients[endpoint], wanted[endpoint]
+		log.Printf("Downloading the LFS objects of %d files from %s", len(names), endpoint)
+		for start := 0; start < len(names); start += lfsBatchSize {
+			chunk := names[start:min(start+lfsBatchSize, len(names))]
+			n, err := resolveLFSBatch(ctx, client, dir, chunk, pointers)
+			downloaded += n
+			if err != nil {
+				return downloaded, err
+			}
+		}
+	}
+	return downloaded, nil
+}
+
+// resolveLFSBatch downloads the objects of the pointer files at the slash separated paths below
+// dir with one batch request, returning the number of objects downloaded
+func resolveLFSBatch(ctx context.Context, client *lfsClient, dir string, names []string, pointers map[string]lfsPointer) (int, error) {
+	var objects []lfsPointer
+	seen := map[string]bool{}
+	for _, name := range names {
+		if pointer := pointers[name]; !seen[pointer.OID] {
+			seen[pointer.OID] = true
+			objects = append(objects, pointer)
+		}
+	}
+	actions, err := client.batch(ctx, objects)
+	if err != nil {
+		return 0, err
+	}
+
+	downloaded := 0
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return downloaded, err
+		}
+		pointer := pointers[name]
+		action, ok := actions[pointer.OID]
+		if ok {
+			err = client.download(ctx, pointer, action, filepath.Join(dir, filepath.FromSlash(name)))
+		} else {
+			err = errors.New("object not available")
+		}
+		if err == nil {
+			downloaded++
+			continue
+		}
+		if ctx.Err() != nil {
+			return downloaded, ctx.Err()
+		}
+		log.Printf("skipping %s: %v", name, err)
+	}
+	return downloaded, nil
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lfsObject returns the pointer file of content
+func lfsObject(content string) (lfsPointer, string) {
+	sum := sha256.Sum256([]byte(content))
+	pointer := lfsPointer{OID: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+	return pointer, fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerVersion, pointer.OID, pointer.Size)
+}
+
+// newLFSServer starts a stand-in of an LFS server holding objects, keyed by OID. It counts the
+// objects requested through the batch API.
+func newLFSServer(t *testing.T, objects map[string]string, requested *int) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /info/lfs/objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "x-access-token" || token != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req lfsBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Operation != "download" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		*requested += len(req.Objects)
+		var resp []map[string]any
+		for _, o := range req.Objects {
+			object := map[string]any{"oid": o.OID, "size": o.Size}
+			if _, ok := objects[o.OID]; ok {
+				object["actions"] = map[string]any{"download": map[string]any{
+					"href":   server.URL + "/objects/" + o.OID,
+					"header": map[string]string{"Authorization": "RemoteAuth download"},
+				}}
+			} else {
+				object["error"] = map[string]any{"code": 404, "message": "Object does not exist"}
+			}
+			resp = append(resp, object)
+		}
+		w.Header().Set("Content-Type", lfsMediaType)
+		json.NewEncoder(w).Encode(map[string]any{"objects": resp})
+	})
+	mux.HandleFunc("GET /objects/{oid}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "RemoteAuth download" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		content, ok := objects[r.PathValue("oid")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveLFSPointers(t *testing.T) {
+	report, reportPointer := lfsObject("# Report\n\nQuarterly numbers.\n")
+	large, largePointer := lfsObject(strings.Repeat("x", 2048))
+	logo, logoPointer := lfsObject("\x89PNG image")
+	_, missingPointer := lfsObject("not on the server")
+	vendored, vendoredPointer := lfsObject("# Vendored\n")
+	objects := map[string]string{
+		report.OID: "# Report\n\nQuarterly numbers.\n",
+		large.OID:  strings.Repeat("x", 2048),
+		logo.OID:   "\x89PNG image",
+	}
+	requested := 0
+	server := newLFSServer(t, objects, &requested)
+	subRequested := 0
+	subServer := newLFSServer(t, map[string]string{vendored.OID: "# Vendored\n"}, &subRequested)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"notes.md":          "# Notes\n",
+		"docs/report.md":    reportPointer,
+		"docs/copy.md":      reportPointer,
+		"docs/large.md":     largePointer,
+		"assets/logo.png":   logoPointer,
+		"missing.md":        missingPointer,
+		"vendor/lib/doc.md": vendoredPointer,
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, name), content)
+	}
+
+	client := newLFSClient(server.URL+"/info/lfs", "secret")
+	subClient := newLFSClient(subServer.URL+"/info/lfs", "secret")
+	clientOf := func(p string) *lfsClient {
+		if strings.HasPrefix(p, "vendor/lib/") {
+			return subClient
+		}
+		return client
+	}
+	want := func(p string) bool { return strings.HasSuffix(p, ".md") }
+	opts := extractOptions{MaxFileSize: 1024, MaxTotalSize: 1 << 20}
+	downloaded, err := resolveLFSPointers(context.Background(), dir, opts, want, clientOf)
+	if err != nil {
+		t.Fatalf("resolveLFSPointers failed: %v", err)
+	}
+
+	wantFiles := map[string]string{
+		"notes.md":          "# Notes\n",
+		"docs/report.md":    "# Report\n\nQuarterly numbers.\n",
+		"docs/copy.md":      "# Report\n\nQuarterly numbers.\n",
+		"vendor/lib/doc.md": "# Vendored\n",
+		// Not wanted, over MaxFileSize and not on the server: the pointers are kept
+		"assets/logo.png": logoPointer,
+		"docs/large.md":   largePointer,
+		"missing.md":      missingPointer,
+	}
+	for name, content := range wantFiles {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != content {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, content)
+		}
+	}
+	if _, _, err := decodeFile("missing.md", []byte(missingPointer)); err == nil {
+		t.Error("decodeFile() read a pointer left in place as text")
+	}
+	if downloaded != 3 {
+		t.Errorf("downloaded %d objects, want 3", downloaded)
+	}
+	if requested != 2 || subRequested != 1 {
+		t.Errorf("requested %d and %d objects, want 2 and 1", requested, subRequested)
+	}
+
+	t.Run("total size", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, filepath.Join(dir, "docs/report.md"), reportPointer)
+		opts := extractOptions{MaxFileSize: 1024, MaxTotalSize: 10}
+		_, err := resolveLFSPointers(context.Background(), dir, opts, want, clientOf)
+		if !errors.Is(err, ErrArchiveTooLarge) {
+			t.Errorf("resolveLFSPointers() error = %v, want %v", err, ErrArchiveTooLarge)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, filepath.Join(dir, "docs/report.md"), reportPointer)
+		wrong := newLFSClient(server.URL+"/info/lfs", "wrong")
+		clientOf := func(string) *lfsClient { return wrong }
+		if _, err := resolveLFSPointers(context.Background(), dir, opts, want, clientOf); err == nil {
+			t.Error("resolveLFSPointers() succeeded with a wrong token")
+		}
+	})
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	pointer, content := lfsObject("content")
+	tests := []struct {
+		name string
+		data string
+		ok   bool
+	}{
+		{name: "pointer", data: content, ok: true},
+		{name: "text", data: "# Notes\n", ok: false},
+		{name: "missing size", data: fmt.Sprintf("%s\noid sha256:%s\n", lfsPointerVersion, pointer.OID), ok: false},
+		{name: "invalid oid", data: fmt.Sprintf("%s\noid sha256:abc\nsize 7\n", lfsPointerVersion), ok: false},
+		{name: "other hash", data: fmt.Sprintf("%s\noid md5:%s\nsize 7\n", lfsPointerVersion, pointer.OID), ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLFSPointer([]byte(tt.data))
+			if ok != tt.ok || (ok && got != pointer) {
+				t.Errorf("parseLFSPointer() = %+v, %v; want %+v, %v", got, ok, pointer, tt.ok)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
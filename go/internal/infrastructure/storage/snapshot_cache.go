@@ -0,0 +1,391 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// DefaultSnapshotCacheSize is the disk quota of the snapshot cache
+const DefaultSnapshotCacheSize = 2 << 30
+
+const (
+	snapshotMetadataFile = "snapshot.json"
+	snapshotCommitsFile  = "commits.json"
+	snapshotModulesFile  = "submodules.json"
+	snapshotTreeDir      = "tree"
+	snapshotTempPrefix   = ".tmp-"
+	// Incomplete snapshots older than this are left over from interrupted runs
+	staleSnapshotAge = time.Hour
+)
+
+// Snapshot is an extracted repository tree in the snapshot cache
+type Snapshot struct {
+	Repo       string    `json:"repo"`
+	Ref        string    `json:"ref"`
+	Commit     string    `json:"commit"`
+	Submodules bool      `json:"submodules,omitempty"` // Whether the tree includes the files of submodules
+	SizeBytes  int64     `json:"size_bytes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"-"` // Modification time of the metadata file
+	Dir        string    `json:"-"` // Directory holding the snapshot
+}
+
+// TreeDir returns the directory of the extracted files
+func (s *Snapshot) TreeDir() string {
+	return filepath.Join(s.Dir, snapshotTreeDir)
+}
+
+// Commits returns the last commits of the files recorded with SaveCommits
+func (s *Snapshot) Commits() (map[string]model.Commit, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, snapshotCommitsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]model.Commit{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	commits := map[string]model.Commit{}
+	if err := json.Unmarshal(data, &commits); err != nil {
+		return nil, fmt.Errorf("invalid commits of snapshot %s: %w", s.Dir, err)
+	}
+	return commits, nil
+}
+
+// SaveCommits records the last commits of the files, keyed by path. They never change for the
+// commit of the snapshot, so later runs do not look them up again.
+func (s *Snapshot) SaveCommits(commits map[string]model.Commit) error {
+	if err := s.writeJSON(snapshotCommitsFile, commits); err != nil {
+		return fmt.Errorf("error saving commits of snapshot: %w", err)
+	}
+	return nil
+}
+
+// submodules returns the submodules extracted into the tree, recorded with saveSubmodules
+func (s *Snapshot) submodules() ([]submodule, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, snapshotModulesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var modules []submodule
+	if err := json.Unmarshal(data, &modules); err != nil {
+		return nil, fmt.Errorf("invalid submodules of snapshot %s: %w", s.Dir, err)
+	}
+	return modules, nil
+}
+
+// saveSubmodules records the submodules extracted into the tree
+func (s *Snapshot) saveSubmodules(modules []submodule) error {
+	if err := s.writeJSON(snapshotModulesFile, modules); err != nil {
+		return fmt.Errorf("error saving submodules of snapshot: %w", err)
+	}
+	return nil
+}
+
+// updateSize measures the tree again and records its size, after files of the tree were replaced
+func (s *Snapshot) updateSize() error {
+	size, err := dirSize(s.TreeDir())
+	if err != nil {
+		return fmt.Errorf("error measuring snapshot: %w", err)
+	}
+	s.SizeBytes = size
+	if err := s.writeJSON(snapshotMetadataFile, s); err != nil {
+		return fmt.Errorf("error saving snapshot metadata: %w", err)
+	}
+	return nil
+}
+
+// writeJSON replaces the file name of the snapshot with v encoded as JSON
+func (s *Snapshot) writeJSON(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, snapshotTempPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, name))
+}
+
+// SnapshotCache keeps extracted repository trees keyed by repository, ref and commit, so that
+// syncs of the same commit share one download across commands and runs. Snapshots are evicted
+// in least recently used order when the cache exceeds its quota.
+type SnapshotCache struct {
+	dir     string
+	maxSize int64
+}
+
+// DefaultSnapshotCacheDir returns the directory of the snapshot cache in the user cache directory
+func DefaultSnapshotCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "personal-agent", "snapshots")
+}
+
+// NewSnapshotCache creates a snapshot cache in dir holding at most maxSize bytes.
+// An empty dir means DefaultSnapshotCacheDir and a zero maxSize DefaultSnapshotCacheSize.
+func NewSnapshotCache(dir string, maxSize int64) *SnapshotCache {
+	if dir == "" {
+		dir = DefaultSnapshotCacheDir()
+	}
+	if maxSize == 0 {
+		maxSize = DefaultSnapshotCacheSize
+	}
+	return &SnapshotCache{dir: dir, maxSize: maxSize}
+}
+
+// Dir returns the directory of the cache
+func (c *SnapshotCache) Dir() string {
+	return c.dir
+}
+
+// MaxSize returns the disk quota of the cache in bytes
+func (c *SnapshotCache) MaxSize() int64 {
+	return c.maxSize
+}
+
+// snapshotName returns the directory name of the snapshot of a commit
+func snapshotName(key Snapshot) string {
+	name := key.Repo + "\x00" + key.Ref + "\x00" + key.Commit
+	if key.Submodules {
+		name += "\x00submodules"
+	}
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Open returns the snapshot of the repository, ref and commit of key, including submodules when
+// key.Submodules is set, calling extract with a new directory to fill it when it is not cached yet.
+// Other snapshots are evicted afterwards to stay within the quota.
+func (c *SnapshotCache) Open(key Snapshot, extract func(dir string) error) (*Snapshot, error) {
+	dir := filepath.Join(c.dir, snapshotName(key))
+	snapshot, err := readSnapshot(dir)
+	if err == nil {
+		log.Printf("Using cached snapshot of %s at %s", key.Repo, shortCommit(key.Commit))
+		touchSnapshot(snapshot)
+		return snapshot, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("warning: discarding snapshot %s: %v", dir, err)
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("error removing snapshot: %w", err)
+		}
+	}
+
+	snapshot, err = c.create(dir, Snapshot{Repo: key.Repo, Ref: key.Ref, Commit: key.Commit, Submodules: key.Submodules}, extract)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.evict(c.maxSize, snapshot.Dir); err != nil {
+		log.Printf("warning: failed to evict snapshots: %v", err)
+	}
+	return snapshot, nil
+}
+
+// create extracts a snapshot into a temporary directory and renames it to dir once it is complete,
+// so that concurrent runs never read a partial snapshot
+func (c *SnapshotCache) create(dir string, snapshot Snapshot, extract func(dir string) error) (*Snapshot, error) {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating snapshot cache: %w", err)
+	}
+	tmp, err := os.MkdirTemp(c.dir, snapshotTempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot directory: %w", err)
+	}
+	defer os.RemoveAll(tmp) // Only removes anything when the snapshot is not renamed
+
+	tree := filepath.Join(tmp, snapshotTreeDir)
+	if err := os.Mkdir(tree, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating snapshot directory: %w", err)
+	}
+	if err := extract(tree); err != nil {
+		return nil, err
+	}
+	snapshot.SizeBytes, err = dirSize(tree)
+	if err != nil {
+		return nil, fmt.Errorf("error measuring snapshot: %w", err)
+	}
+	snapshot.CreatedAt = time.Now()
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, snapshotMetadataFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("error writing snapshot metadata: %w", err)
+	}
+
+	if err := os.Rename(tmp, dir); err != nil {
+		// Another run cached the same commit in the meantime
+		if existing, readErr := readSnapshot(dir); readErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("error storing snapshot: %w", err)
+	}
+	return readSnapshot(dir)
+}
+
+// readSnapshot reads the snapshot in dir; it fails with fs.ErrNotExist when it is not cached
+func readSnapshot(dir string) (*Snapshot, error) {
+	metadataPath := filepath.Join(dir, snapshotMetadataFile)
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot metadata: %w", err)
+	}
+	snapshot.Dir = dir
+	snapshot.LastUsedAt = info.ModTime()
+	return &snapshot, nil
+}
+
+// touchSnapshot records the use of a snapshot for the eviction order
+func touchSnapshot(snapshot *Snapshot) {
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(snapshot.Dir, snapshotMetadataFile), now, now); err != nil {
+		log.Printf("warning: failed to record the use of snapshot %s: %v", snapshot.Dir, err)
+		return
+	}
+	snapshot.LastUsedAt = now
+}
+
+// List returns the cached snapshots, most recently used first
+func (c *SnapshotCache) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot cache: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), snapshotTempPrefix) {
+			continue
+		}
+		snapshot, err := readSnapshot(filepath.Join(c.dir, entry.Name()))
+		if err != nil {
+			log.Printf("warning: skipping snapshot %s: %v", entry.Name(), err)
+			continue
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].LastUsedAt.After(snapshots[j].LastUsedAt)
+	})
+	return snapshots, nil
+}
+
+// Prune removes the least recently used snapshots until the cache holds at most maxSize bytes,
+// together with the leftovers of interrupted extractions. A zero maxSize empties the cache.
+func (c *SnapshotCache) Prune(maxSize int64) ([]Snapshot, error) {
+	c.removeStale()
+	return c.evict(maxSize, "")
+}
+
+// evict removes the least recently used snapshots other than keep until the cache holds at most maxSize bytes
+func (c *SnapshotCache) evict(maxSize int64, keep string) ([]Snapshot, error) {
+	snapshots, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, s := range snapshots {
+		total += s.SizeBytes
+	}
+
+	var removed []Snapshot
+	for i := len(snapshots) - 1; i >= 0 && total > maxSize; i-- {
+		s := snapshots[i]
+		if s.Dir == keep {
+			continue
+		}
+		if err := os.RemoveAll(s.Dir); err != nil {
+			return removed, fmt.Errorf("error removing snapshot %s: %w", s.Dir, err)
+		}
+		total -= s.SizeBytes
+		removed = append(removed, s)
+	}
+	return removed, nil
+}
+
+// removeStale removes the temporary directories of extractions that did not complete
+func (c *SnapshotCache) removeStale() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), snapshotTempPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < staleSnapshotAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(c.dir, entry.Name())); err != nil {
+			log.Printf("warning: failed to remove %s: %v", entry.Name(), err)
+		}
+	}
+}
+
+// dirSize returns the total size of the regular files below dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// shortCommit abbreviates a commit SHA for logs
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotCache(t *testing.T) {
+	// Each snapshot holds a single file of 10 bytes; the quota fits two of them
+	cache := NewSnapshotCache(t.TempDir(), 25)
+	extractions := 0
+	open := func(commit string) *Snapshot {
+		t.Helper()
+		snapshot, err := cache.Open(Snapshot{Repo: "owner/repo", Commit: commit}, func(dir string) error {
+			extractions++
+			return os.WriteFile(filepath.Join(dir, "a.md"), []byte(strings.Repeat(commit, 10)), 0o644)
+		})
+		if err != nil {
+			t.Fatalf("Open(%s) failed: %v", commit, err)
+		}
+		return snapshot
+	}
+
+	first := open("1")
+	if content, err := os.ReadFile(filepath.Join(first.TreeDir(), "a.md")); err != nil || string(content) != "1111111111" {
+		t.Fatalf("got snapshot content %q, %v", content, err)
+	}
+	if open("1"); extractions != 1 {
+		t.Errorf("cached commit was extracted %d times, want once", extractions)
+	}
+
+	// The metadata times only have to differ for the eviction order
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(first.Dir, snapshotMetadataFile), past, past)
+	open("2")
+	open("1") // Most recently used again
+	open("3") // Evicts 2, the least recently used
+
+	snapshots, err := cache.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var commits []string
+	for _, s := range snapshots {
+		commits = append(commits, s.Commit)
+	}
+	if got := strings.Join(commits, ","); got != "3,1" {
+		t.Errorf("got cached commits %s, want 3,1", got)
+	}
+
+	removed, err := cache.Prune(0)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("Prune removed %d snapshots, %v; want 2", len(removed), err)
+	}
+	if snapshots, _ := cache.List(); len(snapshots) != 0 {
+		t.Errorf("got %d snapshots after pruning, want 0", len(snapshots))
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/google/go-github/v58/github"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// maxSubmoduleDepth bounds how deep submodules of submodules are followed
+const maxSubmoduleDepth = 3
+
+// submodule is a GitHub repository extracted into the tree of another at its gitlink commit
+type submodule struct {
+	Path   string `json:"path"` // Slash separated path in the tree of the top repository
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Commit string `json:"commit"`
+}
+
+// parseGitModules returns the URLs of the submodules declared in a .gitmodules file, keyed by path
+func parseGitModules(data []byte) (map[string]string, error) {
+	modules := config.NewModules()
+	if err := modules.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	urls := make(map[string]string, len(modules.Submodules))
+	for _, m := range modules.Submodules {
+		if m.Path != "" && m.URL != "" {
+			urls[m.Path] = m.URL
+		}
+	}
+	return urls, nil
+}
+
+// submoduleRepo returns the GitHub repository of a submodule URL in owner/repo format. Relative
+// URLs are resolved against the repository of the parent.
+func submoduleRepo(url, parentOwner, parentRepo string) (string, error) {
+	if strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../") {
+		resolved := path.Join(parentOwner, parentRepo, url)
+		if strings.HasPrefix(resolved, "../") || resolved == ".." {
+			return "", fmt.Errorf("%w: %q leaves github.com", model.ErrInvalidRepository, url)
+		}
+		url = resolved
+	}
+	return model.ParseGitHubRepo(url)
+}
+
+// WithSubmodules extracts the GitHub submodules of the repository into its tree at their paths,
+// so that their files are synced as part of the store
+func (s *GitHubStorage) WithSubmodules(follow bool) *GitHubStorage {
+	s.followSubmodules = follow
+	return s
+}
+
+// extractSubmodules extracts the GitHub submodules declared in the .gitmodules file of the tree in
+// dir at the commits recorded by ref, following nested submodules up to maxSubmoduleDepth. prefix
+// is the path of the tree in the top repository, and skip reports whether a file at a path of the
+// tree is not needed. Submodules that cannot be read are logged and left empty.
+func (s *GitHubStorage) extractSubmodules(ctx context.Context, dir, prefix, ref string, skip func(string) bool, depth int) ([]submodule, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitmodules"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading .gitmodules: %w", err)
+	}
+	urls, err := parseGitModules(data)
+	if err != nil {
+		log.Printf("warning: skipping submodules of %s/%s: invalid .gitmodules: %v", s.repoOwner, s.repoName, err)
+		return nil, nil
+	}
+	paths := make([]string, 0, len(urls))
+	for p := range urls {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var extracted []submodule
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name, err := archiveEntryPath(p, 0)
+		if err != nil || name == "" {
+			log.Printf("warning: skipping submodule %q: invalid path", p)
+			continue
+		}
+		module, sub, err := s.submoduleAt(ctx, name, urls[p], ref)
+		if err != nil {
+			log.Printf("warning: skipping submodule %s: %v", path.Join(prefix, name), err)
+			continue
+		}
+		module.Path = path.Join(prefix, name)
+
+		var subSkip func(string) bool
+		if skip != nil {
+			subSkip = func(p string) bool { return skip(name + "/" + p) }
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		log.Printf("Extracting submodule %s (%s/%s at %s)", module.Path, module.Owner, module.Repo, shortCommit(module.Commit))
+		if err := sub.extractRepository(ctx, target, module.Commit, subSkip); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("warning: skipping submodule %s: %v", module.Path, err)
+			continue
+		}
+		extracted = append(extracted, module)
+
+		if depth < maxSubmoduleDepth {
+			nested, err := sub.extractSubmodules(ctx, target, module.Path, module.Commit, subSkip, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			extracted = append(extracted, nested...)
+		}
+	}
+	return extracted, nil
+}
+
+// submoduleAt resolves the submodule at the path of the tree of ref into its repository and
+// gitlink commit, returning a storage of the submodule repository
+func (s *GitHubStorage) submoduleAt(ctx context.Context, name, url, ref string) (submodule, *GitHubStorage, error) {
+	repo, err := submoduleRepo(url, s.repoOwner, s.repoName)
+	if err != nil {
+		return submodule{}, nil, err
+	}
+	entry, _, _, err := s.client.Repositories.GetContents(ctx, s.repoOwner, s.repoName, name, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return submodule{}, nil, fmt.Errorf("error getting the commit of the submodule: %w", err)
+	}
+	if entry == nil || entry.GetType() != "submodule" || entry.GetSHA() == "" {
+		return submodule{}, nil, errors.New("not a submodule in the tree")
+	}
+	owner, repoName, _ := strings.Cut(repo, "/")
+	return submodule{Owner: owner, Repo: repoName, Commit: entry.GetSHA()}, s.repository(owner, repoName), nil
+}
+
+// repository returns a storage of another GitHub repository sharing the client
+func (s *GitHubStorage) repository(owner, repo string) *GitHubStorage {
+	return &GitHubStorage{
+		client:    s.client,
+		repoOwner: owner,
+		repoName:  repo,
+	}
+}
+
+// submoduleOf returns the innermost extracted submodule holding the file at path, together with
+// the path of the file in the submodule
+func (s *GitHubStorage) submoduleOf(p string) (submodule, string, bool) {
+	return innermostSubmodule(s.submodules, p)
+}
+
+// innermostSubmodule returns the innermost of the modules holding the file at path, together
+// with the path of the file in the submodule
+func innermostSubmodule(modules []submodule, p string) (submodule, string, bool) {
+	var found submodule
+	for _, m := range modules {
+		if strings.HasPrefix(p, m.Path+"/") && len(m.Path) > len(found.Path) {
+			found = m
+		}
+	}
+	if found.Path == "" {
+		return submodule{}, "", false
+	}
+	return found, strings.TrimPrefix(p, found.Path+"/"), true
+}
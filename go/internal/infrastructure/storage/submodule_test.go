@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitModules(t *testing.T) {
+	data := []byte(`[submodule "handbook"]
+	path = docs/handbook
+	url = https://github.com/acme/handbook.git
+[submodule "shared"]
+	path = shared
+	url = ../shared-notes.git
+	branch = main
+`)
+	got, err := parseGitModules(data)
+	if err != nil {
+		t.Fatalf("parseGitModules failed: %v", err)
+	}
+	want := map[string]string{
+		"docs/handbook": "https://github.com/acme/handbook.git",
+		"shared":        "../shared-notes.git",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitModules() = %v, want %v", got, want)
+	}
+}
+
+func TestSubmoduleRepo(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "https://github.com/acme/handbook.git", want: "acme/handbook"},
+		{url: "git@github.com:acme/handbook.git", want: "acme/handbook"},
+		{url: "../shared-notes.git", want: "owner/shared-notes"},
+		{url: "../../other/notes", want: "other/notes"},
+		{url: "../../../escape", wantErr: true},
+		{url: "https://gitlab.com/acme/handbook.git", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := submoduleRepo(tt.url, "owner", "repo")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("submoduleRepo(%q) = %q, %v; want %q, error %v", tt.url, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSubmoduleOf(t *testing.T) {
+	s := &GitHubStorage{submodules: []submodule{
+		{Path: "docs", Owner: "acme", Repo: "docs"},
+		{Path: "docs/vendor/lib", Owner: "acme", Repo: "lib"},
+	}}
+	tests := []struct {
+		path     string
+		wantRepo string
+		wantPath string
+	}{
+		{path: "docs/guide.md", wantRepo: "docs", wantPath: "guide.md"},
+		{path: "docs/vendor/lib/README.md", wantRepo: "lib", wantPath: "README.md"},
+		{path: "docsite/index.md"},
+		{path: "README.md"},
+	}
+	for _, tt := range tests {
+		module, rel, ok := s.submoduleOf(tt.path)
+		if ok != (tt.wantRepo != "") || module.Repo != tt.wantRepo || rel != tt.wantPath {
+			t.Errorf("submoduleOf(%q) = %q, %q, %v; want %q, %q", tt.path, module.Repo, rel, ok, tt.wantRepo, tt.wantPath)
+		}
+	}
+}
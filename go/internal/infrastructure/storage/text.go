@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	xunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	port "github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// errBinaryFile is returned for files that are not text
+var errBinaryFile = errors.New("binary file")
+
+// utf16Sample is the number of leading bytes inspected to detect UTF-16 without a byte order mark
+const utf16Sample = 4096
+
+// decodeText detects the encoding of a text file and returns its content as UTF-8 together with
+// the encoding. A byte order mark decides between UTF-8 and UTF-16; otherwise the content is
+// UTF-8 if it is valid, and else the Japanese encoding that decodes it into the most Japanese
+// characters. A text without kana or kanji, e.g. with only full-width symbols, is decoded with an
+// encoding that decodes it without errors or penalties, preferring Shift_JIS. Files that are not
+// text fail with errBinaryFile, and texts in none of these encodings with port.ErrUndecodable.
+func decodeText(data []byte) (string, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return checkText(string(data[3:]), model.EncodingUTF8)
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeWith(data, xunicode.UTF16(xunicode.LittleEndian, xunicode.ExpectBOM), model.EncodingUTF16LE)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeWith(data, xunicode.UTF16(xunicode.BigEndian, xunicode.ExpectBOM), model.EncodingUTF16BE)
+	}
+	if enc, name := detectUTF16(data); enc != nil {
+		return decodeWith(data, enc, name)
+	}
+	if isBinary(data) {
+		return "", "", errBinaryFile
+	}
+	if utf8.Valid(data) {
+		return checkText(string(data), model.EncodingUTF8)
+	}
+
+	// Candidates are tried in order of preference, so that the first one wins a tie
+	best, bestName, bestScore := "", "", -1
+	for _, candidate := range []struct {
+		name     string
+		encoding encoding.Encoding
+	}{
+		{model.EncodingShiftJIS, japanese.ShiftJIS},
+		{model.EncodingEUCJP, japanese.EUCJP},
+	} {
+		decoded, err := decode(data, candidate.encoding)
+		if err != nil {
+			continue
+		}
+		if score := japaneseScore(decoded); score > bestScore {
+			best, bestName, bestScore = decoded, candidate.name, score
+		}
+	}
+	if bestName == "" {
+		return "", "", fmt.Errorf("%w: not UTF-8, UTF-16, Shift_JIS or EUC-JP", port.ErrUndecodable)
+	}
+	return checkText(best, bestName)
+}
+
+// decodeFile decodes the content of the file at path with decodeText. Git LFS pointers whose
+// object was not downloaded are skipped like binary files.
+func decodeFile(path string, data []byte) (string, string, error) {
+	if _, ok := parseLFSPointer(data); ok {
+		return "", "", fmt.Errorf("skipping Git LFS pointer whose object was not downloaded: %s", path)
+	}
+	content, name, err := decodeText(data)
+	if errors.Is(err, errBinaryFile) {
+		return "", "", fmt.Errorf("skipping binary file: %s", path)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	return content, name, nil
+}
+
+// decode converts data from enc to UTF-8, failing if any byte sequence is invalid in enc
+func decode(data []byte, enc encoding.Encoding) (string, error) {
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), data)
+	if err != nil {
+		return "", err
+	}
+	if bytes.ContainsRune(decoded, utf8.RuneError) {
+		return "", errors.New("invalid byte sequence")
+	}
+	return string(decoded), nil
+}
+
+// decodeWith decodes data with enc and checks that the result is text
+func decodeWith(data []byte, enc encoding.Encoding, name string) (string, string, error) {
+	decoded, err := decode(data, enc)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: invalid %s: %v", port.ErrUndecodable, name, err)
+	}
+	return checkText(decoded, name)
+}
+
+// checkText rejects decoded content with NUL characters, which text files do not contain
+func checkText(content, name string) (string, string, error) {
+	if strings.ContainsRune(content, 0) {
+		return "", "", errBinaryFile
+	}
+	return content, name, nil
+}
+
+// detectUTF16 detects UTF-16 text without a byte order mark from the zero bytes of the mostly
+// ASCII characters of western text: one byte of nearly every character is zero, always the same
+// one. It returns a nil encoding for other content.
+func detectUTF16(data []byte) (encoding.Encoding, string) {
+	sample := data[:min(len(data), utf16Sample)&^1]
+	if len(sample) < 2 {
+		return nil, ""
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenZeros++
+		}
+		if sample[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	chars := len(sample) / 2
+	switch {
+	case oddZeros*10 >= chars*7 && evenZeros*10 < chars:
+		return xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM), model.EncodingUTF16LE
+	case evenZeros*10 >= chars*7 && oddZeros*10 < chars:
+		return xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM), model.EncodingUTF16BE
+	default:
+		return nil, ""
+	}
+}
+
+// japaneseScore counts the kana and kanji of text, minus the half-width katakana and control
+// characters that decoding with the wrong Japanese encoding produces
+func japaneseScore(text string) int {
+	score := 0
+	for _, r := range text {
+		switch {
+		case r >= 0xFF61 && r <= 0xFF9F: // Half-width katakana
+			score -= 2
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han):
+			score++
+		case unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t':
+			score -= 2
+		}
+	}
+	return score
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	xunicode "golang.org/x/text/encoding/unicode"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	port "github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+func mustEncode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("encoding %q: %v", text, err)
+	}
+	return data
+}
+
+func TestDecodeText(t *testing.T) {
+	const note = "# 会議メモ\n\n今日の議題はリリース計画です。\n"
+	const ascii = "# Meeting notes\n\nRelease plan.\n"
+	const symbols = "（１）■ ①\n"
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantContent  string
+		wantEncoding string
+		wantErr      error
+	}{
+		{"utf-8", []byte(note), note, model.EncodingUTF8, nil},
+		{"utf-8 with bom", append([]byte{0xEF, 0xBB, 0xBF}, note...), note, model.EncodingUTF8, nil},
+		{"utf-16le with bom", mustEncode(t, xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM), note), note, model.EncodingUTF16LE, nil},
+		{"utf-16be with bom", mustEncode(t, xunicode.UTF16(xunicode.BigEndian, xunicode.UseBOM), note), note, model.EncodingUTF16BE, nil},
+		{"utf-16le without bom", mustEncode(t, xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM), ascii), ascii, model.EncodingUTF16LE, nil},
+		{"utf-16be without bom", mustEncode(t, xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM), ascii), ascii, model.EncodingUTF16BE, nil},
+		{"shift_jis", mustEncode(t, japanese.ShiftJIS, note), note, model.EncodingShiftJIS, nil},
+		{"euc-jp", mustEncode(t, japanese.EUCJP, note), note, model.EncodingEUCJP, nil},
+		{"shift_jis symbols only", mustEncode(t, japanese.ShiftJIS, symbols), symbols, model.EncodingShiftJIS, nil},
+		{"euc-jp symbols only", mustEncode(t, japanese.EUCJP, "（）■\n"), "（）■\n", model.EncodingEUCJP, nil},
+		{"latin-1", []byte("caf\xe9 cr\xe8me br\xfbl\xe9e\n"), "", "", port.ErrUndecodable},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "", "", errBinaryFile},
+		{"nul bytes", []byte("text\x00with\x00nul"), "", "", errBinaryFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, enc, err := decodeText(tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("decodeText() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeText() error = %v", err)
+			}
+			if content != tt.wantContent || enc != tt.wantEncoding {
+				t.Errorf("decodeText() = %q, %q, want %q, %q", content, enc, tt.wantContent, tt.wantEncoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffLine is a line of a line-based diff; op is ' ' for unchanged, '-' for removed and '+' for added lines
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// lineDiff compares a and b line by line
+func lineDiff(a, b string) []diffLine {
+	dmp := diffmatchpatch.New()
+	charsA, charsB, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(charsA, charsB, false), lines)
+
+	var result []diffLine
+	for _, d := range diffs {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line != "" {
+				result = append(result, diffLine{op: op, text: strings.TrimSuffix(line, "\n")})
+			}
+		}
+	}
+	return result
+}
+
+// unifiedDiff returns the unified diff from a to b with context unchanged lines around each
+// change, or an empty string if they are equal
+func unifiedDiff(fromName, toName, a, b string, context int) string {
+	lines := lineDiff(a, b)
+
+	// Line numbers of a and b before each line of the diff
+	oldLines, newLines := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if line.op != '+' {
+			oldLines[i+1]++
+		}
+		if line.op != '-' {
+			newLines[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(lines); {
+		for i < len(lines) && lines[i].op == ' ' {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		// Changes closer than twice the context share a hunk
+		last := i
+		for j := i; j < len(lines) && j-last <= 2*context; j++ {
+			if lines[j].op != ' ' {
+				last = j
+			}
+		}
+		start, end := max(i-context, 0), min(last+context+1, len(lines))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(oldLines[start], oldLines[end]-oldLines[start]),
+			hunkRange(newLines[start], newLines[end]-newLines[start]))
+		for _, line := range lines[start:end] {
+			out.WriteByte(line.op)
+			out.WriteString(line.text)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of a hunk that starts after line start and spans count lines
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
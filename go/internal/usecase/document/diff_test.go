@@ -0,0 +1,39 @@
+package document
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "a\nb\n", b: "a\nb\n", want: ""},
+		{
+			name: "changed line",
+			a:    "1\n2\n3\n4\n5\n",
+			b:    "1\n2\nthree\n4\n5\n",
+			want: "--- a\n+++ b\n@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n",
+		},
+		{
+			name: "distant changes get separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\neight\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+eight\n",
+		},
+		{
+			name: "new document",
+			a:    "",
+			b:    "# Title\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+# Title\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("a", "b", tt.a, tt.b, 1); got != tt.want {
+				t.Errorf("got diff\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package document
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+// diffContext is the number of unchanged lines shown around the changes of a diff
+const diffContext = 3
+
+type HistoryUsecase struct {
+	documentRepo repository.DocumentRepository
+	versionRepo  repository.DocumentVersionRepository
+}
+
+// NewHistoryUsecase creates a new HistoryUsecase instance
+func NewHistoryUsecase(documentRepo repository.DocumentRepository, versionRepo repository.DocumentVersionRepository) *HistoryUsecase {
+	return &HistoryUsecase{
+		documentRepo: documentRepo,
+		versionRepo:  versionRepo,
+	}
+}
+
+// History returns the kept versions of the document at path, oldest first, ending with its current content
+func (u *HistoryUsecase) History(ctx context.Context, storeID model.StoreId, path string) ([]*model.DocumentVersion, error) {
+	current, err := u.documentRepo.GetDocument(ctx, storeID, path)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := u.versionRepo.ListVersions(ctx, storeID, path)
+	if err != nil {
+		return nil, err
+	}
+	return append(versions, current.CurrentVersion(nextVersion(versions))), nil
+}
+
+// Diff returns the unified diff between two versions of the document at path. A zero to means the
+// current content and a zero from the version before to.
+func (u *HistoryUsecase) Diff(ctx context.Context, storeID model.StoreId, path string, from, to int) (*model.DocumentDiff, error) {
+	if from < 0 || to < 0 {
+		return nil, fmt.Errorf("version numbers must be positive")
+	}
+	current, err := u.documentRepo.GetDocument(ctx, storeID, path)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := u.versionRepo.ListVersions(ctx, storeID, path)
+	if err != nil {
+		return nil, err
+	}
+	currentNumber := nextVersion(versions)
+	if to == 0 {
+		to = currentNumber
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 {
+		return nil, fmt.Errorf("%s has no version before %d", path, to)
+	}
+
+	fromVersion, err := u.version(ctx, current, currentNumber, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := u.version(ctx, current, currentNumber, to)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DocumentDiff{
+		From: fromVersion,
+		To:   toVersion,
+		Diff: unifiedDiff(
+			fmt.Sprintf("%s@%d", fromVersion.Path, from),
+			fmt.Sprintf("%s@%d", toVersion.Path, to),
+			fromVersion.Content, toVersion.Content, diffContext,
+		),
+	}, nil
+}
+
+// version returns the version numbered number, which is the current content for currentNumber
+func (u *HistoryUsecase) version(ctx context.Context, current *model.Document, currentNumber, number int) (*model.DocumentVersion, error) {
+	switch {
+	case number == currentNumber:
+		return current.CurrentVersion(number), nil
+	case number > currentNumber:
+		return nil, fmt.Errorf("%w: %s@%d (the current version is %d)", repository.ErrDocumentVersionNotFound, current.Path, number, currentNumber)
+	default:
+		version, err := u.versionRepo.GetVersion(ctx, current.StoreId, current.Path, number)
+		if err != nil {
+			return nil, fmt.Errorf("%s@%d: %w", current.Path, number, err)
+		}
+		return version, nil
+	}
+}
+
+// nextVersion returns the number of the version after the last of versions
+func nextVersion(versions []*model.DocumentVersion) int {
+	if len(versions) == 0 {
+		return 1
+	}
+	return versions[len(versions)-1].Number + 1
+}
@@ -0,0 +1,29 @@
+package document
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type ListUsecase struct {
+	documentRepo repository.DocumentRepository
+}
+
+// NewListUsecase creates a new ListUsecase instance
+func NewListUsecase(documentRepo repository.DocumentRepository) *ListUsecase {
+	return &ListUsecase{
+		documentRepo: documentRepo,
+	}
+}
+
+// List returns the ingested documents matching the filter
+func (u *ListUsecase) List(ctx context.Context, filter repository.DocumentFilter) ([]*model.Document, error) {
+	documents, err := u.documentRepo.ListDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	return documents, nil
+}
@@ -0,0 +1,32 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type PruneVersionsUsecase struct {
+	versionRepo repository.DocumentVersionRepository
+}
+
+// NewPruneVersionsUsecase creates a new PruneVersionsUsecase instance
+func NewPruneVersionsUsecase(versionRepo repository.DocumentVersionRepository) *PruneVersionsUsecase {
+	return &PruneVersionsUsecase{
+		versionRepo: versionRepo,
+	}
+}
+
+// Prune removes the versions replaced longer than maxAge ago and the oldest versions above
+// maxVersions per document. It does nothing when both limits are zero.
+func (u *PruneVersionsUsecase) Prune(ctx context.Context, maxVersions int, maxAge time.Duration) (int, error) {
+	if maxVersions < 0 || maxAge < 0 {
+		return 0, fmt.Errorf("history limits must not be negative")
+	}
+	if maxVersions == 0 && maxAge == 0 {
+		return 0, nil
+	}
+	return u.versionRepo.Prune(ctx, maxVersions, maxAge)
+}
@@ -0,0 +1,23 @@
+package document
+
+import (
+	"context"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type ShowUsecase struct {
+	documentRepo repository.DocumentRepository
+}
+
+// NewShowUsecase creates a new ShowUsecase instance
+func NewShowUsecase(documentRepo repository.DocumentRepository) *ShowUsecase {
+	return &ShowUsecase{
+		documentRepo: documentRepo,
+	}
+}
+
+// Show returns the document stored at path in the given store
+func (u *ShowUsecase) Show(ctx context.Context, storeID model.StoreId, path string) (*model.Document, error) {
+	return u.documentRepo.GetDocument(ctx, storeID, path)
+}
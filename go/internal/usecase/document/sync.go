@@ -1,8 +1,11 @@
 package document
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
@@ -10,61 +13,130 @@ import (
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
 )
 
+// usageRecordTimeout bounds recording the usage of an interrupted sync
+const usageRecordTimeout = 5 * time.Second
+
+// DefaultBatchSize is the number of documents saved per transaction unless set with WithBatchSize
+const DefaultBatchSize = 100
+
 type SyncUsecase struct {
 	storeRepo              repository.StoreRepository
 	documentRepo           repository.DocumentRepository
 	storageFactoryProvider storage.StorageFactoryProvider
 	embeddingProvider      embedding.EmbeddingProvider
+	usageRepo              repository.UsageRepository
+	onEvent                model.SyncEventHandler
+	batchSize              int
 }
 
 // NewSyncUsecase creates a new SyncUsecase instance
-func NewSyncUsecase(storeRepo repository.StoreRepository, documentRepo repository.DocumentRepository, factoryProvider storage.StorageFactoryProvider, embeddingProvider embedding.EmbeddingProvider) *SyncUsecase {
+func NewSyncUsecase(storeRepo repository.StoreRepository, documentRepo repository.DocumentRepository, factoryProvider storage.StorageFactoryProvider, embeddingProvider embedding.EmbeddingProvider, usageRepo repository.UsageRepository) *SyncUsecase {
 	return &SyncUsecase{
 		storeRepo:              storeRepo,
 		documentRepo:           documentRepo,
 		storageFactoryProvider: factoryProvider,
 		embeddingProvider:      embeddingProvider,
+		usageRepo:              usageRepo,
+		batchSize:              DefaultBatchSize,
 	}
 }
 
-func (u *SyncUsecase) Sync(storeId string) error {
+// OnEvent registers a handler that receives progress events during Sync
+func (u *SyncUsecase) OnEvent(handler model.SyncEventHandler) *SyncUsecase {
+	u.onEvent = handler
+	return u
+}
+
+// WithBatchSize sets the number of documents saved per transaction; values below 1 are ignored
+func (u *SyncUsecase) WithBatchSize(size int) *SyncUsecase {
+	if size > 0 {
+		u.batchSize = size
+	}
+	return u
+}
+
+// emit sends an event to the registered handler, if any
+func (u *SyncUsecase) emit(event model.SyncEvent) {
+	if u.onEvent == nil {
+		return
+	}
+	event.Time = time.Now()
+	u.onEvent(event)
+}
+
+// Sync fetches all documents of the store and saves the new or changed ones with their embeddings.
+// For storages with revisions, only the paths changed since the last synced revision are fetched.
+// When ctx is done it stops before the next document and returns the partial result with ctx.Err().
+func (u *SyncUsecase) Sync(ctx context.Context, storeId string) (*model.SyncResult, error) {
 	// Convert string storeId to model.StoreId (uint)
 	var id model.StoreId
 	_, err := fmt.Sscanf(storeId, "%d", &id)
 	if err != nil {
-		return fmt.Errorf("invalid store ID format: %v", err)
+		return nil, fmt.Errorf("invalid store ID format: %v", err)
 	}
 
-	store, err := u.storeRepo.GetStore(id)
+	result := &model.SyncResult{StoreId: id, StartedAt: time.Now()}
+	result.Usage.Model = u.embeddingProvider.Model()
+
+	store, err := u.storeRepo.GetStore(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Get the appropriate storage factory for this store type
 	factory, err := u.storageFactoryProvider.GetFactory(store.Type())
 	if err != nil {
-		return fmt.Errorf("failed to get storage factory: %w", err)
+		return nil, fmt.Errorf("failed to get storage factory: %w", err)
 	}
 
 	// Create the storage instance
 	storage, err := factory.CreateStorage(store)
 	if err != nil {
-		return fmt.Errorf("failed to create storage: %w", err)
+		return nil, fmt.Errorf("failed to create storage: %w", err)
 	}
+	defer closeStorage(storage)
 
 	// Get all document entries from storage
-	entries, err := storage.GetDocumentEntries()
+	entries, err := storage.GetDocumentEntries(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get document entries: %w", err)
+		return nil, fmt.Errorf("failed to get document entries: %w", err)
+	}
+
+	filters := store.Filters()
+	var paths []string
+	for _, entry := range entries {
+		if filters.Match(entry.Path) {
+			paths = append(paths, entry.Path)
+		}
+	}
+	result.Total = len(paths)
+	u.emit(model.SyncEvent{Type: model.SyncEventStarted, Total: len(paths)})
+
+	// Documents whose last commit is unknown are looked up again even if they did not change
+	historyStorage, hasHistory := historyOf(storage)
+	withoutCommit := map[string]bool{}
+	if hasHistory {
+		if withoutCommit, err = u.documentRepo.FindPathsWithoutCommit(ctx, store.ID()); err != nil {
+			return nil, fmt.Errorf("failed to find documents without history: %w", err)
+		}
+	}
+
+	// Paths that did not change since the synced revision are not read again
+	if changed, ok := u.changedSince(ctx, store, storage); ok {
+		paths = u.skipUnchanged(result, paths, changed, withoutCommit)
 	}
 
 	// Fetch all documents from storage
 	var documents []*model.Document
 
-	for _, entry := range entries {
-		document, err := storage.FetchDocument(store.ID(), entry.Path)
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		document, err := storage.FetchDocument(ctx, store.ID(), path)
 		if err != nil {
-			log.Printf("failed to fetch document %s: %v", entry.Path, err)
+			log.Printf("failed to fetch document %s: %v", path, err)
+			u.failFetch(result, path, err)
 			continue
 		}
 		if document != nil {
@@ -74,43 +146,252 @@ func (u *SyncUsecase) Sync(storeId string) error {
 		}
 	}
 
-	// Find unchanged documents
-	existingSHAs, err := u.documentRepo.FindExistingSHAs(documents)
+	// Compare with the documents already stored for this store, keyed by path
+	stored, err := u.documentRepo.FindStoredSHAs(ctx, store.ID())
 	if err != nil {
-		return fmt.Errorf("failed to find unchanged documents: %w", err)
+		return nil, fmt.Errorf("failed to find unchanged documents: %w", err)
+	}
+	bodies, err := u.documentRepo.FindStoredBodySHAs(ctx, store.ID(), u.embeddingProvider.Model())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unchanged documents: %w", err)
 	}
 
-	// Create a map for quick lookup of unchanged document IDs
-	existingSHASet := make(map[string]bool, len(existingSHAs))
-	for _, sha := range existingSHAs {
-		existingSHASet[sha] = true
+	// Paths that still exist in the source, including the ones excluded by filters
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[entry.Path] = true
 	}
 
-	// Save only changed documents
-	savedCount := 0
+	// History is only looked up for new and changed documents and the ones without a last
+	// commit, all at once so that storages can share the lookups
+	var pending []*model.Document
 	for _, doc := range documents {
-		if doc == nil {
+		if stored.Unchanged(doc.Path, doc.SHA) && !withoutCommit[doc.Path] {
+			result.Unchanged++
+			u.emit(model.SyncEvent{Type: model.SyncEventUnchanged, Path: doc.Path})
 			continue
 		}
+		pending = append(pending, doc)
+	}
+	if hasHistory && ctx.Err() == nil {
+		u.setLastCommits(ctx, historyStorage, pending)
+	}
 
-		if !existingSHASet[doc.SHA] {
-			// create embedding
-			embedding, err := u.embeddingProvider.Embed(doc.Content)
-			if err != nil {
-				log.Printf("failed to create embedding for document %s: %v", doc.Path, err)
+	// Save only changed documents, in batches of one transaction each
+	var batch []*model.Document
+	for _, doc := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if stored.Unchanged(doc.Path, doc.SHA) {
+			// Only the last commit is saved, if it was found this time
+			if doc.LastCommit.SHA == "" {
+				result.Unchanged++
+				u.emit(model.SyncEvent{Type: model.SyncEventUnchanged, Path: doc.Path})
 				continue
 			}
-			doc.Embedding = embedding
-			if err := u.documentRepo.SaveDocument(doc); err != nil {
-				log.Printf("failed to save document %s: %v", doc.Path, err)
+		} else if from, ok := stored.TakeRenamed(doc.Path, doc.SHA, present); ok {
+			// A renamed document keeps its row and embedding
+			if err := u.documentRepo.MoveDocument(ctx, from, doc); err != nil {
+				log.Printf("failed to move document %s to %s: %v", from, doc.Path, err)
+				u.fail(result, doc.Path, "save", err)
 				continue
 			}
-			savedCount++
-		} else {
+			result.Moved++
+			u.emit(model.SyncEvent{Type: model.SyncEventMoved, Path: doc.Path, From: from})
+			continue
+		} else if !bodies.Unchanged(doc.Path, doc.BodySHA) && !u.embed(ctx, result, doc) {
+			// A document whose body did not change is saved without an embedding and keeps the stored one
+			continue
+		}
+		batch = append(batch, doc)
+		if len(batch) >= u.batchSize {
+			u.saveBatch(ctx, result, batch)
+			batch = nil
+		}
+	}
+	if ctx.Err() == nil {
+		u.saveBatch(ctx, result, batch)
+		batch = nil
+	}
+
+	result.FinishedAt = time.Now()
+	if err := ctx.Err(); err != nil {
+		// The embeddings of the unsaved batch are in the embedding cache for the next sync
+		log.Printf("sync interrupted: %d documents saved, %d moved, %d not saved", result.Saved, result.Moved, len(batch))
+		// The embeddings created so far are billed, so their usage is recorded although ctx is done
+		recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), usageRecordTimeout)
+		defer cancel()
+		if err := u.recordUsage(recordCtx, result); err != nil {
+			log.Printf("failed to record embedding usage: %v", err)
 		}
+		return result, fmt.Errorf("sync interrupted: %w", err)
 	}
 
-	log.Printf("sync completed: %d documents processed, %d documents saved", len(documents), savedCount)
+	log.Printf("sync completed: %d documents processed, %d documents saved (%d metadata only), %d moved", len(documents), result.Saved, result.MetadataOnly, result.Moved)
+
+	if err := u.recordUsage(ctx, result); err != nil {
+		return nil, fmt.Errorf("failed to record embedding usage: %w", err)
+	}
+	if err := u.storeRepo.MarkSynced(ctx, store.ID(), result.FinishedAt, syncedRevision(storage, result)); err != nil {
+		return nil, fmt.Errorf("failed to record sync time: %w", err)
+	}
+	u.emit(model.SyncEvent{Type: model.SyncEventCompleted, Total: result.Total})
+
+	return result, nil
+}
+
+// embed creates the embedding of the document; it reports false if the document failed
+func (u *SyncUsecase) embed(ctx context.Context, result *model.SyncResult, doc *model.Document) bool {
+	embedding, err := u.embeddingProvider.Embed(ctx, doc.EmbeddingText())
+	if err != nil {
+		log.Printf("failed to create embedding for document %s: %v", doc.Path, err)
+		u.fail(result, doc.Path, "embed", err)
+		return false
+	}
+	result.Usage.Add(embedding)
+	doc.Embedding = embedding.Vector
+	doc.TokenCount = embedding.InputTokens
+	if embedding.Truncated {
+		log.Printf("document %s has %d tokens; only the beginning was embedded", doc.Path, embedding.InputTokens)
+	}
+	doc.EmbeddingModel = u.embeddingProvider.Model()
+	return true
+}
+
+// closeStorage closes the storage, logging a failure because the sync result does not depend on it
+func closeStorage(source storage.Storage) {
+	if err := source.Close(); err != nil {
+		log.Printf("failed to close storage: %v", err)
+	}
+}
+
+// historyOf returns the storage as a HistoryStorage if it knows the history of its files
+func historyOf(source storage.Storage) (storage.HistoryStorage, bool) {
+	historyStorage, ok := source.(storage.HistoryStorage)
+	return historyStorage, ok
+}
+
+// setLastCommits sets the last commits of the documents found in the history of the storage.
+// A failed lookup is only logged: the other documents keep the modification time read from the
+// storage and an empty commit, so that the next sync looks them up again.
+func (u *SyncUsecase) setLastCommits(ctx context.Context, source storage.HistoryStorage, docs []*model.Document) {
+	if len(docs) == 0 {
+		return
+	}
+	paths := make([]string, len(docs))
+	for i, doc := range docs {
+		paths[i] = doc.Path
+	}
+	commits, err := source.LastCommits(ctx, paths)
+	if err != nil {
+		log.Printf("failed to find the last commits of %d of %d documents: %v", len(docs)-len(commits), len(docs), err)
+	}
+	for _, doc := range docs {
+		if commit, ok := commits[doc.Path]; ok {
+			doc.SetLastCommit(commit)
+		}
+	}
+}
+
+// changedSince returns the paths changed since the revision of the last sync. It returns false
+// when every path has to be read: the storage has no revisions, the store was never synced or
+// the changes cannot be listed.
+func (u *SyncUsecase) changedSince(ctx context.Context, store model.DocumentStore, source storage.Storage) (map[string]bool, bool) {
+	revisionStorage, ok := source.(storage.RevisionStorage)
+	if !ok {
+		return nil, false
+	}
+	revisionStore, ok := store.(model.RevisionStore)
+	if !ok || revisionStore.SyncedRevision() == "" {
+		return nil, false
+	}
+
+	paths, err := revisionStorage.ChangedPaths(ctx, revisionStore.SyncedRevision())
+	if err != nil {
+		log.Printf("failed to list the paths changed since %s, reading all documents: %v", revisionStore.SyncedRevision(), err)
+		return nil, false
+	}
+	changed := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		changed[p] = true
+	}
+	return changed, true
+}
+
+// skipUnchanged returns the changed paths and the paths without a last commit, and counts the
+// other paths as unchanged
+func (u *SyncUsecase) skipUnchanged(result *model.SyncResult, paths []string, changed, withoutCommit map[string]bool) []string {
+	var read []string
+	for _, p := range paths {
+		if changed[p] || withoutCommit[p] {
+			read = append(read, p)
+			continue
+		}
+		result.Unchanged++
+		u.emit(model.SyncEvent{Type: model.SyncEventUnchanged, Path: p})
+	}
+	return read
+}
+
+// syncedRevision returns the revision to record for the next sync. It is empty unless the storage
+// has revisions and every document was saved, so that failed documents are read again.
+func syncedRevision(source storage.Storage, result *model.SyncResult) string {
+	revisionStorage, ok := source.(storage.RevisionStorage)
+	if !ok || result.Failed > 0 {
+		return ""
+	}
+	return revisionStorage.Revision()
+}
+
+// saveBatch saves the documents in one transaction; if it fails, every document of the batch fails
+func (u *SyncUsecase) saveBatch(ctx context.Context, result *model.SyncResult, batch []*model.Document) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := u.documentRepo.SaveDocuments(ctx, batch); err != nil {
+		log.Printf("failed to save %d documents: %v", len(batch), err)
+		for _, doc := range batch {
+			u.fail(result, doc.Path, "save", err)
+		}
+		return
+	}
+	for _, doc := range batch {
+		result.Saved++
+		if doc.Embedding == nil {
+			result.MetadataOnly++
+		}
+		u.emit(model.SyncEvent{Type: model.SyncEventSaved, Path: doc.Path})
+	}
+}
+
+// recordUsage persists the embedding usage of the sync run
+func (u *SyncUsecase) recordUsage(ctx context.Context, result *model.SyncResult) error {
+	return u.usageRepo.RecordUsage(ctx, &model.UsageRecord{
+		Source:     model.UsageSourceDocument,
+		StoreId:    result.StoreId,
+		Model:      result.Usage.Model,
+		Requests:   result.Usage.Requests,
+		Tokens:     result.Usage.Tokens,
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.FinishedAt,
+	})
+}
+
+// failFetch records a document that could not be fetched, reporting text files in an
+// undetected encoding separately from other failures
+func (u *SyncUsecase) failFetch(result *model.SyncResult, path string, err error) {
+	if errors.Is(err, storage.ErrUndecodable) {
+		result.Undecodable = append(result.Undecodable, path)
+		u.fail(result, path, "decode", err)
+		return
+	}
+	u.fail(result, path, "fetch", err)
+}
 
-	return nil
+// fail records a failed entry and emits a failed event
+func (u *SyncUsecase) fail(result *model.SyncResult, path, stage string, err error) {
+	result.Failed++
+	u.emit(model.SyncEvent{Type: model.SyncEventFailed, Path: path, Stage: stage, Error: err})
 }
@@ -0,0 +1,221 @@
+package document
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// fakeStoreRepository returns GitHub stores with the requested ID; other methods are not used
+type fakeStoreRepository struct {
+	repository.StoreRepository
+}
+
+func (r *fakeStoreRepository) GetStore(_ context.Context, id model.StoreId) (model.DocumentStore, error) {
+	return model.NewGitHubStore(id, "owner/repo"), nil
+}
+
+func (r *fakeStoreRepository) MarkSynced(_ context.Context, _ model.StoreId, _ time.Time, _ string) error {
+	return nil
+}
+
+// fakeDocumentRepository keeps the saved documents keyed by store and path and, as the database
+// does, the stored embedding of a document saved without one; other methods are not used
+type fakeDocumentRepository struct {
+	repository.DocumentRepository
+	documents map[model.StoreId]map[string]*model.Document
+	moves     int
+}
+
+func (r *fakeDocumentRepository) SaveDocuments(_ context.Context, documents []*model.Document) error {
+	for _, doc := range documents {
+		if r.documents[doc.StoreId] == nil {
+			r.documents[doc.StoreId] = map[string]*model.Document{}
+		}
+		saved := *doc
+		if stored, ok := r.documents[doc.StoreId][doc.Path]; ok && doc.Embedding == nil {
+			saved.Embedding, saved.EmbeddingModel = stored.Embedding, stored.EmbeddingModel
+		}
+		r.documents[doc.StoreId][doc.Path] = &saved
+	}
+	return nil
+}
+
+func (r *fakeDocumentRepository) FindStoredSHAs(_ context.Context, storeId model.StoreId) (model.StoredSHAs, error) {
+	shas := model.StoredSHAs{}
+	for path, doc := range r.documents[storeId] {
+		shas[path] = doc.SHA
+	}
+	return shas, nil
+}
+
+func (r *fakeDocumentRepository) FindStoredBodySHAs(_ context.Context, storeId model.StoreId, embeddingModel string) (model.StoredSHAs, error) {
+	shas := model.StoredSHAs{}
+	for path, doc := range r.documents[storeId] {
+		if doc.Embedding != nil && doc.EmbeddingModel == embeddingModel {
+			shas[path] = doc.BodySHA
+		}
+	}
+	return shas, nil
+}
+
+func (r *fakeDocumentRepository) MoveDocument(_ context.Context, from string, document *model.Document) error {
+	stored := r.documents[document.StoreId][from]
+	delete(r.documents[document.StoreId], from)
+	stored.Path = document.Path
+	r.documents[document.StoreId][document.Path] = stored
+	r.moves++
+	return nil
+}
+
+// fakeStorage serves files keyed by path, the same for every store; other methods are not used
+type fakeStorage struct {
+	storage.Storage
+	files map[string]string
+}
+
+func (s *fakeStorage) GetDocumentEntries(_ context.Context) ([]model.DocumentEntry, error) {
+	var entries []model.DocumentEntry
+	for path := range s.files {
+		entries = append(entries, model.DocumentEntry{Path: path})
+	}
+	return entries, nil
+}
+
+func (s *fakeStorage) FetchDocument(_ context.Context, storeId model.StoreId, path string) (*model.Document, error) {
+	doc := &model.Document{StoreId: storeId, Path: path, Content: s.files[path]}
+	doc.SetContentHashes()
+	return doc, nil
+}
+
+func (s *fakeStorage) Close() error { return nil }
+
+func (s *fakeStorage) CreateStorage(_ model.DocumentStore) (storage.Storage, error) {
+	return s, nil
+}
+
+func (s *fakeStorage) GetFactory(_ string) (storage.StorageFactory, error) {
+	return s, nil
+}
+
+// fakeEmbeddingProvider counts the embedded texts of a configurable model
+type fakeEmbeddingProvider struct {
+	model string
+	calls int
+}
+
+func (p *fakeEmbeddingProvider) Embed(_ context.Context, _ string) (*model.EmbeddingResult, error) {
+	p.calls++
+	return &model.EmbeddingResult{Vector: []float64{1, 0}, Tokens: 3, InputTokens: 3}, nil
+}
+
+func (p *fakeEmbeddingProvider) Model() string { return p.model }
+
+// fakeUsageRepository discards the usage records
+type fakeUsageRepository struct {
+	repository.UsageRepository
+}
+
+func (r *fakeUsageRepository) RecordUsage(_ context.Context, _ *model.UsageRecord) error {
+	return nil
+}
+
+// newSyncFakes returns a sync usecase over fakes, serving files for every store
+func newSyncFakes(files map[string]string) (*SyncUsecase, *fakeDocumentRepository, *fakeEmbeddingProvider) {
+	repo := &fakeDocumentRepository{documents: map[model.StoreId]map[string]*model.Document{}}
+	provider := &fakeEmbeddingProvider{model: "test-model"}
+	usecase := NewSyncUsecase(&fakeStoreRepository{}, repo, &fakeStorage{files: files}, provider, &fakeUsageRepository{})
+	return usecase, repo, provider
+}
+
+func TestSyncEmbedsChangedBodies(t *testing.T) {
+	files := map[string]string{
+		"note.md": "---\ntags: [a]\n---\nBody.\n",
+		"meta.md": "---\ntitle: Only metadata\n---\n",
+	}
+	usecase, repo, provider := newSyncFakes(files)
+	if _, err := usecase.Sync(context.Background(), "1"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	// Each step changes one file and syncs again
+	steps := []struct {
+		name      string
+		path      string
+		content   string
+		model     string
+		wantEmbed bool
+	}{
+		{name: "frontmatter change", path: "note.md", content: "---\ntags: [a, b]\n---\nBody.\n"},
+		{name: "body change", path: "note.md", content: "---\ntags: [a, b]\n---\nOther body.\n", wantEmbed: true},
+		{name: "model change", path: "note.md", content: "---\ntags: [c]\n---\nOther body.\n", model: "new-model", wantEmbed: true},
+		{name: "frontmatter change without a body", path: "meta.md", content: "---\ntitle: Changed\n---\n", wantEmbed: true},
+	}
+	for _, step := range steps {
+		files[step.path] = step.content
+		if step.model != "" {
+			provider.model = step.model
+		}
+		calls := provider.calls
+
+		result, err := usecase.Sync(context.Background(), "1")
+		if err != nil {
+			t.Fatalf("%s: Sync failed: %v", step.name, err)
+		}
+		if embedded := provider.calls > calls; embedded != step.wantEmbed {
+			t.Errorf("%s: embedded = %v, want %v", step.name, embedded, step.wantEmbed)
+		}
+		if result.Saved != 1 || (result.MetadataOnly == 1) == step.wantEmbed {
+			t.Errorf("%s: got %d saved, %d metadata only", step.name, result.Saved, result.MetadataOnly)
+		}
+		stored := repo.documents[1][step.path]
+		if stored.Content != step.content || stored.Embedding == nil || stored.EmbeddingModel != provider.model {
+			t.Errorf("%s: got stored content %q with model %q", step.name, stored.Content, stored.EmbeddingModel)
+		}
+	}
+	if provider.calls != 5 {
+		t.Errorf("got %d embeddings, want 5", provider.calls)
+	}
+}
+
+func TestSyncStoresAndRenames(t *testing.T) {
+	files := map[string]string{"a.md": "# Shared\n\nSame content in both stores.\n"}
+	usecase, repo, provider := newSyncFakes(files)
+
+	// The same content is saved in every store that holds it
+	for _, id := range []string{"1", "2"} {
+		if result, err := usecase.Sync(context.Background(), id); err != nil || result.Saved != 1 {
+			t.Fatalf("store %s: got result %+v, error %v", id, result, err)
+		}
+	}
+	for _, id := range []model.StoreId{1, 2} {
+		if _, ok := repo.documents[id]["a.md"]; !ok {
+			t.Errorf("store %d: a.md not saved", id)
+		}
+	}
+
+	// A renamed file is moved with its embedding instead of being embedded again
+	files["b.md"] = files["a.md"]
+	delete(files, "a.md")
+	calls := provider.calls
+	result, err := usecase.Sync(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Moved != 1 || repo.moves != 1 || provider.calls != calls {
+		t.Errorf("got %d moved, %d moves and %d embeddings, want 1, 1 and 0", result.Moved, repo.moves, provider.calls-calls)
+	}
+	if _, ok := repo.documents[1]["a.md"]; ok {
+		t.Error("a.md is still stored after the rename")
+	}
+	if doc, ok := repo.documents[1]["b.md"]; !ok || doc.Embedding == nil {
+		t.Error("b.md is not stored with the embedding of a.md")
+	}
+	if _, ok := repo.documents[2]["a.md"]; !ok {
+		t.Error("the rename in store 1 changed store 2")
+	}
+}
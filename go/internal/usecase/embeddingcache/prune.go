@@ -0,0 +1,31 @@
+package embeddingcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type PruneUsecase struct {
+	cacheRepo repository.EmbeddingCacheRepository
+}
+
+func NewPruneUsecase(cacheRepo repository.EmbeddingCacheRepository) *PruneUsecase {
+	return &PruneUsecase{
+		cacheRepo: cacheRepo,
+	}
+}
+
+// Prune removes the entries unused for longer than maxAge and the least recently used
+// entries above maxEntries. It does nothing when both limits are zero.
+func (u *PruneUsecase) Prune(ctx context.Context, maxEntries int, maxAge time.Duration) (int, error) {
+	if maxEntries < 0 || maxAge < 0 {
+		return 0, fmt.Errorf("cache limits must not be negative")
+	}
+	if maxEntries == 0 && maxAge == 0 {
+		return 0, nil
+	}
+	return u.cacheRepo.Evict(ctx, maxEntries, maxAge)
+}
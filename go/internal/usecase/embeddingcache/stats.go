@@ -0,0 +1,22 @@
+package embeddingcache
+
+import (
+	"context"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type StatsUsecase struct {
+	cacheRepo repository.EmbeddingCacheRepository
+}
+
+func NewStatsUsecase(cacheRepo repository.EmbeddingCacheRepository) *StatsUsecase {
+	return &StatsUsecase{
+		cacheRepo: cacheRepo,
+	}
+}
+
+// Stats returns the size and hit count of the embedding cache
+func (u *StatsUsecase) Stats(ctx context.Context) (*model.EmbeddingCacheStats, error) {
+	return u.cacheRepo.Stats(ctx)
+}
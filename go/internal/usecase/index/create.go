@@ -0,0 +1,40 @@
+package index
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type CreateUsecase struct {
+	indexRepo repository.IndexRepository
+}
+
+func NewCreateUsecase(indexRepo repository.IndexRepository) *CreateUsecase {
+	return &CreateUsecase{
+		indexRepo: indexRepo,
+	}
+}
+
+// Create builds the vector index described by spec, replacing the existing index of the table.
+// For IVFFlat indexes without a number of lists, it is derived from the number of embedded rows.
+func (u *CreateUsecase) Create(ctx context.Context, spec model.VectorIndexSpec) (*model.VectorIndex, error) {
+	if spec.Method == model.IndexMethodIVFFlat && spec.Lists == 0 {
+		current, err := u.indexRepo.GetVectorIndex(ctx, spec.Table)
+		if err != nil {
+			return nil, err
+		}
+		spec.Lists = model.RecommendedLists(current.EmbeddedRows)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := u.indexRepo.CreateVectorIndex(ctx, spec); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return u.indexRepo.GetVectorIndex(ctx, spec.Table)
+}
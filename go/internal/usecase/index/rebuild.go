@@ -0,0 +1,26 @@
+package index
+
+import (
+	"context"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type RebuildUsecase struct {
+	indexRepo repository.IndexRepository
+}
+
+func NewRebuildUsecase(indexRepo repository.IndexRepository) *RebuildUsecase {
+	return &RebuildUsecase{
+		indexRepo: indexRepo,
+	}
+}
+
+// Rebuild rebuilds the vector index of the table with its current parameters,
+// e.g. to repair an invalid index or reclaim space after many updates
+func (u *RebuildUsecase) Rebuild(ctx context.Context, table string) (*model.VectorIndex, error) {
+	if err := u.indexRepo.RebuildVectorIndex(ctx, table); err != nil {
+		return nil, err
+	}
+	return u.indexRepo.GetVectorIndex(ctx, table)
+}
@@ -0,0 +1,61 @@
+package index
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type StatusUsecase struct {
+	indexRepo repository.IndexRepository
+}
+
+func NewStatusUsecase(indexRepo repository.IndexRepository) *StatusUsecase {
+	return &StatusUsecase{
+		indexRepo: indexRepo,
+	}
+}
+
+// IndexStatus is the vector index of a table together with problems found in it
+type IndexStatus struct {
+	Index    *model.VectorIndex
+	Warnings []string
+}
+
+// Status returns the state of the vector index of every table
+func (u *StatusUsecase) Status(ctx context.Context) ([]IndexStatus, error) {
+	statuses := make([]IndexStatus, 0, len(model.VectorTables))
+	for _, table := range model.VectorTables {
+		index, err := u.indexRepo.GetVectorIndex(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, IndexStatus{Index: index, Warnings: checkHealth(index)})
+	}
+	return statuses, nil
+}
+
+// checkHealth returns the problems that make similarity queries on the table slow or inaccurate
+func checkHealth(index *model.VectorIndex) []string {
+	var warnings []string
+	if !index.Exists() {
+		if index.EmbeddedRows > 0 {
+			warnings = append(warnings, "no vector index: similarity queries scan the whole table")
+		}
+		return warnings
+	}
+	if !index.Valid {
+		warnings = append(warnings, "index is invalid after an interrupted build and is not used by queries")
+	}
+	if index.Method == model.IndexMethodIVFFlat {
+		// IVFFlat clusters are computed at build time and degrade as the table grows or shrinks
+		recommended := model.RecommendedLists(index.EmbeddedRows)
+		if index.Lists > 2*recommended || 2*index.Lists < recommended {
+			warnings = append(warnings, fmt.Sprintf("built with %d lists but %d are recommended for %d rows",
+				index.Lists, recommended, index.EmbeddedRows))
+		}
+	}
+	return warnings
+}
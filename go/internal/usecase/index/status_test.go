@@ -0,0 +1,71 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// fakeIndexRepository keeps one index per table in memory
+type fakeIndexRepository struct {
+	indexes map[string]*model.VectorIndex
+	created []model.VectorIndexSpec
+}
+
+func (r *fakeIndexRepository) GetVectorIndex(_ context.Context, table string) (*model.VectorIndex, error) {
+	if index, ok := r.indexes[table]; ok {
+		return index, nil
+	}
+	return &model.VectorIndex{Table: table}, nil
+}
+
+func (r *fakeIndexRepository) CreateVectorIndex(_ context.Context, spec model.VectorIndexSpec) error {
+	r.created = append(r.created, spec)
+	return nil
+}
+
+func (r *fakeIndexRepository) RebuildVectorIndex(_ context.Context, table string) error { return nil }
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name     string
+		index    model.VectorIndex
+		warnings int
+	}{
+		{"empty table without index", model.VectorIndex{}, 0},
+		{"missing index", model.VectorIndex{EmbeddedRows: 10}, 1},
+		{"healthy hnsw", model.VectorIndex{Name: "idx", Method: model.IndexMethodHNSW, Valid: true, EmbeddedRows: 100000}, 0},
+		{"invalid index", model.VectorIndex{Name: "idx", Method: model.IndexMethodHNSW, EmbeddedRows: 100}, 1},
+		{"ivfflat lists in range", model.VectorIndex{Name: "idx", Method: model.IndexMethodIVFFlat, Valid: true, Lists: 80, EmbeddedRows: 100000}, 0},
+		{"ivfflat built on a small table", model.VectorIndex{Name: "idx", Method: model.IndexMethodIVFFlat, Valid: true, Lists: 1, EmbeddedRows: 100000}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkHealth(&tt.index)
+			if len(got) != tt.warnings {
+				t.Errorf("got warnings %v, want %d", got, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestCreate_DerivesLists(t *testing.T) {
+	repo := &fakeIndexRepository{indexes: map[string]*model.VectorIndex{
+		"documents": {Table: "documents", EmbeddedRows: 120000},
+	}}
+
+	_, err := NewCreateUsecase(repo).Create(context.Background(), model.VectorIndexSpec{Table: "documents", Method: model.IndexMethodIVFFlat})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.created) != 1 || repo.created[0].Lists != 120 {
+		t.Errorf("got %+v, want one index with 120 lists", repo.created)
+	}
+
+	_, err = NewCreateUsecase(repo).Create(context.Background(), model.VectorIndexSpec{Table: "stores", Method: model.IndexMethodHNSW, M: 16, EfConstruction: 64})
+	if err == nil {
+		t.Error("expected an error for a table without embeddings")
+	}
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// ErrStalePlan is returned for a merge whose memories changed after the plan was made
+var ErrStalePlan = errors.New("memory changed since the plan was made")
+
+// ConsolidationResult is the outcome of one merge of a plan
+type ConsolidationResult struct {
+	Merge   model.MemoryMerge
+	Applied bool
+	Err     error
+}
+
+type ConsolidateUsecase struct {
+	memoryRepo    repository.MemoryRepository
+	saveUsecase   *SaveUsecase
+	deleteUsecase *DeleteUsecase
+}
+
+// NewConsolidateUsecase creates a new ConsolidateUsecase instance
+func NewConsolidateUsecase(memoryRepo repository.MemoryRepository, memoryStorageFactory storage.MemoryStorageFactory, embeddingProvider embedding.EmbeddingProvider, usageRepo repository.UsageRepository) *ConsolidateUsecase {
+	return &ConsolidateUsecase{
+		memoryRepo:    memoryRepo,
+		saveUsecase:   NewSaveUsecase(memoryRepo, memoryStorageFactory, embeddingProvider, usageRepo),
+		deleteUsecase: NewDeleteUsecase(memoryRepo, memoryStorageFactory),
+	}
+}
+
+// Plan clusters the active memories whose embeddings are at least threshold similar and
+// proposes to merge every cluster into its most recently modified memory. Nothing is written.
+func (u *ConsolidateUsecase) Plan(ctx context.Context, threshold float64) (*model.ConsolidationPlan, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, fmt.Errorf("invalid threshold %v: must be greater than 0 and at most 1", threshold)
+	}
+
+	pairs, err := u.memoryRepo.FindSimilarMemories(ctx, threshold)
+	if err != nil {
+		return nil, err
+	}
+	plan := &model.ConsolidationPlan{Threshold: threshold, CreatedAt: time.Now()}
+	clusters := model.ClusterMemories(pairs)
+	if len(clusters) == 0 {
+		return plan, nil
+	}
+
+	memories, err := u.memoryRepo.ListMemories(ctx, repository.MemoryFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	byPath := make(map[string]*model.Memory, len(memories))
+	for _, m := range memories {
+		byPath[m.Path] = m
+	}
+	similarity := make(map[[2]string]float64, len(pairs))
+	for _, p := range pairs {
+		similarity[[2]string{p.PathA, p.PathB}] = p.Similarity
+		similarity[[2]string{p.PathB, p.PathA}] = p.Similarity
+	}
+
+	for _, cluster := range clusters {
+		members := make([]*model.Memory, 0, len(cluster))
+		for _, path := range cluster {
+			if m, ok := byPath[path]; ok {
+				members = append(members, m)
+			}
+		}
+		if len(members) < 2 {
+			continue
+		}
+
+		// The newest memory has the most up to date wording
+		keep := members[0]
+		for _, m := range members[1:] {
+			if m.ModifiedAt.After(keep.ModifiedAt) {
+				keep = m
+			}
+		}
+
+		merge := model.MemoryMerge{Keep: keep.Path, Similarity: 1, SHAs: make(map[string]string, len(members))}
+		var others []*model.Memory
+		for i, m := range members {
+			merge.SHAs[m.Path] = m.SHA
+			for _, n := range members[i+1:] {
+				merge.Similarity = min(merge.Similarity, similarity[[2]string{m.Path, n.Path}])
+			}
+			if m != keep {
+				others = append(others, m)
+				merge.Merge = append(merge.Merge, m.Path)
+			}
+		}
+		if merge.Content, err = model.MergeMemories(keep, others); err != nil {
+			return nil, err
+		}
+		plan.Merges = append(plan.Merges, merge)
+	}
+	return plan, nil
+}
+
+// Apply performs the merges of a reviewed plan: the merged content is written to the kept memory
+// in the memory repository and the database, then the merged memories are deleted from both.
+// A merge is skipped with ErrStalePlan if one of its memories changed after the plan was made.
+// The other merges are still applied when one fails; the returned error counts the failures.
+func (u *ConsolidateUsecase) Apply(ctx context.Context, plan *model.ConsolidationPlan) ([]ConsolidationResult, error) {
+	results := make([]ConsolidationResult, 0, len(plan.Merges))
+	failed := 0
+	for _, merge := range plan.Merges {
+		if ctx.Err() != nil {
+			break
+		}
+		err := u.applyMerge(ctx, merge)
+		if err != nil {
+			log.Printf("failed to merge memories into %s: %v", merge.Keep, err)
+			failed++
+		}
+		results = append(results, ConsolidationResult{Merge: merge, Applied: err == nil, Err: err})
+	}
+
+	if err := ctx.Err(); err != nil {
+		return results, fmt.Errorf("consolidation interrupted: %w", err)
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d merges failed", failed, len(plan.Merges))
+	}
+	return results, nil
+}
+
+// applyMerge checks that the memories of merge are unchanged, then writes and deletes them
+func (u *ConsolidateUsecase) applyMerge(ctx context.Context, merge model.MemoryMerge) error {
+	for _, path := range append([]string{merge.Keep}, merge.Merge...) {
+		m, err := u.memoryRepo.GetMemory(ctx, path)
+		if errors.Is(err, repository.ErrMemoryNotFound) {
+			return fmt.Errorf("%s was deleted: %w", path, ErrStalePlan)
+		}
+		if err != nil {
+			return err
+		}
+		if m.SHA != merge.SHAs[path] || !m.ArchivedAt.IsZero() {
+			return fmt.Errorf("%s: %w", path, ErrStalePlan)
+		}
+	}
+
+	if _, err := u.saveUsecase.Save(ctx, merge.Keep, merge.Content); err != nil {
+		return err
+	}
+	for _, path := range merge.Merge {
+		if err := u.deleteUsecase.Delete(ctx, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+// fakeSimilarMemoryRepository returns fixed memories and similar pairs; other methods are not used
+type fakeSimilarMemoryRepository struct {
+	repository.MemoryRepository
+	memories []*model.Memory
+	pairs    []model.MemorySimilarity
+}
+
+func (r *fakeSimilarMemoryRepository) FindSimilarMemories(_ context.Context, minSimilarity float64) ([]model.MemorySimilarity, error) {
+	var pairs []model.MemorySimilarity
+	for _, p := range r.pairs {
+		if p.Similarity >= minSimilarity {
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs, nil
+}
+
+func (r *fakeSimilarMemoryRepository) ListMemories(_ context.Context, _ repository.MemoryFilter) ([]*model.Memory, error) {
+	return r.memories, nil
+}
+
+func (r *fakeSimilarMemoryRepository) GetMemory(_ context.Context, path string) (*model.Memory, error) {
+	for _, m := range r.memories {
+		if m.Path == path {
+			return m, nil
+		}
+	}
+	return nil, repository.ErrMemoryNotFound
+}
+
+func TestConsolidateUsecase(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Now()
+	repo := &fakeSimilarMemoryRepository{
+		memories: []*model.Memory{
+			{Path: "prefs/go", Content: "Prefers Go.", SHA: "1", ModifiedAt: now.Add(-2 * day)},
+			{Path: "prefs/go-backend", Content: "Likes Go for backend work.", SHA: "2", ModifiedAt: now.Add(-day)},
+			{Path: "prefs/golang", Content: "Go is the favorite language.", SHA: "3", ModifiedAt: now.Add(-3 * day)},
+			{Path: "facts/home", Content: "Lives in Tokyo.", SHA: "4", ModifiedAt: now},
+		},
+		pairs: []model.MemorySimilarity{
+			{PathA: "prefs/go", PathB: "prefs/go-backend", Similarity: 0.95},
+			{PathA: "prefs/go", PathB: "prefs/golang", Similarity: 0.93},
+			{PathA: "prefs/go-backend", PathB: "prefs/golang", Similarity: 0.92},
+			{PathA: "facts/home", PathB: "prefs/go", Similarity: 0.5},
+		},
+	}
+	u := NewConsolidateUsecase(repo, nil, nil, nil)
+
+	if _, err := u.Plan(context.Background(), 1.5); err == nil {
+		t.Error("expected an error for a threshold above 1")
+	}
+
+	plan, err := u.Plan(context.Background(), 0.9)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Merges) != 1 {
+		t.Fatalf("got %d merges, want 1", len(plan.Merges))
+	}
+	merge := plan.Merges[0]
+	if merge.Keep != "prefs/go-backend" {
+		t.Errorf("kept %s, want the newest memory prefs/go-backend", merge.Keep)
+	}
+	if want := []string{"prefs/go", "prefs/golang"}; !reflect.DeepEqual(merge.Merge, want) {
+		t.Errorf("merged %v, want %v", merge.Merge, want)
+	}
+	if merge.Similarity != 0.92 {
+		t.Errorf("min similarity %v, want 0.92", merge.Similarity)
+	}
+
+	// A memory edited after planning makes the merge stale; nothing is written
+	repo.memories[2].SHA = "changed"
+	results, err := u.Apply(context.Background(), plan)
+	if err == nil || len(results) != 1 || !errors.Is(results[0].Err, ErrStalePlan) || results[0].Applied {
+		t.Errorf("got results %+v and error %v, want a stale merge", results, err)
+	}
+}
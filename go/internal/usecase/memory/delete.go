@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+type DeleteUsecase struct {
+	memoryRepo           repository.MemoryRepository
+	memoryStorageFactory storage.MemoryStorageFactory
+}
+
+// NewDeleteUsecase creates a new DeleteUsecase instance
+func NewDeleteUsecase(memoryRepo repository.MemoryRepository, memoryStorageFactory storage.MemoryStorageFactory) *DeleteUsecase {
+	return &DeleteUsecase{
+		memoryRepo:           memoryRepo,
+		memoryStorageFactory: memoryStorageFactory,
+	}
+}
+
+// Delete removes the file of the memory from the memory repository and the memory from the database.
+// A memory that exists in only one of them is still deleted there; ErrMemoryNotFound is returned
+// when it exists in neither.
+func (u *DeleteUsecase) Delete(ctx context.Context, path string) error {
+	path, err := model.NormalizeMemoryPath(path)
+	if err != nil {
+		return err
+	}
+
+	memoryStorage, err := u.memoryStorageFactory.CreateMemoryStorage()
+	if err != nil {
+		return fmt.Errorf("failed to get storage factory: %w", err)
+	}
+	defer closeStorage(memoryStorage)
+	fileErr := memoryStorage.DeleteMemory(ctx, path)
+	if fileErr != nil && !errors.Is(fileErr, storage.ErrMemoryNotFound) {
+		return fmt.Errorf("failed to delete memory file %s: %w", path, fileErr)
+	}
+
+	rowErr := u.memoryRepo.DeleteMemory(ctx, path)
+	if rowErr != nil && !errors.Is(rowErr, repository.ErrMemoryNotFound) {
+		return fmt.Errorf("failed to delete memory %s from the database: %w", path, rowErr)
+	}
+
+	if fileErr != nil && rowErr != nil {
+		return fmt.Errorf("%s: %w", path, repository.ErrMemoryNotFound)
+	}
+	return nil
+}
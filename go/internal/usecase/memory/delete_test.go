@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// fakeMemoryRepository keeps the paths of the stored memories; other methods are not used
+type fakeMemoryRepository struct {
+	repository.MemoryRepository
+	paths map[string]bool
+}
+
+func (r *fakeMemoryRepository) DeleteMemory(_ context.Context, path string) error {
+	if !r.paths[path] {
+		return repository.ErrMemoryNotFound
+	}
+	delete(r.paths, path)
+	return nil
+}
+
+// fakeMemoryStorage keeps the paths of the memory files; other methods are not used
+type fakeMemoryStorage struct {
+	storage.Storage
+	paths map[string]bool
+}
+
+func (s *fakeMemoryStorage) DeleteMemory(_ context.Context, path string) error {
+	if !s.paths[path] {
+		return storage.ErrMemoryNotFound
+	}
+	delete(s.paths, path)
+	return nil
+}
+
+func (s *fakeMemoryStorage) Close() error { return nil }
+
+func (s *fakeMemoryStorage) CreateMemoryStorage() (storage.Storage, error) {
+	return s, nil
+}
+
+func TestDeleteUsecase(t *testing.T) {
+	tests := []struct {
+		name    string
+		inFile  bool
+		inDB    bool
+		wantErr error
+	}{
+		{name: "file and row", inFile: true, inDB: true},
+		{name: "file only", inFile: true},
+		{name: "row only", inDB: true},
+		{name: "neither", wantErr: repository.ErrMemoryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := &fakeMemoryStorage{paths: map[string]bool{"prefs/editor": tt.inFile}}
+			repo := &fakeMemoryRepository{paths: map[string]bool{"prefs/editor": tt.inDB}}
+
+			err := NewDeleteUsecase(repo, files).Delete(context.Background(), ".memories/prefs/editor.md")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if files.paths["prefs/editor"] || repo.paths["prefs/editor"] {
+				t.Error("memory was not deleted everywhere")
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type ListUsecase struct {
+	memoryRepo repository.MemoryRepository
+}
+
+// NewListUsecase creates a new ListUsecase instance
+func NewListUsecase(memoryRepo repository.MemoryRepository) *ListUsecase {
+	return &ListUsecase{
+		memoryRepo: memoryRepo,
+	}
+}
+
+// List returns the synchronized memories matching the filter
+func (u *ListUsecase) List(ctx context.Context, filter repository.MemoryFilter) ([]*model.Memory, error) {
+	memories, err := u.memoryRepo.ListMemories(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	return memories, nil
+}
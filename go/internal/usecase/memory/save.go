@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+type SaveUsecase struct {
+	memoryRepo           repository.MemoryRepository
+	memoryStorageFactory storage.MemoryStorageFactory
+	embeddingProvider    embedding.EmbeddingProvider
+	usageRepo            repository.UsageRepository
+}
+
+// NewSaveUsecase creates a new SaveUsecase instance
+func NewSaveUsecase(memoryRepo repository.MemoryRepository, memoryStorageFactory storage.MemoryStorageFactory, embeddingProvider embedding.EmbeddingProvider, usageRepo repository.UsageRepository) *SaveUsecase {
+	return &SaveUsecase{
+		memoryRepo:           memoryRepo,
+		memoryStorageFactory: memoryStorageFactory,
+		embeddingProvider:    embeddingProvider,
+		usageRepo:            usageRepo,
+	}
+}
+
+// Fetch returns the memory as it is in the memory repository, e.g. to edit it
+func (u *SaveUsecase) Fetch(ctx context.Context, path string) (*model.Memory, error) {
+	path, err := model.NormalizeMemoryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := u.memoryStorageFactory.CreateMemoryStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage factory: %w", err)
+	}
+	defer closeStorage(storage)
+	memory, err := storage.FetchMemory(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch memory %s: %w", path, err)
+	}
+	return memory, nil
+}
+
+// Save embeds the content, writes it to the file of the memory in the memory repository, then
+// upserts the memory in the database so that it is available without a sync.
+// The content is checked and embedded before anything is written, so that a failure writes
+// nothing; if the database cannot be updated after the file was committed, the next memory
+// sync picks the change up.
+func (u *SaveUsecase) Save(ctx context.Context, path, content string) (*model.Memory, error) {
+	path, err := model.NormalizeMemoryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	memory := &model.Memory{Path: path, Content: content}
+	if err := memory.SetMetadataFromContent(); err != nil {
+		return nil, fmt.Errorf("invalid memory %s: %w", path, err)
+	}
+	memory.SetContentHashes()
+
+	if err := u.embed(ctx, memory); err != nil {
+		return nil, fmt.Errorf("failed to embed memory %s: %w", path, err)
+	}
+
+	storage, err := u.memoryStorageFactory.CreateMemoryStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage factory: %w", err)
+	}
+	defer closeStorage(storage)
+	if err := storage.SaveMemory(ctx, memory); err != nil {
+		return nil, fmt.Errorf("failed to write memory %s: %w", path, err)
+	}
+
+	if err := u.memoryRepo.SaveMemory(ctx, memory); err != nil {
+		return nil, fmt.Errorf("memory %s was written but not saved to the database, run memory sync: %w", path, err)
+	}
+	return memory, nil
+}
+
+// embed creates the embedding of the memory and records its usage. A memory whose body is
+// stored unchanged with an embedding of the current model is left without an embedding, so
+// that saving it keeps the stored one.
+func (u *SaveUsecase) embed(ctx context.Context, memory *model.Memory) error {
+	stored, err := u.memoryRepo.GetMemory(ctx, memory.Path)
+	if err != nil && !errors.Is(err, repository.ErrMemoryNotFound) {
+		return fmt.Errorf("failed to get the stored memory: %w", err)
+	}
+	if err == nil && stored.BodySHA != "" && stored.BodySHA == memory.BodySHA &&
+		len(stored.Embedding) > 0 && stored.EmbeddingModel == u.embeddingProvider.Model() {
+		memory.TokenCount = stored.TokenCount
+		memory.EmbeddingModel = stored.EmbeddingModel
+		return nil
+	}
+
+	startedAt := time.Now()
+	result, err := u.embeddingProvider.Embed(ctx, memory.EmbeddingText())
+	if err != nil {
+		return err
+	}
+	memory.Embedding = result.Vector
+	memory.TokenCount = result.InputTokens
+	memory.EmbeddingModel = u.embeddingProvider.Model()
+
+	usage := model.EmbeddingUsage{Model: u.embeddingProvider.Model()}
+	usage.Add(result)
+	if err := u.usageRepo.RecordUsage(ctx, &model.UsageRecord{
+		Source:     model.UsageSourceMemory,
+		Model:      usage.Model,
+		Requests:   usage.Requests,
+		Tokens:     usage.Tokens,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to record embedding usage: %w", err)
+	}
+	return nil
+}
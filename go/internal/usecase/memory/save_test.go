@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// fakeSavedMemoryRepository keeps the saved memories and, as the database does, the stored
+// embedding of a memory saved without one; other methods are not used
+type fakeSavedMemoryRepository struct {
+	repository.MemoryRepository
+	memories map[string]*model.Memory
+}
+
+func (r *fakeSavedMemoryRepository) GetMemory(_ context.Context, path string) (*model.Memory, error) {
+	if m, ok := r.memories[path]; ok {
+		return m, nil
+	}
+	return nil, repository.ErrMemoryNotFound
+}
+
+func (r *fakeSavedMemoryRepository) SaveMemory(_ context.Context, memory *model.Memory) error {
+	if stored, ok := r.memories[memory.Path]; ok && memory.Embedding == nil {
+		memory.Embedding = stored.Embedding
+	}
+	r.memories[memory.Path] = memory
+	return nil
+}
+
+// fakeSavedMemoryStorage keeps the written memory files; other methods are not used
+type fakeSavedMemoryStorage struct {
+	storage.Storage
+	contents map[string]string
+}
+
+func (s *fakeSavedMemoryStorage) SaveMemory(_ context.Context, memory *model.Memory) error {
+	s.contents[memory.Path] = memory.Content
+	return nil
+}
+
+func (s *fakeSavedMemoryStorage) Close() error { return nil }
+
+func (s *fakeSavedMemoryStorage) CreateMemoryStorage() (storage.Storage, error) {
+	return s, nil
+}
+
+// fakeEmbeddingProvider returns a fixed embedding, or err
+type fakeEmbeddingProvider struct {
+	err   error
+	calls int
+}
+
+func (p *fakeEmbeddingProvider) Embed(_ context.Context, text string) (*model.EmbeddingResult, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &model.EmbeddingResult{Vector: []float64{1, 0}, Tokens: 3, InputTokens: 3}, nil
+}
+
+func (p *fakeEmbeddingProvider) Model() string { return "test-model" }
+
+// fakeUsageRepository discards the usage records
+type fakeUsageRepository struct {
+	repository.UsageRepository
+}
+
+func (r *fakeUsageRepository) RecordUsage(_ context.Context, _ *model.UsageRecord) error {
+	return nil
+}
+
+func TestSaveUsecase(t *testing.T) {
+	ctx := context.Background()
+	files := &fakeSavedMemoryStorage{contents: map[string]string{}}
+	repo := &fakeSavedMemoryRepository{memories: map[string]*model.Memory{}}
+	provider := &fakeEmbeddingProvider{err: errors.New("rate limited")}
+	usecase := NewSaveUsecase(repo, files, provider, &fakeUsageRepository{})
+
+	// A failed embedding writes neither the file nor the row
+	if _, err := usecase.Save(ctx, "prefs/editor", "Uses vim.\n"); err == nil {
+		t.Fatal("Save succeeded although the embedding failed")
+	}
+	if len(files.contents) != 0 || len(repo.memories) != 0 {
+		t.Errorf("got files %v and rows %v, want nothing written", files.contents, repo.memories)
+	}
+
+	provider.err = nil
+	memory, err := usecase.Save(ctx, "prefs/editor", "Uses vim.\n")
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if files.contents["prefs/editor"] != "Uses vim.\n" || repo.memories["prefs/editor"] != memory {
+		t.Errorf("got files %v and rows %v, want the memory written to both", files.contents, repo.memories)
+	}
+	if memory.EmbeddingModel != "test-model" || len(memory.Embedding) != 2 {
+		t.Errorf("got embedding model %q and %d dimensions", memory.EmbeddingModel, len(memory.Embedding))
+	}
+
+	// A frontmatter change keeps the stored embedding
+	calls := provider.calls
+	if _, err := usecase.Save(ctx, "prefs/editor", "---\nimportance: 4\n---\nUses vim.\n"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if provider.calls != calls {
+		t.Error("a frontmatter change re-embedded the memory")
+	}
+
+	// A memory embedded with another model is embedded again
+	repo.memories["prefs/editor"].EmbeddingModel = "old-model"
+	if _, err := usecase.Save(ctx, "prefs/editor", "---\nimportance: 5\n---\nUses vim.\n"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if provider.calls != calls+1 || repo.memories["prefs/editor"].EmbeddingModel != "test-model" {
+		t.Error("a memory embedded with another model kept its embedding")
+	}
+}
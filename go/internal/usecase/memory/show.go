@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type ShowUsecase struct {
+	memoryRepo repository.MemoryRepository
+}
+
+// NewShowUsecase creates a new ShowUsecase instance
+func NewShowUsecase(memoryRepo repository.MemoryRepository) *ShowUsecase {
+	return &ShowUsecase{
+		memoryRepo: memoryRepo,
+	}
+}
+
+// Show returns the synchronized memory at path, archived or not
+func (u *ShowUsecase) Show(ctx context.Context, path string) (*model.Memory, error) {
+	path, err := model.NormalizeMemoryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return u.memoryRepo.GetMemory(ctx, path)
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type SweepUsecase struct {
+	memoryRepo repository.MemoryRepository
+}
+
+// NewSweepUsecase creates a new SweepUsecase instance
+func NewSweepUsecase(memoryRepo repository.MemoryRepository) *SweepUsecase {
+	return &SweepUsecase{
+		memoryRepo: memoryRepo,
+	}
+}
+
+// Sweep archives the memories that expired at or before now and those superseded by an active memory.
+// Archived memories are kept in the database but excluded from listings by default.
+func (u *SweepUsecase) Sweep(ctx context.Context, now time.Time) (*model.SweepResult, error) {
+	expired, err := u.memoryRepo.ArchiveExpired(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sweep expired memories: %w", err)
+	}
+	superseded, err := u.memoryRepo.ArchiveSuperseded(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sweep superseded memories: %w", err)
+	}
+	return &model.SweepResult{Expired: expired, Superseded: superseded}, nil
+}
@@ -1,8 +1,10 @@
 package memory
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/embedding"
@@ -10,85 +12,241 @@ import (
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
 )
 
+// usageRecordTimeout bounds recording the usage of an interrupted sync
+const usageRecordTimeout = 5 * time.Second
+
+// DefaultBatchSize is the number of memories saved per transaction unless set with WithBatchSize
+const DefaultBatchSize = 100
+
 type SyncUsecase struct {
 	memoryRepo           repository.MemoryRepository
 	memoryStorageFactory storage.MemoryStorageFactory
 	embeddingProvider    embedding.EmbeddingProvider
+	usageRepo            repository.UsageRepository
+	onEvent              model.SyncEventHandler
+	batchSize            int
 }
 
 // NewSyncUsecase creates a new SyncUsecase instance
-func NewSyncUsecase(memoryRepo repository.MemoryRepository, memoryStorageFactory storage.MemoryStorageFactory, embeddingProvider embedding.EmbeddingProvider) *SyncUsecase {
+func NewSyncUsecase(memoryRepo repository.MemoryRepository, memoryStorageFactory storage.MemoryStorageFactory, embeddingProvider embedding.EmbeddingProvider, usageRepo repository.UsageRepository) *SyncUsecase {
 	return &SyncUsecase{
 		memoryRepo:           memoryRepo,
 		memoryStorageFactory: memoryStorageFactory,
 		embeddingProvider:    embeddingProvider,
+		usageRepo:            usageRepo,
+		batchSize:            DefaultBatchSize,
+	}
+}
+
+// OnEvent registers a handler that receives progress events during Sync
+func (u *SyncUsecase) OnEvent(handler model.SyncEventHandler) *SyncUsecase {
+	u.onEvent = handler
+	return u
+}
+
+// WithBatchSize sets the number of memories saved per transaction; values below 1 are ignored
+func (u *SyncUsecase) WithBatchSize(size int) *SyncUsecase {
+	if size > 0 {
+		u.batchSize = size
 	}
+	return u
 }
 
-func (u *SyncUsecase) Sync() error {
+// emit sends an event to the registered handler, if any
+func (u *SyncUsecase) emit(event model.SyncEvent) {
+	if u.onEvent == nil {
+		return
+	}
+	event.Time = time.Now()
+	u.onEvent(event)
+}
+
+// Sync fetches all memories from the memory repository and saves the new or changed ones with their embeddings.
+// When ctx is done it stops before the next memory and returns the partial result with ctx.Err().
+func (u *SyncUsecase) Sync(ctx context.Context) (*model.SyncResult, error) {
+	result := &model.SyncResult{StartedAt: time.Now()}
+	result.Usage.Model = u.embeddingProvider.Model()
+
 	// Get the storage
 	storage, err := u.memoryStorageFactory.CreateMemoryStorage()
 	if err != nil {
-		return fmt.Errorf("failed to get storage factory: %w", err)
+		return nil, fmt.Errorf("failed to get storage factory: %w", err)
 	}
+	defer closeStorage(storage)
 
 	// Get all document entries from storage
-	entries, err := storage.GetMemoryEntries()
+	entries, err := storage.GetMemoryEntries(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get memory entries: %w", err)
+		return nil, fmt.Errorf("failed to get memory entries: %w", err)
 	}
+	result.Total = len(entries)
+	u.emit(model.SyncEvent{Type: model.SyncEventStarted, Total: len(entries)})
 
 	// Fetch all documents from storage
 	var memories []*model.Memory
 
 	for _, entry := range entries {
-		memory, err := storage.FetchMemory(entry.Path)
+		if ctx.Err() != nil {
+			break
+		}
+		memory, err := storage.FetchMemory(ctx, entry.Path)
 		if err != nil {
 			log.Printf("failed to fetch memory %s: %v", entry.Path, err)
+			u.fail(result, entry.Path, "fetch", err)
 			continue
 		}
-		if memory != nil {
-			memories = append(memories, memory)
+		if memory == nil {
+			continue
 		}
+		if err := memory.SetMetadataFromContent(); err != nil {
+			log.Printf("failed to parse memory %s: %v", entry.Path, err)
+			u.fail(result, entry.Path, "parse", err)
+			continue
+		}
+		memories = append(memories, memory)
 	}
 
-	// Find unchanged memories
-	existingSHAs, err := u.memoryRepo.FindExistingSHAs(memories)
+	// Compare with the memories already stored, keyed by path
+	stored, err := u.memoryRepo.FindStoredSHAs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unchanged memories: %w", err)
+	}
+	bodies, err := u.memoryRepo.FindStoredBodySHAs(ctx, u.embeddingProvider.Model())
 	if err != nil {
-		return fmt.Errorf("failed to find unchanged memories: %w", err)
+		return nil, fmt.Errorf("failed to find unchanged memories: %w", err)
 	}
 
-	// Create a map for quick lookup of unchanged document IDs
-	existingSHASet := make(map[string]bool, len(existingSHAs))
-	for _, sha := range existingSHAs {
-		existingSHASet[sha] = true
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[entry.Path] = true
 	}
 
-	// Save only changed memories
-	savedCount := 0
+	// Save only changed memories, in batches of one transaction each
+	var batch []*model.Memory
 	for _, mem := range memories {
+		if ctx.Err() != nil {
+			break
+		}
 		if mem == nil {
 			continue
 		}
 
-		if !existingSHASet[mem.SHA] {
-			// create embedding
-			embedding, err := u.embeddingProvider.Embed(mem.Content)
-			if err != nil {
-				log.Printf("failed to create embedding for memory %s: %v", mem.Path, err)
-				continue
-			}
-			mem.Embedding = embedding
-			if err := u.memoryRepo.SaveMemory(mem); err != nil {
-				log.Printf("failed to save memory %s: %v", mem.Path, err)
+		if stored.Unchanged(mem.Path, mem.SHA) {
+			result.Unchanged++
+			u.emit(model.SyncEvent{Type: model.SyncEventUnchanged, Path: mem.Path})
+			continue
+		}
+
+		// A renamed memory keeps its row and embedding
+		if from, ok := stored.TakeRenamed(mem.Path, mem.SHA, present); ok {
+			if err := u.memoryRepo.MoveMemory(ctx, from, mem.Path, mem.ModifiedAt); err != nil {
+				log.Printf("failed to move memory %s to %s: %v", from, mem.Path, err)
+				u.fail(result, mem.Path, "save", err)
 				continue
 			}
-			savedCount++
-		} else {
+			result.Moved++
+			u.emit(model.SyncEvent{Type: model.SyncEventMoved, Path: mem.Path, From: from})
+			continue
+		}
+
+		// A memory whose body did not change is saved without an embedding and keeps the stored one
+		if !bodies.Unchanged(mem.Path, mem.BodySHA) && !u.embed(ctx, result, mem) {
+			continue
+		}
+		batch = append(batch, mem)
+		if len(batch) >= u.batchSize {
+			u.saveBatch(ctx, result, batch)
+			batch = nil
 		}
 	}
+	if ctx.Err() == nil {
+		u.saveBatch(ctx, result, batch)
+		batch = nil
+	}
+
+	result.FinishedAt = time.Now()
+	if err := ctx.Err(); err != nil {
+		// The embeddings of the unsaved batch are in the embedding cache for the next sync
+		log.Printf("sync interrupted: %d memories saved, %d moved, %d not saved", result.Saved, result.Moved, len(batch))
+		// The embeddings created so far are billed, so their usage is recorded although ctx is done
+		recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), usageRecordTimeout)
+		defer cancel()
+		if err := u.recordUsage(recordCtx, result); err != nil {
+			log.Printf("failed to record embedding usage: %v", err)
+		}
+		return result, fmt.Errorf("sync interrupted: %w", err)
+	}
+
+	log.Printf("sync completed: %d memories processed, %d memories saved (%d metadata only), %d moved", len(memories), result.Saved, result.MetadataOnly, result.Moved)
+
+	if err := u.recordUsage(ctx, result); err != nil {
+		return nil, fmt.Errorf("failed to record embedding usage: %w", err)
+	}
+	u.emit(model.SyncEvent{Type: model.SyncEventCompleted, Total: result.Total})
 
-	log.Printf("sync completed: %d memories processed, %d memories saved", len(memories), savedCount)
+	return result, nil
+}
 
-	return nil
+// embed creates the embedding of the memory; it reports false if the memory failed
+func (u *SyncUsecase) embed(ctx context.Context, result *model.SyncResult, mem *model.Memory) bool {
+	embedding, err := u.embeddingProvider.Embed(ctx, mem.EmbeddingText())
+	if err != nil {
+		log.Printf("failed to create embedding for memory %s: %v", mem.Path, err)
+		u.fail(result, mem.Path, "embed", err)
+		return false
+	}
+	result.Usage.Add(embedding)
+	mem.Embedding = embedding.Vector
+	mem.TokenCount = embedding.InputTokens
+	if embedding.Truncated {
+		log.Printf("memory %s has %d tokens; only the beginning was embedded", mem.Path, embedding.InputTokens)
+	}
+	mem.EmbeddingModel = u.embeddingProvider.Model()
+	return true
+}
+
+// saveBatch saves the memories in one transaction; if it fails, every memory of the batch fails
+func (u *SyncUsecase) saveBatch(ctx context.Context, result *model.SyncResult, batch []*model.Memory) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := u.memoryRepo.SaveMemories(ctx, batch); err != nil {
+		log.Printf("failed to save %d memories: %v", len(batch), err)
+		for _, mem := range batch {
+			u.fail(result, mem.Path, "save", err)
+		}
+		return
+	}
+	for _, mem := range batch {
+		result.Saved++
+		if mem.Embedding == nil {
+			result.MetadataOnly++
+		}
+		u.emit(model.SyncEvent{Type: model.SyncEventSaved, Path: mem.Path})
+	}
+}
+
+// recordUsage persists the embedding usage of the sync run
+func (u *SyncUsecase) recordUsage(ctx context.Context, result *model.SyncResult) error {
+	return u.usageRepo.RecordUsage(ctx, &model.UsageRecord{
+		Source:     model.UsageSourceMemory,
+		Model:      result.Usage.Model,
+		Requests:   result.Usage.Requests,
+		Tokens:     result.Usage.Tokens,
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.FinishedAt,
+	})
+}
+
+// fail records a failed entry and emits a failed event
+func (u *SyncUsecase) fail(result *model.SyncResult, path, stage string, err error) {
+	result.Failed++
+	u.emit(model.SyncEvent{Type: model.SyncEventFailed, Path: path, Stage: stage, Error: err})
+}
+
+// closeStorage closes the storage, logging a failure because the result does not depend on it
+func closeStorage(memoryStorage storage.Storage) {
+	if err := memoryStorage.Close(); err != nil {
+		log.Printf("failed to close storage: %v", err)
+	}
 }
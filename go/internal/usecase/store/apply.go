@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+type ApplyAction string
+
+const (
+	ApplyActionCreated   ApplyAction = "created"
+	ApplyActionUpdated   ApplyAction = "updated"
+	ApplyActionUnchanged ApplyAction = "unchanged"
+)
+
+// SpecError reports a failure for the store declared at Index
+type SpecError struct {
+	Index int
+	Err   error
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("store #%d: %v", e.Index, e.Err)
+}
+
+func (e *SpecError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyResult describes what Apply did, or would do, for one declared store
+type ApplyResult struct {
+	Index      int
+	Action     ApplyAction
+	Store      model.DocumentStore // nil for stores that would be created in a dry run
+	Repository *model.RepositoryInfo
+}
+
+type ApplyUsecase struct {
+	storeRepo repository.StoreRepository
+	validator storage.StoreValidator
+}
+
+func NewApplyUsecase(storeRepo repository.StoreRepository, validator storage.StoreValidator) *ApplyUsecase {
+	return &ApplyUsecase{
+		storeRepo: storeRepo,
+		validator: validator,
+	}
+}
+
+// Apply creates or updates stores so that they match the declared specs.
+// Stores are identified by repository and ref; only filters are updated in place.
+// Stores that are not declared are left untouched. With dryRun nothing is written.
+func (u *ApplyUsecase) Apply(ctx context.Context, specs []CreateInput, dryRun bool) ([]ApplyResult, error) {
+	existing, err := u.storeRepo.ListStores(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stores: %w", err)
+	}
+
+	// Normalize and check all specs before changing anything
+	candidates := make([]model.DocumentStore, len(specs))
+	for i, spec := range specs {
+		candidate, err := newStore(spec)
+		if err != nil {
+			return nil, &SpecError{Index: i, Err: err}
+		}
+		for j := 0; j < i; j++ {
+			if sameSource(candidates[j], candidate) {
+				return nil, &SpecError{Index: i, Err: fmt.Errorf("%w: %s is also declared by store #%d", ErrStoreExists, storeLocation(candidate), j)}
+			}
+		}
+		candidates[i] = candidate
+	}
+
+	results := make([]ApplyResult, 0, len(candidates))
+	for i, candidate := range candidates {
+		result, err := u.applyOne(ctx, existing, candidate, dryRun)
+		if err != nil {
+			return results, &SpecError{Index: i, Err: err}
+		}
+		result.Index = i
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// filterSetter is implemented by the stores whose filters can be changed
+type filterSetter interface {
+	SetFilters(filters model.StoreFilters)
+}
+
+// applyOne creates or updates the store declared by candidate
+func (u *ApplyUsecase) applyOne(ctx context.Context, existing []model.DocumentStore, candidate model.DocumentStore, dryRun bool) (*ApplyResult, error) {
+	current := findStore(existing, candidate, 0)
+	if current == nil {
+		info, err := u.validator.Validate(ctx, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if dryRun {
+			return &ApplyResult{Action: ApplyActionCreated, Repository: info}, nil
+		}
+
+		created, err := u.storeRepo.CreateStore(ctx, candidate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create store: %w", err)
+		}
+		return &ApplyResult{Action: ApplyActionCreated, Store: created, Repository: info}, nil
+	}
+
+	if filtersEqual(current.Filters(), candidate.Filters()) {
+		return &ApplyResult{Action: ApplyActionUnchanged, Store: current}, nil
+	}
+
+	setter, ok := current.(filterSetter)
+	if !ok {
+		return nil, model.ErrUnsupportedStoreType
+	}
+	setter.SetFilters(candidate.Filters())
+	if !dryRun {
+		if err := u.storeRepo.UpdateStore(ctx, current); err != nil {
+			return nil, fmt.Errorf("failed to update store: %w", err)
+		}
+	}
+	return &ApplyResult{Action: ApplyActionUpdated, Store: current}, nil
+}
+
+// filtersEqual compares filters, treating nil and empty pattern lists as equal
+func filtersEqual(a, b model.StoreFilters) bool {
+	if a.IsEmpty() && b.IsEmpty() {
+		return true
+	}
+	return reflect.DeepEqual(nonNil(a.Include), nonNil(b.Include)) &&
+		reflect.DeepEqual(nonNil(a.Exclude), nonNil(b.Exclude))
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+func TestApplyUsecase_Apply(t *testing.T) {
+	existing := model.NewGitHubStore(1, "owner/notes")
+	existing.SetFilters(model.StoreFilters{Include: []string{"**/*.md"}})
+	repo := &fakeStoreRepository{stores: []model.DocumentStore{existing}}
+	validator := &fakeValidator{known: map[string]*model.RepositoryInfo{
+		"owner/notes":    {FullName: "owner/notes"},
+		"owner/handbook": {FullName: "owner/handbook"},
+	}}
+	usecase := NewApplyUsecase(repo, validator)
+
+	specs := []CreateInput{
+		{Repo: "https://github.com/owner/notes", Filters: model.StoreFilters{Include: []string{"**/*.md"}}},
+		{Repo: "owner/handbook", Ref: "main"},
+	}
+
+	results, err := usecase.Apply(context.Background(), specs, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Action != ApplyActionUnchanged || results[1].Action != ApplyActionCreated {
+		t.Fatalf("got actions %s, %s; want unchanged, created", results[0].Action, results[1].Action)
+	}
+
+	// Applying the same specs again does not create anything
+	results, err = usecase.Apply(context.Background(), specs, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[1].Action != ApplyActionUnchanged || len(repo.stores) != 2 {
+		t.Fatalf("second apply: got action %s with %d stores", results[1].Action, len(repo.stores))
+	}
+
+	// Changing filters updates the store in place
+	specs[0].Filters = model.StoreFilters{Exclude: []string{"archive/"}}
+	results, err = usecase.Apply(context.Background(), specs, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Action != ApplyActionUpdated || results[0].Store.ID() != 1 {
+		t.Fatalf("got action %s for store %d, want updated store 1", results[0].Action, results[0].Store.ID())
+	}
+}
+
+func TestApplyUsecase_ApplyErrors(t *testing.T) {
+	validator := &fakeValidator{known: map[string]*model.RepositoryInfo{"owner/notes": {}}}
+
+	tests := []struct {
+		name      string
+		specs     []CreateInput
+		wantIndex int
+		wantErr   error
+	}{
+		{
+			name:      "duplicate declaration",
+			specs:     []CreateInput{{Repo: "owner/notes"}, {Repo: "git@github.com:owner/notes.git"}},
+			wantIndex: 1,
+			wantErr:   ErrStoreExists,
+		},
+		{
+			name:      "invalid repository",
+			specs:     []CreateInput{{Repo: "owner/notes"}, {Repo: "not a repo"}},
+			wantIndex: 1,
+			wantErr:   model.ErrInvalidRepository,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeStoreRepository{}
+			_, err := NewApplyUsecase(repo, validator).Apply(context.Background(), tt.specs, false)
+
+			var specErr *SpecError
+			if !errors.As(err, &specErr) || specErr.Index != tt.wantIndex || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want SpecError at %d wrapping %v", err, tt.wantIndex, tt.wantErr)
+			}
+			if len(repo.stores) != 0 {
+				t.Errorf("expected nothing to be created, got %d stores", len(repo.stores))
+			}
+		})
+	}
+}
@@ -1,40 +1,153 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
 	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
 )
 
+// ErrStoreExists is returned when a store for the same repository and ref already exists
+var ErrStoreExists = errors.New("store already exists")
+
 type CreateUsecase struct {
 	storeRepo repository.StoreRepository
+	validator storage.StoreValidator
 }
 
-func NewCreateUsecase(storeRepo repository.StoreRepository) *CreateUsecase {
+func NewCreateUsecase(storeRepo repository.StoreRepository, validator storage.StoreValidator) *CreateUsecase {
 	return &CreateUsecase{
 		storeRepo: storeRepo,
+		validator: validator,
 	}
 }
 
+// CreateInput describes a store to create
+type CreateInput struct {
+	// Type is model.StoreTypeGitHub, the default, or model.StoreTypeGit
+	Type string
+	// Repo is a GitHub repository as "owner/repo", an HTTPS URL or an SSH remote,
+	// or the URL of the remote of a git store
+	Repo    string
+	Ref     string
+	Filters model.StoreFilters
+}
+
+// CreateResult is the created store together with information about its repository
+type CreateResult struct {
+	Store      model.DocumentStore
+	Repository *model.RepositoryInfo
+}
+
 // Create creates a new document store
-// Currently only GitHub repositories are supported in the format "owner/repo"
+// GitHub repository references are normalized to "owner/repo" and git remote URLs are
+// validated. The repository is verified before the store is saved.
 // Returns the created store with its generated ID
-func (u *CreateUsecase) Create(repo string) (model.DocumentStore, error) {
-	if repo == "" {
-		return nil, errors.New("repository cannot be empty")
-	}
-
+func (u *CreateUsecase) Create(ctx context.Context, input CreateInput) (*CreateResult, error) {
 	// Create a new store with a temporary ID (0 for auto-increment)
 	// The actual ID will be assigned by the database
-	tempStore := model.NewGitHubStore(0, repo)
-	
+	tempStore, err := newStore(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.checkDuplicate(ctx, tempStore); err != nil {
+		return nil, err
+	}
+
+	info, err := u.validator.Validate(ctx, tempStore)
+	if err != nil {
+		return nil, err
+	}
+
 	// Save the store and get the version with the generated ID
-	createdStore, err := u.storeRepo.CreateStore(tempStore)
+	createdStore, err := u.storeRepo.CreateStore(ctx, tempStore)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create store: %w", err)
 	}
-	
-	return createdStore, nil
+
+	return &CreateResult{
+		Store:      createdStore,
+		Repository: info,
+	}, nil
+}
+
+// newStore returns the unsaved store described by input, with its repository normalized
+func newStore(input CreateInput) (model.DocumentStore, error) {
+	ref := strings.TrimSpace(input.Ref)
+	switch input.Type {
+	case "", model.StoreTypeGitHub:
+		repo, err := model.ParseGitHubRepo(input.Repo)
+		if err != nil {
+			return nil, err
+		}
+		store := model.NewGitHubStore(0, repo)
+		store.SetRef(ref)
+		store.SetFilters(input.Filters)
+		return store, nil
+	case model.StoreTypeGit:
+		url, err := model.ParseGitURL(input.Repo)
+		if err != nil {
+			return nil, err
+		}
+		store := model.NewGitStore(0, url)
+		store.SetRef(ref)
+		store.SetFilters(input.Filters)
+		return store, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", model.ErrUnsupportedStoreType, input.Type)
+	}
+}
+
+// checkDuplicate returns ErrStoreExists if another store points at the same repository and ref
+func (u *CreateUsecase) checkDuplicate(ctx context.Context, store model.DocumentStore) error {
+	existing, err := u.storeRepo.ListStores(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list stores: %w", err)
+	}
+	if dup := findStore(existing, store, store.ID()); dup != nil {
+		return fmt.Errorf("%w: store %d already syncs %s", ErrStoreExists, dup.ID(), storeLocation(store))
+	}
+	return nil
+}
+
+// findStore returns the store that syncs the same source as store, ignoring the store with skipID
+func findStore(stores []model.DocumentStore, store model.DocumentStore, skipID model.StoreId) model.DocumentStore {
+	for _, s := range stores {
+		if s.ID() != skipID && sameSource(s, store) {
+			return s
+		}
+	}
+	return nil
+}
+
+// sameSource reports whether two stores sync the same ref of the same repository
+func sameSource(a, b model.DocumentStore) bool {
+	switch a := a.(type) {
+	case *model.GitHubStore:
+		b, ok := b.(*model.GitHubStore)
+		return ok && strings.EqualFold(a.Repo(), b.Repo()) && a.Ref() == b.Ref()
+	case *model.GitStore:
+		b, ok := b.(*model.GitStore)
+		return ok && a.URL() == b.URL() && a.Ref() == b.Ref()
+	default:
+		return false
+	}
+}
+
+// storeLocation returns the repository of a GitHub store or the remote URL of a git store without
+// its credentials, for messages
+func storeLocation(store model.DocumentStore) string {
+	switch s := store.(type) {
+	case *model.GitHubStore:
+		return s.Repo()
+	case *model.GitStore:
+		return model.RedactGitURL(s.URL())
+	default:
+		return ""
+	}
 }
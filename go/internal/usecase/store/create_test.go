@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+// fakeStoreRepository keeps stores in memory
+type fakeStoreRepository struct {
+	stores []model.DocumentStore
+}
+
+func (r *fakeStoreRepository) GetStore(_ context.Context, id model.StoreId) (model.DocumentStore, error) {
+	for _, s := range r.stores {
+		if s.ID() == id {
+			return s, nil
+		}
+	}
+	return nil, repository.ErrStoreNotFound
+}
+
+func (r *fakeStoreRepository) ListStores(_ context.Context) ([]model.DocumentStore, error) {
+	return r.stores, nil
+}
+
+func (r *fakeStoreRepository) CreateStore(_ context.Context, store model.DocumentStore) (model.DocumentStore, error) {
+	id := model.StoreId(len(r.stores) + 1)
+	var created model.DocumentStore
+	switch s := store.(type) {
+	case *model.GitHubStore:
+		gs := model.NewGitHubStore(id, s.Repo())
+		gs.SetRef(s.Ref())
+		gs.SetFilters(s.Filters())
+		created = gs
+	case *model.GitStore:
+		gs := model.NewGitStore(id, s.URL())
+		gs.SetRef(s.Ref())
+		gs.SetFilters(s.Filters())
+		created = gs
+	}
+	r.stores = append(r.stores, created)
+	return created, nil
+}
+
+func (r *fakeStoreRepository) UpdateStore(_ context.Context, store model.DocumentStore) error {
+	return nil
+}
+
+func (r *fakeStoreRepository) DeleteStore(_ context.Context, id model.StoreId) error { return nil }
+
+func (r *fakeStoreRepository) GetStoreStats(_ context.Context, id model.StoreId) (*model.StoreStats, error) {
+	return &model.StoreStats{}, nil
+}
+
+func (r *fakeStoreRepository) MarkSynced(_ context.Context, id model.StoreId, syncedAt time.Time, revision string) error {
+	return nil
+}
+
+// fakeValidator accepts the repositories listed in known
+type fakeValidator struct {
+	known map[string]*model.RepositoryInfo
+	calls int
+}
+
+func (v *fakeValidator) Validate(_ context.Context, store model.DocumentStore) (*model.RepositoryInfo, error) {
+	v.calls++
+	info, ok := v.known[storeLocation(store)]
+	if !ok {
+		return nil, storage.ErrRepositoryNotFound
+	}
+	return info, nil
+}
+
+func TestCreateUsecase_Create(t *testing.T) {
+	info := &model.RepositoryInfo{FullName: "owner/repo", DefaultBranch: "main", SizeKB: 2048}
+
+	tests := []struct {
+		name      string
+		existing  []model.DocumentStore
+		input     CreateInput
+		wantRepo  string
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "normalizes URL",
+			input:     CreateInput{Repo: "https://github.com/owner/repo.git"},
+			wantRepo:  "owner/repo",
+			wantCalls: 1,
+		},
+		{
+			name:      "normalizes SSH remote",
+			input:     CreateInput{Repo: "git@github.com:owner/repo.git"},
+			wantRepo:  "owner/repo",
+			wantCalls: 1,
+		},
+		{
+			name:      "creates git store",
+			input:     CreateInput{Type: model.StoreTypeGit, Repo: "https://gitlab.com/owner/repo.git/"},
+			wantRepo:  "https://gitlab.com/owner/repo.git",
+			wantCalls: 1,
+		},
+		{
+			name:      "rejects duplicate git store",
+			existing:  []model.DocumentStore{model.NewGitStore(1, "https://gitlab.com/owner/repo.git")},
+			input:     CreateInput{Type: model.StoreTypeGit, Repo: "https://gitlab.com/owner/repo.git"},
+			wantErr:   ErrStoreExists,
+			wantCalls: 0,
+		},
+		{
+			name:      "rejects unsupported type",
+			input:     CreateInput{Type: "svn", Repo: "owner/repo"},
+			wantErr:   model.ErrUnsupportedStoreType,
+			wantCalls: 0,
+		},
+		{
+			name:      "rejects malformed repository without calling validator",
+			input:     CreateInput{Repo: "owner"},
+			wantErr:   model.ErrInvalidRepository,
+			wantCalls: 0,
+		},
+		{
+			name:      "rejects unknown repository",
+			input:     CreateInput{Repo: "owner/rep"},
+			wantErr:   storage.ErrRepositoryNotFound,
+			wantCalls: 1,
+		},
+		{
+			name:      "rejects duplicate",
+			existing:  []model.DocumentStore{model.NewGitHubStore(1, "Owner/Repo")},
+			input:     CreateInput{Repo: "owner/repo"},
+			wantErr:   ErrStoreExists,
+			wantCalls: 0,
+		},
+		{
+			name:      "allows same repository with another ref",
+			existing:  []model.DocumentStore{model.NewGitHubStore(1, "owner/repo")},
+			input:     CreateInput{Repo: "owner/repo", Ref: "release"},
+			wantRepo:  "owner/repo",
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeStoreRepository{stores: tt.existing}
+			validator := &fakeValidator{known: map[string]*model.RepositoryInfo{"owner/repo": info, "https://gitlab.com/owner/repo.git": info}}
+			usecase := NewCreateUsecase(repo, validator)
+
+			result, err := usecase.Create(context.Background(), tt.input)
+			if validator.calls != tt.wantCalls {
+				t.Errorf("validator called %d times, want %d", validator.calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := storeLocation(result.Store); got != tt.wantRepo {
+				t.Errorf("got repo %q, want %q", got, tt.wantRepo)
+			}
+			if result.Repository != info {
+				t.Errorf("got repository info %+v, want %+v", result.Repository, info)
+			}
+		})
+	}
+}
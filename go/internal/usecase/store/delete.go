@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type DeleteUsecase struct {
+	storeRepo repository.StoreRepository
+}
+
+func NewDeleteUsecase(storeRepo repository.StoreRepository) *DeleteUsecase {
+	return &DeleteUsecase{
+		storeRepo: storeRepo,
+	}
+}
+
+// Delete removes the store and all of its documents
+func (u *DeleteUsecase) Delete(ctx context.Context, storeID model.StoreId) error {
+	if err := u.storeRepo.DeleteStore(ctx, storeID); err != nil {
+		return fmt.Errorf("failed to delete store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type ListUsecase struct {
+	storeRepo repository.StoreRepository
+}
+
+func NewListUsecase(storeRepo repository.StoreRepository) *ListUsecase {
+	return &ListUsecase{
+		storeRepo: storeRepo,
+	}
+}
+
+// List returns all document stores ordered by ID
+func (u *ListUsecase) List(ctx context.Context) ([]model.DocumentStore, error) {
+	stores, err := u.storeRepo.ListStores(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stores: %w", err)
+	}
+	return stores, nil
+}
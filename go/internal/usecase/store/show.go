@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type ShowUsecase struct {
+	storeRepo repository.StoreRepository
+}
+
+func NewShowUsecase(storeRepo repository.StoreRepository) *ShowUsecase {
+	return &ShowUsecase{
+		storeRepo: storeRepo,
+	}
+}
+
+// StoreDetail is a store together with statistics about its documents
+type StoreDetail struct {
+	Store model.DocumentStore
+	Stats *model.StoreStats
+}
+
+// Show returns the store with the given ID and its document statistics
+func (u *ShowUsecase) Show(ctx context.Context, storeID model.StoreId) (*StoreDetail, error) {
+	store, err := u.storeRepo.GetStore(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := u.storeRepo.GetStoreStats(ctx, storeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get store stats: %w", err)
+	}
+
+	return &StoreDetail{
+		Store: store,
+		Stats: stats,
+	}, nil
+}
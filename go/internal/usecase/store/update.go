@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/storage"
+)
+
+type UpdateUsecase struct {
+	storeRepo repository.StoreRepository
+	validator storage.StoreValidator
+}
+
+func NewUpdateUsecase(storeRepo repository.StoreRepository, validator storage.StoreValidator) *UpdateUsecase {
+	return &UpdateUsecase{
+		storeRepo: storeRepo,
+		validator: validator,
+	}
+}
+
+// UpdateInput describes the changes to apply to a store.
+// Nil fields are left unchanged.
+type UpdateInput struct {
+	Repo    *string // GitHub repository, or remote URL of a git store
+	Ref     *string
+	Include *[]string
+	Exclude *[]string
+}
+
+// Update applies the given changes to the store and returns the updated store
+func (u *UpdateUsecase) Update(ctx context.Context, storeID model.StoreId, input UpdateInput) (model.DocumentStore, error) {
+	store, err := u.storeRepo.GetStore(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch s := store.(type) {
+	case *model.GitHubStore:
+		if input.Repo != nil {
+			repo, err := model.ParseGitHubRepo(*input.Repo)
+			if err != nil {
+				return nil, err
+			}
+			s.SetRepo(repo)
+		}
+		if input.Ref != nil {
+			s.SetRef(strings.TrimSpace(*input.Ref))
+		}
+		filters := s.Filters()
+		if input.Include != nil {
+			filters.Include = *input.Include
+		}
+		if input.Exclude != nil {
+			filters.Exclude = *input.Exclude
+		}
+		s.SetFilters(filters)
+	case *model.GitStore:
+		if input.Repo != nil {
+			url, err := model.ParseGitURL(*input.Repo)
+			if err != nil {
+				return nil, err
+			}
+			s.SetURL(url)
+		}
+		if input.Ref != nil {
+			s.SetRef(strings.TrimSpace(*input.Ref))
+		}
+		if input.Include != nil || input.Exclude != nil {
+			filters := s.Filters()
+			if input.Include != nil {
+				filters.Include = *input.Include
+			}
+			if input.Exclude != nil {
+				filters.Exclude = *input.Exclude
+			}
+			s.SetFilters(filters)
+		}
+	default:
+		return nil, model.ErrUnsupportedStoreType
+	}
+
+	// The source only needs to be verified again when it changed
+	if input.Repo != nil || input.Ref != nil {
+		if err := u.validate(ctx, store); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.storeRepo.UpdateStore(ctx, store); err != nil {
+		return nil, fmt.Errorf("failed to update store: %w", err)
+	}
+
+	return store, nil
+}
+
+// validate rejects duplicates and unreachable repositories
+func (u *UpdateUsecase) validate(ctx context.Context, store model.DocumentStore) error {
+	existing, err := u.storeRepo.ListStores(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list stores: %w", err)
+	}
+	if dup := findStore(existing, store, store.ID()); dup != nil {
+		return fmt.Errorf("%w: store %d already syncs %s", ErrStoreExists, dup.ID(), storeLocation(store))
+	}
+
+	_, err = u.validator.Validate(ctx, store)
+	return err
+}
@@ -0,0 +1,95 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/port/repository"
+)
+
+type ReportUsecase struct {
+	usageRepo repository.UsageRepository
+	prices    map[string]float64
+}
+
+// NewReportUsecase creates a new ReportUsecase. prices are in USD per million tokens
+// and override model.DefaultEmbeddingPrices.
+func NewReportUsecase(usageRepo repository.UsageRepository, prices map[string]float64) *ReportUsecase {
+	merged := make(map[string]float64, len(model.DefaultEmbeddingPrices)+len(prices))
+	for m, price := range model.DefaultEmbeddingPrices {
+		merged[m] = price
+	}
+	for m, price := range prices {
+		merged[m] = price
+	}
+	return &ReportUsecase{
+		usageRepo: usageRepo,
+		prices:    merged,
+	}
+}
+
+// UsageLine is the usage of a source, store and model with its estimated cost
+type UsageLine struct {
+	model.UsageSummary
+	EstimatedCost float64
+	// Priced is false when the price of the model is unknown
+	Priced bool
+}
+
+// ModelTotal is the usage of a model across all sources and stores
+type ModelTotal struct {
+	Model         string
+	Requests      int
+	Tokens        int
+	EstimatedCost float64
+	Priced        bool
+}
+
+// UsageReport is the embedding usage since a point in time
+type UsageReport struct {
+	Since     time.Time
+	Lines     []UsageLine
+	Models    []ModelTotal
+	TotalCost float64
+}
+
+// Report aggregates the embedding usage of the sync runs started at or after since
+func (u *ReportUsecase) Report(ctx context.Context, since time.Time) (*UsageReport, error) {
+	summaries, err := u.usageRepo.SummarizeUsage(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UsageReport{Since: since}
+	totals := make(map[string]*ModelTotal)
+	for _, summary := range summaries {
+		price, priced := u.prices[summary.Model]
+		line := UsageLine{
+			UsageSummary:  summary,
+			EstimatedCost: model.EstimateCost(summary.Tokens, price),
+			Priced:        priced,
+		}
+		report.Lines = append(report.Lines, line)
+		report.TotalCost += line.EstimatedCost
+
+		total, ok := totals[summary.Model]
+		if !ok {
+			total = &ModelTotal{Model: summary.Model, Priced: priced}
+			totals[summary.Model] = total
+		}
+		total.Requests += summary.Requests
+		total.Tokens += summary.Tokens
+		total.EstimatedCost += line.EstimatedCost
+	}
+
+	for _, total := range totals {
+		report.Models = append(report.Models, *total)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		return report.Models[i].Model < report.Models[j].Model
+	})
+
+	return report, nil
+}
@@ -0,0 +1,50 @@
+package usage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+// fakeUsageRepository returns fixed summaries
+type fakeUsageRepository struct {
+	summaries []model.UsageSummary
+}
+
+func (r *fakeUsageRepository) RecordUsage(_ context.Context, record *model.UsageRecord) error {
+	return nil
+}
+
+func (r *fakeUsageRepository) SummarizeUsage(_ context.Context, since time.Time) ([]model.UsageSummary, error) {
+	return r.summaries, nil
+}
+
+func TestReport(t *testing.T) {
+	repo := &fakeUsageRepository{summaries: []model.UsageSummary{
+		{Source: model.UsageSourceDocument, StoreId: 1, Model: "text-embedding-3-small", Requests: 10, Tokens: 2000000},
+		{Source: model.UsageSourceDocument, StoreId: 2, Model: "text-embedding-3-small", Requests: 5, Tokens: 1000000},
+		{Source: model.UsageSourceMemory, Model: "in-house", Requests: 3, Tokens: 500},
+	}}
+
+	report, err := NewReportUsecase(repo, map[string]float64{"text-embedding-3-small": 0.05}).Report(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(report.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(report.Lines))
+	}
+	if report.Lines[2].Priced {
+		t.Error("expected the model without a price to be reported as unpriced")
+	}
+	if len(report.Models) != 2 || report.Models[1].Model != "text-embedding-3-small" || report.Models[1].Tokens != 3000000 {
+		t.Errorf("unexpected model totals: %+v", report.Models)
+	}
+	// The configured price overrides the default
+	if math.Abs(report.TotalCost-0.15) > 1e-9 {
+		t.Errorf("got total cost %v, want 0.15", report.TotalCost)
+	}
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE stores ADD COLUMN ref VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE stores ADD COLUMN filters JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE stores ADD COLUMN last_synced_at TIMESTAMP WITH TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE stores DROP COLUMN last_synced_at;
+ALTER TABLE stores DROP COLUMN filters;
+ALTER TABLE stores DROP COLUMN ref;
+-- +goose StatementEnd
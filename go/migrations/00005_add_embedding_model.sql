@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE documents ADD COLUMN embedding_model VARCHAR(100);
+ALTER TABLE memories ADD COLUMN embedding_model VARCHAR(100);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE documents DROP COLUMN embedding_model;
+ALTER TABLE memories DROP COLUMN embedding_model;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- HNSW indexes for cosine distance (<=>); requires pgvector 0.5.0 or later.
+-- Rebuild them with other parameters using "personal-agent index create".
+CREATE INDEX IF NOT EXISTS idx_documents_embedding ON documents
+    USING hnsw (embedding vector_cosine_ops) WITH (m = 16, ef_construction = 64);
+CREATE INDEX IF NOT EXISTS idx_memories_embedding ON memories
+    USING hnsw (embedding vector_cosine_ops) WITH (m = 16, ef_construction = 64);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_memories_embedding;
+DROP INDEX IF EXISTS idx_documents_embedding;
+-- +goose StatementEnd
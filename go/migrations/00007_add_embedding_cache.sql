@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Embeddings keyed by the SHA-256 of the embedded text and the model that produced them
+CREATE TABLE IF NOT EXISTS embedding_cache (
+    sha VARCHAR(64) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    embedding VECTOR NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (sha, model)
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_cache_last_used_at ON embedding_cache(last_used_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_embedding_cache_last_used_at;
+DROP TABLE IF EXISTS embedding_cache;
+-- +goose StatementEnd
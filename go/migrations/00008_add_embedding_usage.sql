@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Embedding requests and tokens billed by each sync run
+CREATE TABLE IF NOT EXISTS embedding_usage (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(20) NOT NULL, -- 'document' or 'memory'
+    store_id INTEGER REFERENCES stores(id) ON DELETE SET NULL,
+    model VARCHAR(100) NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_usage_started_at ON embedding_usage(started_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_embedding_usage_started_at;
+DROP TABLE IF EXISTS embedding_usage;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE documents ADD COLUMN token_count INTEGER;
+ALTER TABLE memories ADD COLUMN token_count INTEGER;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE documents DROP COLUMN token_count;
+ALTER TABLE memories DROP COLUMN token_count;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Keep only the most recently updated document of duplicated paths before adding the constraints;
+-- documents are read again from their store by the next sync
+DELETE FROM documents d
+USING documents newer
+WHERE d.store_id = newer.store_id
+  AND d.path = newer.path
+  AND (d.updated_at, d.id) < (newer.updated_at, newer.id);
+
+-- Memories saved by the agent may share a category path and cannot be synced again, so all but the
+-- most recently updated row of a duplicated path are kept under the path suffixed with their id
+UPDATE memories m
+SET path = m.path || '-' || m.id
+WHERE EXISTS (
+  SELECT 1 FROM memories newer
+  WHERE newer.path = m.path
+    AND (m.updated_at, m.id) < (newer.updated_at, newer.id)
+);
+
+-- Bulk saves upsert with ON CONFLICT on these constraints
+ALTER TABLE documents ADD CONSTRAINT documents_store_id_path_key UNIQUE (store_id, path);
+ALTER TABLE memories ADD CONSTRAINT memories_path_key UNIQUE (path);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE memories DROP CONSTRAINT IF EXISTS memories_path_key;
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_store_id_path_key;
+-- +goose StatementEnd
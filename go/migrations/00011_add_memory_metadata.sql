@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE memories ADD COLUMN type TEXT NOT NULL DEFAULT '';
+ALTER TABLE memories ADD COLUMN importance SMALLINT;
+ALTER TABLE memories ADD COLUMN source TEXT NOT NULL DEFAULT '';
+ALTER TABLE memories ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE memories ADD COLUMN supersedes JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE memories ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_memories_type ON memories(type);
+CREATE INDEX idx_memories_expires_at ON memories(expires_at) WHERE archived_at IS NULL;
+CREATE INDEX idx_memories_supersedes ON memories USING GIN (supersedes);
+
+-- Make the next memory sync parse the frontmatter of the existing memories
+UPDATE memories SET sha = NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_memories_supersedes;
+DROP INDEX IF EXISTS idx_memories_expires_at;
+DROP INDEX IF EXISTS idx_memories_type;
+
+ALTER TABLE memories DROP COLUMN archived_at;
+ALTER TABLE memories DROP COLUMN supersedes;
+ALTER TABLE memories DROP COLUMN expires_at;
+ALTER TABLE memories DROP COLUMN source;
+ALTER TABLE memories DROP COLUMN importance;
+ALTER TABLE memories DROP COLUMN type;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Git stores keep the remote URL in repo, which can be longer than an owner/repo name
+ALTER TABLE stores ALTER COLUMN repo TYPE TEXT;
+ALTER TABLE stores ADD COLUMN synced_revision TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE stores DROP COLUMN synced_revision;
+ALTER TABLE stores ALTER COLUMN repo TYPE VARCHAR(255);
+-- +goose StatementEnd
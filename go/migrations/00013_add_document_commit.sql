@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The last commit that changed each document, so that recent changes can be listed per author
+ALTER TABLE documents ADD COLUMN commit_sha TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN author TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN commit_message TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_documents_modified_at ON documents (modified_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_documents_modified_at;
+ALTER TABLE documents DROP COLUMN commit_message;
+ALTER TABLE documents DROP COLUMN author;
+ALTER TABLE documents DROP COLUMN commit_sha;
+-- +goose StatementEnd
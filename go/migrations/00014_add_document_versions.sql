@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Previous contents of documents, recorded when a sync changes their content
+CREATE TABLE IF NOT EXISTS document_versions (
+    id SERIAL PRIMARY KEY,
+    document_id INTEGER NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    content TEXT NOT NULL,
+    sha VARCHAR(64),
+    embedding_model VARCHAR(100),
+    modified_at TIMESTAMP,
+    commit_sha TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    commit_message TEXT NOT NULL DEFAULT '',
+    replaced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (document_id, version)
+);
+CREATE INDEX IF NOT EXISTS idx_document_versions_replaced_at ON document_versions (replaced_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS document_versions;
+-- +goose StatementEnd
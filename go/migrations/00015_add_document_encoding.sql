@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The encoding each document was decoded from; the content is always stored as UTF-8
+ALTER TABLE documents ADD COLUMN encoding VARCHAR(20) NOT NULL DEFAULT 'UTF-8';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE documents DROP COLUMN encoding;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Hashes of the embedded body and of the frontmatter, so that frontmatter changes do not re-embed documents
+ALTER TABLE documents ADD COLUMN body_sha TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN metadata_sha TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE documents DROP COLUMN metadata_sha;
+ALTER TABLE documents DROP COLUMN body_sha;
+-- +goose StatementEnd
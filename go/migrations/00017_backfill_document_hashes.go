@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/bonyuta0204/personal-agent/go/internal/domain/model"
+)
+
+func init() {
+	goose.AddMigrationContext(upBackfillDocumentHashes, nil)
+}
+
+// upBackfillDocumentHashes hashes the stored content of every document the way syncs do since
+// 00016, so that the first sync after the upgrade sees unchanged documents as unchanged instead
+// of re-embedding them and recording a version for the new hashes
+func upBackfillDocumentHashes(ctx context.Context, tx *sql.Tx) error {
+	return backfillContentHashes(ctx, tx, "documents")
+}
+
+// contentHashes are the hashes of the content of a stored row
+type contentHashes struct {
+	id                        string
+	sha, bodySHA, metadataSHA string
+}
+
+// backfillContentHashes sets the sha, body_sha and metadata_sha columns of every row of table
+// from its content
+func backfillContentHashes(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, content FROM `+table)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	// The rows are read before updating, since a connection runs one query at a time
+	var hashes []contentHashes
+	for rows.Next() {
+		var h contentHashes
+		var content string
+		if err := rows.Scan(&h.id, &content); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		h.sha, h.bodySHA, h.metadataSHA = model.ContentHashes(content)
+		hashes = append(hashes, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", table, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE `+table+` SET sha = $2, body_sha = $3, metadata_sha = $4 WHERE id = $1`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, h := range hashes {
+		if _, err := stmt.ExecContext(ctx, h.id, h.sha, h.bodySHA, h.metadataSHA); err != nil {
+			return fmt.Errorf("failed to hash %s %s: %w", table, h.id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Hashes of the embedded body and of the frontmatter, so that frontmatter changes do not re-embed memories
+ALTER TABLE memories ADD COLUMN body_sha TEXT NOT NULL DEFAULT '';
+ALTER TABLE memories ADD COLUMN metadata_sha TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE memories DROP COLUMN metadata_sha;
+ALTER TABLE memories DROP COLUMN body_sha;
+-- +goose StatementEnd
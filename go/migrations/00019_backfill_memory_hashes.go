@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upBackfillMemoryHashes, nil)
+}
+
+// upBackfillMemoryHashes hashes the stored content of every memory the way syncs do since 00018,
+// so that the first sync after the upgrade does not re-embed unchanged memories
+func upBackfillMemoryHashes(ctx context.Context, tx *sql.Tx) error {
+	return backfillContentHashes(ctx, tx, "memories")
+}
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations so that the CLI can apply them
+// with "personal-agent migrate".
+package migrations
+
+import "embed"
+
+// FS contains the goose SQL migration files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -7,6 +7,12 @@ export interface Config {
     password: string;
     ssl: boolean;
   };
+  search: {
+    // Query-time parameters of the vector indexes, set with SET LOCAL around every
+    // similarity query; undefined or 0 keeps the server setting
+    efSearch?: number;
+    probes?: number;
+  };
   openai: {
     openaiApiKey: string;
     model: string;
@@ -24,6 +30,11 @@ export function loadConfig(): Config {
       password: Deno.env.get("DB_PASSWORD") || "",
       ssl: Deno.env.get("DB_SSL") === "true",
     },
+    search: {
+      // The bounds are those that pgvector accepts
+      efSearch: optionalInt("SEARCH_EF_SEARCH", 1000),
+      probes: optionalInt("SEARCH_PROBES", 32768),
+    },
     openai: {
       openaiApiKey: Deno.env.get("OPENAI_API_KEY") || "",
       model: Deno.env.get("OPENAI_MODEL") || "gpt-4.1-mini",
@@ -31,3 +42,16 @@ export function loadConfig(): Config {
     },
   };
 }
+
+// optionalInt reads an integer between 0 and max from the environment variable
+function optionalInt(name: string, max: number): number | undefined {
+  const value = Deno.env.get(name);
+  if (!value) {
+    return undefined;
+  }
+  const n = Number(value);
+  if (!Number.isInteger(n) || n < 0 || n > max) {
+    throw new Error(`${name}: must be an integer between 0 and ${max}, got "${value}"`);
+  }
+  return n;
+}
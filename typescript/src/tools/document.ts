@@ -1,42 +1,40 @@
 import { z } from "zod";
-import {
-  DistanceStrategy,
-  PGVectorStore,
-} from "@langchain/community/vectorstores/pgvector";
+import { Document } from "@langchain/core/documents";
 import { OpenAIEmbeddings } from "@langchain/openai";
 import { Pool } from "pg";
 import { tool } from "@langchain/core/tools";
 
 import { Config } from "../config/index.ts";
+import { withSearchParameters } from "./search.ts";
 
 export async function createDocumentSemanticTool(pool: Pool, config: Config) {
-  const vectorStoreConifg = {
-    pool,
-    tableName: "documents",
-    columns: {
-      idColumnName: "id",
-      vectorColumnName: "embedding",
-      contentColumnName: "content",
-    },
-    distanceStrategy: "cosine" as DistanceStrategy,
-  };
-
   const embeddings = new OpenAIEmbeddings({
     model: config.openai.embeddingModel,
   });
 
-  const vectorStore = await PGVectorStore.initialize(
-    embeddings,
-    vectorStoreConifg
-  );
   return tool(
     async (input: { query: string; k?: number }) => {
-      const retriever = vectorStore.asRetriever({
-        k: input.k ?? 5,
-        searchType: "similarity",
+      const queryEmbedding = await embeddings.embedQuery(input.query);
+      // The query runs in withSearchParameters, which the PGVectorStore retriever cannot do, and
+      // compares only embeddings of the query model; ordering by the cosine distance alone lets
+      // the vector index serve it
+      const rows = await withSearchParameters(pool, config, async (client) => {
+        const res = await client.query(
+          `SELECT id, path, content, tags,
+            (1 - (embedding <=> $1::vector)) as similarity
+           FROM documents
+           WHERE embedding IS NOT NULL AND embedding_model = $3
+           ORDER BY embedding <=> $1::vector
+           LIMIT $2`,
+          [`[${queryEmbedding.join(',')}]`, input.k ?? 5, config.openai.embeddingModel]
+        );
+        return res.rows;
       });
-
-      const results = await retriever.invoke(input.query);
+      // Results keep the shape of the retriever output: documents with the content as
+      // pageContent and the other columns as metadata
+      const results = rows.map(({ id, content, ...metadata }) =>
+        new Document({ id: String(id), pageContent: content, metadata })
+      );
       return JSON.stringify(results, null, 2);
     },
     {
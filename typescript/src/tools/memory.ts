@@ -3,6 +3,7 @@ import { tool } from "@langchain/core/tools";
 import { z } from "zod";
 import { OpenAIEmbeddings } from "@langchain/openai";
 import { Config } from "../config/index.ts";
+import { withSearchParameters } from "./search.ts";
 
 // Enhanced memory creation with automatic embedding generation
 export function createNewMemoryTool(pool: Pool, config?: Config) {
@@ -28,14 +29,27 @@ export function createNewMemoryTool(pool: Pool, config?: Config) {
           embedding = await embeddings.embedQuery(textToEmbed);
         }
         
-        const res = await client.query(
-          embedding 
-            ? `INSERT INTO memories (content, path, tags, embedding) VALUES ($1, $2, $3, $4::vector) RETURNING *`
-            : `INSERT INTO memories (content, path, tags) VALUES ($1, $2, $3) RETURNING *`,
-          embedding 
-            ? [input.content, input.path, JSON.stringify(input.tags), `[${embedding.join(',')}]`]
-            : [input.content, input.path, JSON.stringify(input.tags)]
-        );
+        // Paths are unique, and the model reuses category paths such as
+        // "preferences/coding": a taken path gets a random suffix, so that
+        // saving never overwrites or fails on an earlier memory
+        const insert = (path: string) =>
+          client.query(
+            `INSERT INTO memories (content, path, tags, embedding, embedding_model)
+             VALUES ($1, $2, $3, $4::vector, $5)
+             ON CONFLICT (path) DO NOTHING
+             RETURNING *`,
+            [
+              input.content,
+              path,
+              JSON.stringify(input.tags),
+              embedding ? `[${embedding.join(',')}]` : null,
+              embedding ? config!.openai.embeddingModel : null,
+            ]
+          );
+        let res = await insert(input.path);
+        while (res.rows.length === 0) {
+          res = await insert(`${input.path}-${crypto.randomUUID().slice(0, 8)}`);
+        }
         
         return JSON.stringify({
           success: true,
@@ -55,7 +69,7 @@ export function createNewMemoryTool(pool: Pool, config?: Config) {
         "Input: { content: string, path: string, tags: string[], context?: string }",
       schema: z.object({
         content: z.string().describe("The main content to remember"),
-        path: z.string().describe("Category path like 'preferences/coding' or 'facts/personal'"),
+        path: z.string().describe("Category path like 'preferences/coding' or 'facts/personal'; a suffix is added if the path is taken"),
         tags: z.array(z.string()).describe("Relevant tags for easy retrieval"),
         context: z.string().optional().describe("Additional context to improve semantic search"),
       }),
@@ -65,10 +79,11 @@ export function createNewMemoryTool(pool: Pool, config?: Config) {
 
 export function retrieveMemoriesTool(pool: Pool) {
   return tool(
-    async (input: { path?: string; tags?: string[]; limit?: number }) => {
+    async (input: { path?: string; tags?: string[]; type?: string; minImportance?: number; limit?: number }) => {
       const client = await pool.connect();
       try {
-        let query = "SELECT * FROM memories WHERE 1=1";
+        // Archived memories (expired or superseded) are swept by the sync service
+        let query = "SELECT * FROM memories WHERE archived_at IS NULL";
         const params: (string | number)[] = [];
         let paramCount = 0;
 
@@ -84,6 +99,18 @@ export function retrieveMemoriesTool(pool: Pool) {
           params.push(JSON.stringify(input.tags));
         }
 
+        if (input.type) {
+          paramCount++;
+          query += ` AND type = $${paramCount}`;
+          params.push(input.type);
+        }
+
+        if (input.minImportance) {
+          paramCount++;
+          query += ` AND importance >= $${paramCount}`;
+          params.push(input.minImportance);
+        }
+
         query += " ORDER BY created_at DESC";
 
         if (input.limit) {
@@ -101,10 +128,13 @@ export function retrieveMemoriesTool(pool: Pool) {
     {
       name: "retrieve_memories",
       description:
-        "Retrieve memories filtered by path and/or tags. Input: { path?: string, tags?: string[], limit?: number }.",
+        "Retrieve memories filtered by path, tags, type and/or importance. " +
+        "Input: { path?: string, tags?: string[], type?: 'preference' | 'fact' | 'instruction' | 'event', minImportance?: number (1-5), limit?: number }.",
       schema: z.object({
         path: z.string().optional(),
         tags: z.array(z.string()).optional(),
+        type: z.enum(['preference', 'fact', 'instruction', 'event']).optional(),
+        minImportance: z.number().int().min(1).max(5).optional(),
         limit: z.number().optional(),
       }),
     }
@@ -119,24 +149,23 @@ export function createMemorySemanticSearchTool(pool: Pool, config: Config) {
 
   return tool(
     async (input: { query: string; k?: number; recencyWeight?: number }) => {
-      const client = await pool.connect();
-      try {
-        // Generate embedding for the query
-        const queryEmbedding = await embeddings.embedQuery(input.query);
-        
+      // Generate embedding for the query
+      const queryEmbedding = await embeddings.embedQuery(input.query);
+
+      return await withSearchParameters(pool, config, async (client) => {
         // Combine semantic similarity with recency
         const recencyWeight = input.recencyWeight || 0.1;
         const res = await client.query(
           `SELECT 
-            id, path, content, tags, created_at,
+            id, path, content, tags, type, importance, source, created_at,
             (1 - (embedding <=> $1::vector)) as similarity,
             ((1 - (embedding <=> $1::vector)) * (1 - $3) + 
              (1 / (1 + EXTRACT(EPOCH FROM (NOW() - created_at))/86400)) * $3) as combined_score
            FROM memories 
-           WHERE embedding IS NOT NULL
+           WHERE embedding IS NOT NULL AND embedding_model = $4 AND archived_at IS NULL
            ORDER BY combined_score DESC 
            LIMIT $2`,
-          [`[${queryEmbedding.join(',')}]`, input.k || 5, recencyWeight]
+          [`[${queryEmbedding.join(',')}]`, input.k || 5, recencyWeight, config.openai.embeddingModel]
         );
         
         return JSON.stringify({
@@ -144,9 +173,7 @@ export function createMemorySemanticSearchTool(pool: Pool, config: Config) {
           memories: res.rows,
           count: res.rows.length
         }, null, 2);
-      } finally {
-        client.release();
-      }
+      });
     },
     {
       name: "search_memories_semantic",
@@ -0,0 +1,32 @@
+import { Pool, PoolClient } from "pg";
+
+import { Config } from "../config/index.ts";
+
+// withSearchParameters runs a similarity query in a transaction that sets the
+// query-time parameters of the HNSW (ef_search) and IVFFlat (probes) indexes
+// with SET LOCAL, so that they do not leak to other users of the connection
+export async function withSearchParameters<T>(
+  pool: Pool,
+  config: Config,
+  query: (client: PoolClient) => Promise<T>
+): Promise<T> {
+  const client = await pool.connect();
+  try {
+    await client.query("BEGIN");
+    // SET does not take bind parameters; the values are validated integers
+    if (config.search.efSearch) {
+      await client.query(`SET LOCAL hnsw.ef_search = ${config.search.efSearch}`);
+    }
+    if (config.search.probes) {
+      await client.query(`SET LOCAL ivfflat.probes = ${config.search.probes}`);
+    }
+    const result = await query(client);
+    await client.query("COMMIT");
+    return result;
+  } catch (error) {
+    await client.query("ROLLBACK");
+    throw error;
+  } finally {
+    client.release();
+  }
+}